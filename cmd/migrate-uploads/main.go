@@ -0,0 +1,138 @@
+// Command migrate-uploads copies files from a local uploads directory into the storage backend
+// configured by STORAGE_DRIVER and rewrites menu image URLs to point at the new location.
+//
+//	go run ./cmd/migrate-uploads -src ./uploads -dry-run
+//	go run ./cmd/migrate-uploads -src ./uploads -delete-source
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/storage"
+)
+
+func main() {
+    srcDir := flag.String("src", "./uploads", "local directory holding the existing uploads")
+    srcPrefix := flag.String("src-url-prefix", "/uploads/", "URL prefix the local files were served at")
+    dryRun := flag.Bool("dry-run", false, "only print what would be copied and rewritten")
+    deleteSource := flag.Bool("delete-source", false, "remove local files after a successful copy")
+    flag.Parse()
+
+    config.LoadEnv()
+    config.ConnectMySQL("")
+
+    src := storage.NewLocal(*srcDir, *srcPrefix)
+    dst, err := storage.FromEnv()
+    if err != nil {
+        log.Fatalf("failed to configure target storage: %v", err)
+    }
+    if l, ok := dst.(*storage.Local); ok && l.Dir == src.Dir {
+        log.Fatalf("target storage is the source directory, set STORAGE_DRIVER=s3")
+    }
+
+    // menus are loaded first so a dry run reports the rewrites without touching the database
+    var menus []model.Menu
+    if err := config.DB.Where("image_url <> ''").Find(&menus).Error; err != nil {
+        log.Fatalf("failed to load menus: %v", err)
+    }
+
+    ctx := context.Background()
+    keys, err := src.List(ctx, "")
+    if err != nil {
+        log.Fatalf("failed to list %s: %v", *srcDir, err)
+    }
+    copied, rewrites, err := migrate(ctx, src, dst, keys, menus, *dryRun, os.Stdout)
+    if err != nil {
+        log.Fatal(err)
+    }
+
+    // rewrite menu image URLs in one transaction
+    rewritten := 0
+    if !*dryRun {
+        tx := config.DB.Begin()
+        for _, rw := range rewrites {
+            if err := tx.Model(&model.Menu{}).Where("id = ?", rw.MenuID).Update("image_url", rw.To).Error; err != nil {
+                tx.Rollback()
+                log.Fatalf("failed to update menu %d: %v", rw.MenuID, err)
+            }
+            rewritten++
+        }
+        if err := tx.Commit().Error; err != nil {
+            log.Fatalf("failed to commit url rewrite: %v", err)
+        }
+    }
+
+    if *deleteSource && !*dryRun {
+        for _, key := range keys {
+            if err := src.Delete(ctx, key); err != nil {
+                log.Printf("failed to delete source %s: %v", key, err)
+            }
+        }
+    }
+    fmt.Printf("copied %d files, rewrote %d menu urls\n", copied, rewritten)
+}
+
+// rewrite is a menu image URL moving to the target storage
+type rewrite struct {
+    MenuID uint
+    From   string
+    To     string
+}
+
+// migrate copies keys from src to dst and returns the menu image URLs to rewrite. With dryRun
+// it only prints what would be copied and rewritten; dst is never written to.
+func migrate(ctx context.Context, src *storage.Local, dst storage.Storage, keys []string, menus []model.Menu, dryRun bool, out io.Writer) (int, []rewrite, error) {
+    copied := 0
+    for _, key := range keys {
+        if dryRun {
+            fmt.Fprintf(out, "copy %s -> %s\n", key, dst.URL(key))
+            continue
+        }
+        if err := copyObject(ctx, src, dst, key); err != nil {
+            return copied, nil, fmt.Errorf("failed to copy %s: %w", key, err)
+        }
+        copied++
+    }
+    var rewrites []rewrite
+    for _, m := range menus {
+        key, ok := src.Key(m.ImageURL)
+        if !ok {
+            continue
+        }
+        rw := rewrite{MenuID: m.ID, From: m.ImageURL, To: dst.URL(key)}
+        fmt.Fprintf(out, "menu %d: %s -> %s\n", rw.MenuID, rw.From, rw.To)
+        rewrites = append(rewrites, rw)
+    }
+    return copied, rewrites, nil
+}
+
+func copyObject(ctx context.Context, src, dst storage.Storage, key string) error {
+    r, err := src.Get(ctx, key)
+    if err != nil {
+        return err
+    }
+    defer r.Close()
+    var size int64 = -1
+    if f, ok := r.(*os.File); ok {
+        if st, err := f.Stat(); err == nil {
+            size = st.Size()
+        }
+    }
+    return dst.Put(ctx, key, r, size, contentTypeFor(key))
+}
+
+func contentTypeFor(key string) string {
+    if ct := mime.TypeByExtension(path.Ext(key)); ct != "" {
+        return ct
+    }
+    return "application/octet-stream"
+}
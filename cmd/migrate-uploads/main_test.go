@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/storage"
+)
+
+// readOnly is a target storage that fails the test when written to
+type readOnly struct {
+    storage.Storage
+    t *testing.T
+}
+
+func (r readOnly) Put(ctx context.Context, key string, _ io.Reader, _ int64, _ string) error {
+    r.t.Errorf("dry run wrote %s to the target storage", key)
+    return nil
+}
+
+func (r readOnly) Delete(ctx context.Context, key string) error {
+    r.t.Errorf("dry run deleted %s from the target storage", key)
+    return nil
+}
+
+func (r readOnly) URL(key string) string {
+    return "/files/" + key
+}
+
+func newSource(t *testing.T) (*storage.Local, []string) {
+    t.Helper()
+    dir := t.TempDir()
+    for _, name := range []string{"menus/1.jpg", "menus/2.png"} {
+        p := filepath.Join(dir, filepath.FromSlash(name))
+        if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+            t.Fatal(err)
+        }
+        if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+            t.Fatal(err)
+        }
+    }
+    src := storage.NewLocal(dir, "/uploads/")
+    keys, err := src.List(context.Background(), "")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return src, keys
+}
+
+var menus = []model.Menu{
+    {ID: 1, ImageURL: "/uploads/menus/1.jpg"},
+    {ID: 2, ImageURL: "https://example.com/external.jpg"},
+    {ID: 3, ImageURL: "/uploads/menus/2.png"},
+}
+
+func TestDryRunOnlyReports(t *testing.T) {
+    src, keys := newSource(t)
+    var out bytes.Buffer
+    copied, rewrites, err := migrate(context.Background(), src, readOnly{t: t}, keys, menus, true, &out)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if copied != 0 {
+        t.Errorf("copied = %d, want 0", copied)
+    }
+    want := "copy menus/1.jpg -> /files/menus/1.jpg\n" +
+        "copy menus/2.png -> /files/menus/2.png\n" +
+        "menu 1: /uploads/menus/1.jpg -> /files/menus/1.jpg\n" +
+        "menu 3: /uploads/menus/2.png -> /files/menus/2.png\n"
+    if out.String() != want {
+        t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+    }
+    // main applies the rewrites only without -dry-run; they are still planned
+    if len(rewrites) != 2 || rewrites[0].MenuID != 1 || rewrites[1].MenuID != 3 {
+        t.Errorf("rewrites = %+v, want menus 1 and 3", rewrites)
+    }
+    for _, k := range keys {
+        if _, err := os.Stat(filepath.Join(src.Dir, filepath.FromSlash(k))); err != nil {
+            t.Errorf("source %s: %v", k, err)
+        }
+    }
+}
+
+func TestMigrateCopies(t *testing.T) {
+    src, keys := newSource(t)
+    dst := storage.NewLocal(t.TempDir(), "/files/")
+    copied, rewrites, err := migrate(context.Background(), src, dst, keys, menus, false, io.Discard)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if copied != 2 || len(rewrites) != 2 {
+        t.Fatalf("copied %d, %d rewrites, want 2 and 2", copied, len(rewrites))
+    }
+    r, err := dst.Get(context.Background(), "menus/2.png")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer r.Close()
+    b, _ := io.ReadAll(r)
+    if string(b) != "menus/2.png" {
+        t.Errorf("copied content %q", b)
+    }
+    if !strings.HasPrefix(rewrites[1].To, "/files/") {
+        t.Errorf("rewrite to %q, want the target URL", rewrites[1].To)
+    }
+}
@@ -0,0 +1,19 @@
+package config
+
+import "time"
+
+// PINMaxAttempts is how many wrong manager PINs a cashier, or for one manager, are allowed
+// before approvals by PIN are locked (PIN_MAX_ATTEMPTS)
+func PINMaxAttempts() int {
+    n := GetEnvInt("PIN_MAX_ATTEMPTS", 5)
+    if n < 1 {
+        return 5
+    }
+    return n
+}
+
+// PINLockout is how long approvals by PIN stay locked after too many wrong PINs
+// (PIN_LOCKOUT_MINUTES)
+func PINLockout() time.Duration {
+    return time.Duration(GetEnvInt("PIN_LOCKOUT_MINUTES", 15)) * time.Minute
+}
@@ -3,6 +3,7 @@ package config
 import (
     "log"
     "os"
+    "strconv"
 
     "github.com/joho/godotenv"
 )
@@ -21,3 +22,14 @@ func GetEnv(key, fallback string) string {
     }
     return fallback
 }
+
+// GetEnvInt returns an integer environment variable or fallback if unset or invalid
+func GetEnvInt(key string, fallback int) int {
+    if value, ok := os.LookupEnv(key); ok {
+        if n, err := strconv.Atoi(value); err == nil {
+            return n
+        }
+        log.Printf("invalid integer for %s: %q, using %d", key, value, fallback)
+    }
+    return fallback
+}
@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// IdempotencyRetention is how long responses to Idempotency-Key requests are kept for
+// retries (IDEMPOTENCY_RETENTION_HOURS)
+func IdempotencyRetention() time.Duration {
+    return time.Duration(GetEnvInt("IDEMPOTENCY_RETENTION_HOURS", 24)) * time.Hour
+}
+
+// IdempotencyMaxBody bounds the body of an Idempotency-Key request, which is read into memory
+// to be hashed (IDEMPOTENCY_MAX_BODY_MB, default one MB above UPLOAD_MAX_MB so uploads fit)
+func IdempotencyMaxBody() int64 {
+    return int64(GetEnvInt("IDEMPOTENCY_MAX_BODY_MB", GetEnvInt("UPLOAD_MAX_MB", 5)+1)) << 20
+}
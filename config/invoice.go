@@ -0,0 +1,33 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// InvoicePrefix starts every invoice number (INVOICE_PREFIX, e.g. one per outlet)
+func InvoicePrefix() string {
+    return GetEnv("INVOICE_PREFIX", "WRG")
+}
+
+// InvoiceDigits is the minimum width of the sequence part, zero padded (INVOICE_DIGITS)
+func InvoiceDigits() int {
+    n := GetEnvInt("INVOICE_DIGITS", 4)
+    if n < 1 || n > 10 {
+        log.Printf("invalid INVOICE_DIGITS %d, using 4", n)
+        return 4
+    }
+    return n
+}
+
+// InvoiceReset is when the sequence starts again at 1: daily (default), monthly, yearly or
+// never (INVOICE_RESET)
+func InvoiceReset() string {
+    reset := strings.ToLower(GetEnv("INVOICE_RESET", "daily"))
+    switch reset {
+    case "daily", "monthly", "yearly", "never":
+        return reset
+    }
+    log.Printf("invalid INVOICE_RESET %q, using daily", reset)
+    return "daily"
+}
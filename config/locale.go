@@ -0,0 +1,22 @@
+package config
+
+import "strings"
+
+// DefaultLocale is the language menus are written in (DEFAULT_LOCALE, default "id")
+func DefaultLocale() string {
+    return strings.ToLower(GetEnv("DEFAULT_LOCALE", "id"))
+}
+
+// SupportedLocales lists the locales translations may be stored for (SUPPORTED_LOCALES,
+// comma separated). The default locale is always included.
+func SupportedLocales() []string {
+    def := DefaultLocale()
+    out := []string{def}
+    for _, l := range strings.Split(GetEnv("SUPPORTED_LOCALES", "id,en"), ",") {
+        l = strings.ToLower(strings.TrimSpace(l))
+        if l != "" && l != def {
+            out = append(out, l)
+        }
+    }
+    return out
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// CashRounding is the unit cash totals are rounded to, e.g. 100 or 500 (CASH_ROUNDING, 0 = off)
+func CashRounding() int {
+    n := GetEnvInt("CASH_ROUNDING", 0)
+    if n < 0 {
+        return 0
+    }
+    return n
+}
+
+// CashRoundingMode is nearest (default), up or down (CASH_ROUNDING_MODE)
+func CashRoundingMode() string {
+    mode := strings.ToLower(GetEnv("CASH_ROUNDING_MODE", "nearest"))
+    switch mode {
+    case "nearest", "up", "down":
+        return mode
+    }
+    log.Printf("invalid CASH_ROUNDING_MODE %q, using nearest", mode)
+    return "nearest"
+}
+
+// QRISExpiry is how long a dynamic QRIS code can be paid (QRIS_EXPIRY_MINUTES)
+func QRISExpiry() time.Duration {
+    return time.Duration(GetEnvInt("QRIS_EXPIRY_MINUTES", 15)) * time.Minute
+}
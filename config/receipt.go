@@ -0,0 +1,52 @@
+package config
+
+import (
+	"log"
+	"strings"
+)
+
+// StoreName, StoreAddress and StorePhone head the printed receipts
+func StoreName() string {
+    return GetEnv("STORE_NAME", "WARUNG MAKAN")
+}
+
+func StoreAddress() string {
+    return GetEnv("STORE_ADDRESS", "")
+}
+
+func StorePhone() string {
+    return GetEnv("STORE_PHONE", "")
+}
+
+// ReceiptFooter is printed below the payment (RECEIPT_FOOTER)
+func ReceiptFooter() string {
+    return GetEnv("RECEIPT_FOOTER", "Terima kasih atas kunjungan Anda")
+}
+
+// ReceiptQRURL is encoded as a QR code at the bottom of receipts, e.g. a review page
+// (RECEIPT_QR_URL, empty = no QR code); see ReceiptLinkQR
+func ReceiptQRURL() string {
+    return GetEnv("RECEIPT_QR_URL", "")
+}
+
+// ReceiptWidth is the default paper width in mm: 58 or 80 (RECEIPT_WIDTH_MM)
+func ReceiptWidth() int {
+    w := GetEnvInt("RECEIPT_WIDTH_MM", 58)
+    if w != 58 && w != 80 {
+        log.Printf("invalid RECEIPT_WIDTH_MM %d, using 58", w)
+        return 58
+    }
+    return w
+}
+
+// ReceiptBaseURL is where this server is reached by customers; public receipt links are
+// <base>/r/<token> (RECEIPT_BASE_URL)
+func ReceiptBaseURL() string {
+    return strings.TrimSuffix(GetEnv("RECEIPT_BASE_URL", "http://localhost:"+GetEnv("PORT", "8080")), "/")
+}
+
+// ReceiptLinkQR prints the QR code of the sale's public receipt link instead of
+// RECEIPT_QR_URL (RECEIPT_LINK_QR=true)
+func ReceiptLinkQR() bool {
+    return GetEnv("RECEIPT_LINK_QR", "false") == "true"
+}
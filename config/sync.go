@@ -0,0 +1,9 @@
+package config
+
+import "time"
+
+// SyncTombstoneRetention is how long deletions are remembered for catalog sync
+// (SYNC_TOMBSTONE_RETENTION_DAYS); devices that last synced earlier get the full catalog
+func SyncTombstoneRetention() time.Duration {
+    return time.Duration(GetEnvInt("SYNC_TOMBSTONE_RETENTION_DAYS", 30)) * 24 * time.Hour
+}
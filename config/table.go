@@ -0,0 +1,21 @@
+package config
+
+import "strings"
+
+// TableTokenSecret signs table QR tokens (TABLE_QR_SECRET, falls back to the JWT secret)
+func TableTokenSecret() []byte {
+    if s := GetEnv("TABLE_QR_SECRET", ""); s != "" {
+        return []byte(s)
+    }
+    return JwtSecret()
+}
+
+// SelfOrderBaseURL is the customer ordering page; the table token is appended to it
+func SelfOrderBaseURL() string {
+    return strings.TrimSuffix(GetEnv("SELF_ORDER_BASE_URL", GetEnv("FRONTEND_ORIGIN", "http://localhost:5554")+"/order"), "/")
+}
+
+// SelfOrderMaxPending limits how many unhandled orders a single table may have
+func SelfOrderMaxPending() int {
+    return GetEnvInt("SELF_ORDER_MAX_PENDING", 5)
+}
@@ -0,0 +1,19 @@
+package config
+
+import "github.com/IndalAwalaikal/warung-pos/backend/utils"
+
+// UploadImageOptions returns the image pipeline settings for uploaded menu photos.
+// UPLOAD_MAX_MB limits the raw upload, UPLOAD_MAX_MEGAPIXELS its dimensions and the
+// rendition sizes bound the longest side in pixels.
+func UploadImageOptions() utils.ImageOptions {
+    return utils.ImageOptions{
+        MaxBytes:  int64(GetEnvInt("UPLOAD_MAX_MB", 5)) << 20,
+        MaxPixels: int64(GetEnvInt("UPLOAD_MAX_MEGAPIXELS", 40)) * 1_000_000,
+        Quality:   GetEnvInt("UPLOAD_JPEG_QUALITY", 82),
+        Renditions: []utils.Rendition{
+            {Name: "original", MaxSize: GetEnvInt("UPLOAD_ORIGINAL_SIZE", 1600)},
+            {Name: "medium", MaxSize: GetEnvInt("UPLOAD_MEDIUM_SIZE", 800)},
+            {Name: "thumbnail", MaxSize: GetEnvInt("UPLOAD_THUMBNAIL_SIZE", 200)},
+        },
+    }
+}
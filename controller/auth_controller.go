@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
 	"github.com/IndalAwalaikal/warung-pos/backend/middleware"
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"github.com/IndalAwalaikal/warung-pos/backend/service"
@@ -18,11 +19,12 @@ func NewAuthController(s service.AuthService) *AuthController {
 }
 
 func (c *AuthController) Register(ctx *gin.Context) {
-    var in model.User
-    if err := ctx.ShouldBindJSON(&in); err != nil {
+    var req dto.RegisterRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
         ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
+    in := model.User{Name: req.Name, Email: req.Email, Password: req.Password}
     if err := c.svc.Register(&in); err != nil {
         ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
@@ -64,3 +66,38 @@ func (c *AuthController) Me(ctx *gin.Context) {
     }
     ctx.JSON(http.StatusOK, gin.H{"status":"success","data": user})
 }
+
+// SetPIN sets the approval PIN of the current user (admins and managers)
+func (c *AuthController) SetPIN(ctx *gin.Context) {
+    u, ok := ctx.MustGet(middleware.ContextUserKey).(*model.User)
+    if !ok {
+        ctx.JSON(http.StatusUnauthorized, gin.H{"status":"error","message":"unauthorized"})
+        return
+    }
+    var req struct{
+        PIN string `json:"pin" binding:"required"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if err := c.svc.SetPIN(u, req.PIN); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// Approvers lists the managers a cashier can pick to approve with their PIN
+func (c *AuthController) Approvers(ctx *gin.Context) {
+    list, err := c.svc.Approvers()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    out := make([]gin.H, 0, len(list))
+    for _, u := range list {
+        out = append(out, gin.H{"id": u.ID, "name": u.Name, "role": u.Role})
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": out})
+}
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// BillController manages open bills of dine-in tables
+type BillController struct{
+    svc service.BillService
+}
+
+func NewBillController(s service.BillService) *BillController {
+    return &BillController{svc: s}
+}
+
+// List returns bills (query param status, default open; "all" for every bill)
+func (c *BillController) List(ctx *gin.Context) {
+    status := ctx.DefaultQuery("status", model.BillOpen)
+    if status == "all" {
+        status = ""
+    }
+    list, err := c.svc.List(status)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *BillController) Get(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    b, err := c.svc.GetByID(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if b == nil {
+        ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": b})
+}
+
+func (c *BillController) Open(ctx *gin.Context) {
+    var req dto.BillRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    b, err := c.svc.Open(&req, currentUserID(ctx))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": b})
+}
+
+func (c *BillController) AddItems(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.BillItemsRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    b, err := c.svc.AddItems(id, req.Items, currentUserID(ctx))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": b})
+}
+
+// RemoveItem removes a line, or only query param quantity units of it
+func (c *BillController) RemoveItem(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    itemID, err := strconv.ParseUint(ctx.Param("item_id"), 10, 64)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid item id"})
+        return
+    }
+    quantity := 0
+    if q := ctx.Query("quantity"); q != "" {
+        if quantity, err = strconv.Atoi(q); err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid quantity"})
+            return
+        }
+    }
+    b, err := c.svc.RemoveItem(id, uint(itemID), quantity)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": b})
+}
+
+func (c *BillController) Transfer(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.BillTransferRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    b, err := c.svc.Transfer(id, req.TableID)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": b})
+}
+
+func (c *BillController) Merge(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.BillMergeRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    b, err := c.svc.Merge(id, req.BillID)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": b})
+}
+
+// Split answers the new bill
+func (c *BillController) Split(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.BillSplitRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    b, err := c.svc.Split(id, &req, currentUserID(ctx))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": b})
+}
+
+// Settle closes the bill into a paid transaction, priced with the current menu prices
+func (c *BillController) Settle(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.BillSettleRequest
+    if ctx.Request.ContentLength != 0 {
+        if err := ctx.ShouldBindJSON(&req); err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+            return
+        }
+    }
+    t, err := c.svc.Settle(id, &req, currentUserID(ctx))
+    if err != nil {
+        checkoutError(ctx, t, err)
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": t})
+}
+
+func (c *BillController) Cancel(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.Cancel(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// Tables returns the floor plan: every table with its open bills
+func (c *BillController) Tables(ctx *gin.Context) {
+    list, err := c.svc.Tables()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
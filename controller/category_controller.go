@@ -1,19 +1,23 @@
 package controller
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type CategoryController struct{
-    svc service.CategoryService
+    svc     service.CategoryService
+    i18n    service.TranslationService
+    catalog service.CatalogService
 }
 
-func NewCategoryController(s service.CategoryService) *CategoryController {
-    return &CategoryController{svc: s}
+func NewCategoryController(s service.CategoryService, ts service.TranslationService, cs service.CatalogService) *CategoryController {
+    return &CategoryController{svc: s, i18n: ts, catalog: cs}
 }
 
 func (c *CategoryController) Create(ctx *gin.Context) {
@@ -30,10 +34,29 @@ func (c *CategoryController) Create(ctx *gin.Context) {
 }
 
 func (c *CategoryController) List(ctx *gin.Context) {
-    list, err := c.svc.List()
+    locale := requestLocale(ctx)
+    st, err := c.catalog.Version()
     if err != nil {
         ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
-    ctx.JSON(http.StatusOK, gin.H{"data": list})
+    variant := "categories|" + locale
+    if utils.NotModified(ctx, utils.CatalogETag(st.Version, variant), st.UpdatedAt) {
+        return
+    }
+    body, err := c.catalog.Cached(st.Version, variant, func() ([]byte, error) {
+        list, err := c.svc.List()
+        if err != nil {
+            return nil, err
+        }
+        if err := c.i18n.LocalizeCategories(list, locale); err != nil {
+            return nil, err
+        }
+        return json.Marshal(gin.H{"data": list})
+    })
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        return
+    }
+    ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
 }
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// errorStatus maps a service error to its HTTP status: validation problems are the
+// client's fault, missing records are 404, missing permissions 403, state clashes 409,
+// locked PIN approvals 429, payments the gateway could not start 402, other payment gateway failures 502 and
+// anything else is a server error
+func errorStatus(err error) int {
+    switch {
+    case service.IsValidationError(err):
+        return http.StatusBadRequest
+    case errors.Is(err, service.ErrNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, service.ErrForbidden):
+        return http.StatusForbidden
+    case service.IsConflictError(err):
+        return http.StatusConflict
+    case errors.Is(err, service.ErrTooManyAttempts):
+        return http.StatusTooManyRequests
+    case errors.Is(err, service.ErrPaymentNotStarted):
+        return http.StatusPaymentRequired
+    case errors.Is(err, service.ErrGateway):
+        return http.StatusBadGateway
+    }
+    return http.StatusInternalServerError
+}
+
+// checkoutError answers a failed checkout; a sale that was stored before its payment failed
+// goes along, so the client (and a replay of the Idempotency-Key) sees it was cancelled
+func checkoutError(ctx *gin.Context, t *model.Transaction, err error) {
+    body := gin.H{"status":"error","message": err.Error()}
+    if t != nil {
+        body["data"] = t
+    }
+    ctx.JSON(errorStatus(err), body)
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// IngredientController manages ingredients and menu recipes used to derive cost prices (admin only)
+type IngredientController struct{
+    svc service.IngredientService
+}
+
+func NewIngredientController(s service.IngredientService) *IngredientController {
+    return &IngredientController{svc: s}
+}
+
+func (c *IngredientController) List(ctx *gin.Context) {
+    list, err := c.svc.List()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *IngredientController) Create(ctx *gin.Context) {
+    var req dto.IngredientRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    i, err := c.svc.Create(&req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": i})
+}
+
+// Update changes an ingredient; menus costed by recipe are recalculated
+func (c *IngredientController) Update(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.IngredientRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    i, err := c.svc.Update(id, &req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": i})
+}
+
+func (c *IngredientController) Delete(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.Delete(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// MenuCost returns the cost price, margin and recipe of a menu
+func (c *IngredientController) MenuCost(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    cost, err := c.svc.MenuCost(id)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": cost})
+}
+
+// SetRecipe replaces the recipe of a menu; its cost price is then derived from the ingredients
+func (c *IngredientController) SetRecipe(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.RecipeRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    cost, err := c.svc.SetRecipe(id, req.Ingredients)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": cost})
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// KitchenController serves the kitchen display: stations, their tickets and live feeds
+type KitchenController struct{
+    svc service.KitchenService
+}
+
+func NewKitchenController(s service.KitchenService) *KitchenController {
+    return &KitchenController{svc: s}
+}
+
+func (c *KitchenController) ListStations(ctx *gin.Context) {
+    list, err := c.svc.ListStations()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *KitchenController) CreateStation(ctx *gin.Context) {
+    var req dto.KitchenStationRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    st, err := c.svc.CreateStation(&req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": st})
+}
+
+func (c *KitchenController) UpdateStation(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.KitchenStationRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    st, err := c.svc.UpdateStation(id, &req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": st})
+}
+
+func (c *KitchenController) DeleteStation(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.DeleteStation(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","message":"deleted"})
+}
+
+// Tickets lists tickets (query params station_id, and status: active by default, or all)
+func (c *KitchenController) Tickets(ctx *gin.Context) {
+    var stationID uint
+    if v := ctx.Query("station_id"); v != "" {
+        id, err := strconv.ParseUint(v, 10, 64)
+        if err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid station_id"})
+            return
+        }
+        stationID = uint(id)
+    }
+    list, err := c.svc.Tickets(stationID, ctx.DefaultQuery("status", "active") != "all")
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+// Advance moves a ticket to the next state (queued → preparing → ready → served)
+func (c *KitchenController) Advance(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.TicketStatusRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    k, err := c.svc.Advance(id, req.Status)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": k})
+}
+
+// Stream opens an SSE stream with the ticket events of one station
+func (c *KitchenController) Stream(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    streamEvents(ctx, service.KitchenTopic(id))
+}
@@ -1,20 +1,38 @@
 package controller
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
 
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
 	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
 	"github.com/gin-gonic/gin"
 )
 
 type MenuController struct{
-    svc service.MenuService
+    svc       service.MenuService
+    importSvc service.MenuImportService
+    i18n      service.TranslationService
+    catalog   service.CatalogService
 }
 
-func NewMenuController(s service.MenuService) *MenuController {
-    return &MenuController{svc: s}
+func NewMenuController(s service.MenuService, is service.MenuImportService, ts service.TranslationService, cs service.CatalogService) *MenuController {
+    return &MenuController{svc: s, importSvc: is, i18n: ts, catalog: cs}
+}
+
+// requestLocale resolves the response language of a public catalog request and announces it
+func requestLocale(ctx *gin.Context) string {
+    locale := utils.ResolveLocale(ctx, config.SupportedLocales(), config.DefaultLocale())
+    ctx.Header("Content-Language", locale)
+    ctx.Header("Vary", "Accept-Language")
+    return locale
 }
 
 func (c *MenuController) Create(ctx *gin.Context) {
@@ -24,19 +42,113 @@ func (c *MenuController) Create(ctx *gin.Context) {
         return
     }
     if err := c.svc.Create(&in); err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
         return
     }
     ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": in})
 }
 
+// List returns menus with search, filtering, sorting and pagination.
+// Query params: q, category_id (comma separated), min_price, max_price, is_available,
+// plus the generic page/limit/cursor/sort/filter params (see utils.ParseListQuery).
+// Without page, limit or cursor every matching menu is returned.
+// Names and descriptions are translated by ?lang= or Accept-Language.
 func (c *MenuController) List(ctx *gin.Context) {
-    list, err := c.svc.List()
+    q, err := utils.ParseListQuery(ctx, repository.MenuListOptions)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    f, err := parseMenuFilter(ctx)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    locale := requestLocale(ctx)
+    if locale != config.DefaultLocale() {
+        f.Locale = locale
+    }
+
+    // conditional GET: the response only changes when the catalog version does
+    st, err := c.catalog.Version()
     if err != nil {
         ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
         return
     }
-    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+    variant := "menus?" + ctx.Request.URL.Query().Encode() + "|" + locale
+    if utils.NotModified(ctx, utils.CatalogETag(st.Version, variant), st.UpdatedAt) {
+        return
+    }
+    body, err := c.catalog.Cached(st.Version, variant, func() ([]byte, error) {
+        list, meta, err := c.svc.Search(f, q)
+        if err != nil {
+            return nil, err
+        }
+        if err := c.i18n.LocalizeMenus(list, locale); err != nil {
+            return nil, err
+        }
+        hideCosts(list)
+        return json.Marshal(gin.H{"status":"success","data": list, "meta": meta})
+    })
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func parseMenuFilter(ctx *gin.Context) (repository.MenuFilter, error) {
+    f := repository.MenuFilter{Search: ctx.Query("q")}
+    if v := ctx.Query("category_id"); v != "" {
+        for _, p := range strings.Split(v, ",") {
+            id, err := strconv.ParseUint(strings.TrimSpace(p), 10, 64)
+            if err != nil {
+                return f, fmt.Errorf("invalid category_id")
+            }
+            f.CategoryIDs = append(f.CategoryIDs, uint(id))
+        }
+    }
+    if v := ctx.Query("min_price"); v != "" {
+        p, err := strconv.ParseFloat(v, 64)
+        if err != nil {
+            return f, fmt.Errorf("invalid min_price")
+        }
+        f.MinPrice = &p
+    }
+    if v := ctx.Query("max_price"); v != "" {
+        p, err := strconv.ParseFloat(v, 64)
+        if err != nil {
+            return f, fmt.Errorf("invalid max_price")
+        }
+        f.MaxPrice = &p
+    }
+    if v := ctx.Query("is_available"); v != "" {
+        b, err := strconv.ParseBool(v)
+        if err != nil {
+            return f, fmt.Errorf("invalid is_available")
+        }
+        f.IsAvailable = &b
+    }
+    if v := ctx.Query("tag"); v != "" {
+        for _, p := range strings.Split(v, ",") {
+            if slug := utils.Slugify(p); slug != "" {
+                f.TagSlugs = append(f.TagSlugs, slug)
+            }
+        }
+    }
+    if v := ctx.Query("exclude_allergens"); v != "" {
+        for _, p := range strings.Split(v, ",") {
+            code := strings.ToLower(strings.TrimSpace(p))
+            if code == "" {
+                continue
+            }
+            if !model.IsAllergen(code) {
+                return f, fmt.Errorf("unknown allergen %q", p)
+            }
+            f.ExcludeAllergens = append(f.ExcludeAllergens, code)
+        }
+    }
+    return f, nil
 }
 
 func (c *MenuController) Get(ctx *gin.Context) {
@@ -55,7 +167,22 @@ func (c *MenuController) Get(ctx *gin.Context) {
         ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
         return
     }
-    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": m})
+    one := []model.Menu{*m}
+    if err := c.i18n.LocalizeMenus(one, requestLocale(ctx)); err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    hideCosts(one)
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": one[0]})
+}
+
+// hideCosts clears cost prices before menus go out on public endpoints; admins read them
+// from GET /api/menus/:id/cost
+func hideCosts(list []model.Menu) {
+    for i := range list {
+        list[i].CostPrice = 0
+        list[i].CostMode = ""
+    }
 }
 
 func (c *MenuController) Update(ctx *gin.Context) {
@@ -103,14 +230,64 @@ func (c *MenuController) Update(ctx *gin.Context) {
     if v, ok := payload["is_available"].(bool); ok {
         existing.IsAvailable = v
     }
+    // an explicit cost price switches the menu to manual costing
+    if v, ok := payload["cost_price"].(float64); ok {
+        existing.CostPrice = v
+        existing.CostMode = model.CostModeManual
+    }
+    if v, ok := payload["cost_mode"].(string); ok {
+        existing.CostMode = v
+    }
+    if v, ok := payload["sku"]; ok {
+        if str, ok := v.(string); ok {
+            existing.SKU = &str
+        } else if v == nil {
+            existing.SKU = nil
+        }
+    }
+    if v, ok := payload["barcodes"].([]interface{}); ok {
+        existing.Barcodes = parseBarcodes(v)
+    }
+    if v, ok := payload["tags"].([]interface{}); ok {
+        existing.Tags = parseTags(v)
+    }
+    if v, ok := payload["allergens"].([]interface{}); ok {
+        existing.Allergens = parseAllergens(v)
+    }
 
     if err := c.svc.Update(existing); err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
         return
     }
     ctx.JSON(http.StatusOK, gin.H{"status":"success","data": existing})
 }
 
+// SetAvailability marks a menu available or sold out ({"is_available": false}); staff may do
+// this at the till, everything else goes through the admin Update
+func (c *MenuController) SetAvailability(ctx *gin.Context) {
+    idStr := ctx.Param("id")
+    var id uint
+    if _, err := fmt.Sscan(idStr, &id); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid id"})
+        return
+    }
+    var req struct{
+        IsAvailable *bool `json:"is_available" binding:"required"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    m, err := c.svc.SetAvailability(id, *req.IsAvailable)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    one := []model.Menu{*m}
+    hideCosts(one)
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": one[0]})
+}
+
 func (c *MenuController) Delete(ctx *gin.Context) {
     idStr := ctx.Param("id")
     var id uint
@@ -124,3 +301,147 @@ func (c *MenuController) Delete(ctx *gin.Context) {
     }
     ctx.JSON(http.StatusOK, gin.H{"status":"success","message":"deleted"})
 }
+
+// Export downloads all menus as CSV or XLSX (query param: format=csv|xlsx, default csv)
+func (c *MenuController) Export(ctx *gin.Context) {
+    format := ctx.DefaultQuery("format", "csv")
+    data, err := c.importSvc.Export(format)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    contentType := "text/csv"
+    if format == "xlsx" {
+        contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+    }
+    ctx.Header("Content-Disposition", "attachment; filename=menus."+format)
+    ctx.Data(http.StatusOK, contentType, data)
+}
+
+// Import upserts menus from a CSV or XLSX file (multipart field: file).
+// Query params: format=csv|xlsx (defaults to the file extension), dry_run=true to only validate.
+func (c *MenuController) Import(ctx *gin.Context) {
+    fh, err := ctx.FormFile("file")
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"file is required"})
+        return
+    }
+    format := ctx.Query("format")
+    if format == "" {
+        format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fh.Filename)), ".")
+    }
+    dryRun, _ := strconv.ParseBool(ctx.Query("dry_run"))
+
+    f, err := fh.Open()
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"cannot read file"})
+        return
+    }
+    defer f.Close()
+
+    report, err := c.importSvc.Import(f, format, dryRun)
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if report.Failed > 0 {
+        ctx.JSON(http.StatusUnprocessableEntity, gin.H{"status":"error","message":"import has invalid rows, nothing was saved","data": report})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": report})
+}
+
+// parseBarcodes accepts barcodes as plain strings or as {"code", "symbology"} objects
+func parseBarcodes(raw []interface{}) []model.MenuBarcode {
+    out := []model.MenuBarcode{}
+    for _, item := range raw {
+        switch v := item.(type) {
+        case string:
+            out = append(out, model.MenuBarcode{Code: v})
+        case map[string]interface{}:
+            code, _ := v["code"].(string)
+            sym, _ := v["symbology"].(string)
+            out = append(out, model.MenuBarcode{Code: code, Symbology: sym})
+        }
+    }
+    return out
+}
+
+// parseTags accepts tag names or {"name": ...} objects
+func parseTags(raw []interface{}) []model.Tag {
+    out := []model.Tag{}
+    for _, item := range raw {
+        switch v := item.(type) {
+        case string:
+            out = append(out, model.Tag{Name: v})
+        case map[string]interface{}:
+            name, _ := v["name"].(string)
+            out = append(out, model.Tag{Name: name})
+        }
+    }
+    return out
+}
+
+// parseAllergens accepts allergen codes or {"code": ...} objects
+func parseAllergens(raw []interface{}) []model.MenuAllergen {
+    out := []model.MenuAllergen{}
+    for _, item := range raw {
+        switch v := item.(type) {
+        case string:
+            out = append(out, model.MenuAllergen{Code: v})
+        case map[string]interface{}:
+            code, _ := v["code"].(string)
+            out = append(out, model.MenuAllergen{Code: code})
+        }
+    }
+    return out
+}
+
+// Lookup finds a menu for scanner input (query param: barcode=... or sku=...)
+func (c *MenuController) Lookup(ctx *gin.Context) {
+    var m *model.Menu
+    var err error
+    if code := ctx.Query("barcode"); code != "" {
+        m, err = c.svc.Lookup(code)
+    } else if sku := ctx.Query("sku"); sku != "" {
+        m, err = c.svc.LookupSKU(sku)
+    } else {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"barcode or sku is required"})
+        return
+    }
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if m == nil {
+        ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
+        return
+    }
+    m.CostPrice, m.CostMode = 0, ""
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": m})
+}
+
+// Labels returns a PDF sheet of barcode labels.
+// Query params: ids=1,2,3 (required), copies (per menu, default 1), symbology=auto|ean13|code128
+func (c *MenuController) Labels(ctx *gin.Context) {
+    var ids []uint
+    for _, p := range strings.Split(ctx.Query("ids"), ",") {
+        if p = strings.TrimSpace(p); p == "" {
+            continue
+        }
+        id, err := strconv.ParseUint(p, 10, 64)
+        if err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid ids"})
+            return
+        }
+        ids = append(ids, uint(id))
+    }
+    copies, _ := strconv.Atoi(ctx.DefaultQuery("copies", "1"))
+    data, err := c.svc.LabelsPDF(ids, copies, ctx.DefaultQuery("symbology", "auto"))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.Header("Content-Disposition", "attachment; filename=labels.pdf")
+    ctx.Data(http.StatusOK, "application/pdf", data)
+}
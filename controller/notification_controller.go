@@ -17,6 +17,11 @@ func NewNotificationController() *NotificationController {
 
 // Stream opens an SSE stream to push notifications to the client
 func (c *NotificationController) Stream(ctx *gin.Context) {
+	streamEvents(ctx, "")
+}
+
+// streamEvents pushes the notifications of topic (all when empty) to the client as SSE
+func streamEvents(ctx *gin.Context, topic string) {
 	w := ctx.Writer
 	r := ctx.Request
 
@@ -31,7 +36,7 @@ func (c *NotificationController) Stream(ctx *gin.Context) {
 	}
 
 	ch := make(chan string)
-	utils.NotifierInstance.AddClient(ch)
+	utils.NotifierInstance.AddTopicClient(ch, topic)
 	defer utils.NotifierInstance.RemoveClient(ch)
 
 	// send a welcome event
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/gateway"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentController handles gateway webhooks and pending QRIS payments
+type PaymentController struct{
+    svc service.PaymentService
+}
+
+func NewPaymentController(s service.PaymentService) *PaymentController {
+    return &PaymentController{svc: s}
+}
+
+// webhookMaxBytes bounds a gateway notification body
+const webhookMaxBytes = 64 << 10
+
+// Webhook receives settlement notifications; the signature is the only authentication
+func (c *PaymentController) Webhook(ctx *gin.Context) {
+    body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, webhookMaxBytes))
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if err := c.svc.HandleWebhook(ctx.Request.Header, body); err != nil {
+        status := errorStatus(err)
+        if errors.Is(err, gateway.ErrSignature) {
+            status = http.StatusUnauthorized
+        }
+        ctx.JSON(status, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// QRCode renders the dynamic QRIS code of a pending payment (PNG, ?size=)
+func (c *PaymentController) QRCode(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    size, _ := strconv.Atoi(ctx.Query("size"))
+    png, err := c.svc.QRCode(id, size)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.Header("Cache-Control", "no-store")
+    ctx.Data(http.StatusOK, "image/png", png)
+}
+
+// Cancel gives up the pending QRIS payment of a transaction (:id is the transaction)
+func (c *PaymentController) Cancel(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.Cancel(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// Simulate settles (or fails) a payment with the mock gateway, for development
+func (c *PaymentController) Simulate(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req struct {
+        Status string `json:"status"`
+    }
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if err := c.svc.Simulate(id, req.Status); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
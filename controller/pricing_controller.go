@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// PricingController manages price rules and reports the prices they currently set
+type PricingController struct{
+    svc service.PricingService
+}
+
+func NewPricingController(s service.PricingService) *PricingController {
+    return &PricingController{svc: s}
+}
+
+// Current lists menus whose price a rule changes right now (e.g. during a happy hour)
+func (c *PricingController) Current(ctx *gin.Context) {
+    list, err := c.svc.Current()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.Header("Cache-Control", "no-cache")
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *PricingController) List(ctx *gin.Context) {
+    list, err := c.svc.ListRules()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *PricingController) Create(ctx *gin.Context) {
+    var req dto.PriceRuleRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    r, err := c.svc.CreateRule(&req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": r})
+}
+
+func (c *PricingController) Update(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.PriceRuleRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    r, err := c.svc.UpdateRule(id, &req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": r})
+}
+
+func (c *PricingController) Delete(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.DeleteRule(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
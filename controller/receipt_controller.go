@@ -0,0 +1,80 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ReceiptController serves the customer receipts of transactions
+type ReceiptController struct{
+    svc service.ReceiptService
+}
+
+func NewReceiptController(s service.ReceiptService) *ReceiptController {
+    return &ReceiptController{svc: s}
+}
+
+// Print returns the receipt of a transaction.
+// Query params: format=text|escpos|pdf (default text), width=58|80 (mm, default RECEIPT_WIDTH_MM)
+func (c *ReceiptController) Print(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    width := 0
+    if w := ctx.Query("width"); w != "" {
+        var err error
+        if width, err = strconv.Atoi(w); err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid width"})
+            return
+        }
+    }
+    format := ctx.DefaultQuery("format", service.ReceiptText)
+    data, contentType, err := c.svc.Render(id, format, width)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    switch format {
+    case service.ReceiptPDF:
+        ctx.Header("Content-Disposition", fmt.Sprintf("inline; filename=receipt-%d.pdf", id))
+    case service.ReceiptESCPOS:
+        ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=receipt-%d.bin", id))
+    }
+    ctx.Data(http.StatusOK, contentType, data)
+}
+
+// Link returns the public receipt link of a transaction ({"token", "url"})
+func (c *ReceiptController) Link(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    link, err := c.svc.Link(id)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": link})
+}
+
+// Public serves the receipt page of a public link (query param format=html|pdf, default html)
+func (c *ReceiptController) Public(ctx *gin.Context) {
+    format := ctx.DefaultQuery("format", service.ReceiptHTML)
+    data, contentType, err := c.svc.Public(ctx.Param("token"), format)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    // links are revoked on void, so nothing may keep a copy
+    ctx.Header("Cache-Control", "no-store")
+    ctx.Header("X-Robots-Tag", "noindex")
+    if format == service.ReceiptPDF {
+        ctx.Header("Content-Disposition", "inline; filename=receipt.pdf")
+    }
+    ctx.Data(http.StatusOK, contentType, data)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RefundController voids and refunds transactions; a manager approves each with their PIN
+// or their token in the X-Approval-Token header
+type RefundController struct{
+    svc service.RefundService
+}
+
+func NewRefundController(s service.RefundService) *RefundController {
+    return &RefundController{svc: s}
+}
+
+func (c *RefundController) Void(ctx *gin.Context) {
+    c.reverse(ctx, c.svc.Void)
+}
+
+func (c *RefundController) Refund(ctx *gin.Context) {
+    c.reverse(ctx, c.svc.Refund)
+}
+
+func (c *RefundController) reverse(ctx *gin.Context, fn func(uint, *dto.RefundRequest, *uint) (*model.Refund, error)) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.RefundRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    req.ApprovalToken = ctx.GetHeader("X-Approval-Token")
+    rf, err := fn(id, &req, currentUserID(ctx))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": rf})
+}
+
+func (c *RefundController) List(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    list, err := c.svc.List(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/middleware"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SelfOrderController serves the customer endpoints behind a table QR code (no login, the
+// table token is the credential) and the cashier queue of pending orders
+type SelfOrderController struct{
+    svc    service.SelfOrderService
+    tables service.TableService
+}
+
+func NewSelfOrderController(s service.SelfOrderService, tables service.TableService) *SelfOrderController {
+    return &SelfOrderController{svc: s, tables: tables}
+}
+
+// Table returns the table a QR token belongs to
+func (c *SelfOrderController) Table(ctx *gin.Context) {
+    t, err := c.tables.Resolve(ctx.Param("token"))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": dto.PublicTable{ID: t.ID, Name: t.Name}})
+}
+
+// Submit places a customer order for the table of the token
+func (c *SelfOrderController) Submit(ctx *gin.Context) {
+    var req dto.SelfOrderRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    o, err := c.svc.Submit(ctx.Param("token"), &req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": dto.SelfOrderStatus{ID: o.ID, Status: o.Status, Total: o.Total}})
+}
+
+// Status lets the customer poll an order placed from the same table
+func (c *SelfOrderController) Status(ctx *gin.Context) {
+    var id uint
+    if _, err := fmt.Sscan(ctx.Param("id"), &id); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid id"})
+        return
+    }
+    st, err := c.svc.Status(ctx.Param("token"), id)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": st})
+}
+
+// List returns the cashier queue (query param status, default pending; "all" for every order)
+func (c *SelfOrderController) List(ctx *gin.Context) {
+    status := ctx.DefaultQuery("status", model.SelfOrderPending)
+    if status == "all" {
+        status = ""
+    }
+    list, err := c.svc.List(status)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *SelfOrderController) Get(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    o, err := c.svc.GetByID(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if o == nil {
+        ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": o})
+}
+
+// Accept converts a pending order into a transaction, priced with the current menu prices
+func (c *SelfOrderController) Accept(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.SelfOrderAcceptRequest
+    if ctx.Request.ContentLength != 0 {
+        if err := ctx.ShouldBindJSON(&req); err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+            return
+        }
+    }
+    t, err := c.svc.Accept(id, currentUserID(ctx), &req)
+    if err != nil {
+        checkoutError(ctx, t, err)
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": t})
+}
+
+func (c *SelfOrderController) Reject(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.SelfOrderRejectRequest
+    if ctx.Request.ContentLength != 0 {
+        if err := ctx.ShouldBindJSON(&req); err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+            return
+        }
+    }
+    if err := c.svc.Reject(id, currentUserID(ctx), req.Reason); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// currentUserID returns the id of the logged in user, nil on public routes
+func currentUserID(ctx *gin.Context) *uint {
+    if v, exists := ctx.Get(middleware.ContextUserKey); exists {
+        if u, ok := v.(*model.User); ok {
+            return &u.ID
+        }
+    }
+    return nil
+}
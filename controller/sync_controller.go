@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// SyncController serves offline devices: uploading sales and refreshing the catalog
+type SyncController struct{
+    tx   service.TransactionService
+    sync service.SyncService
+}
+
+func NewSyncController(tx service.TransactionService, s service.SyncService) *SyncController {
+    return &SyncController{tx: tx, sync: s}
+}
+
+// Transactions stores a batch of offline sales and reports the outcome of each one
+func (c *SyncController) Transactions(ctx *gin.Context) {
+    var req dto.SyncTransactionsRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+        return
+    }
+    outcomes, err := c.tx.Sync(req.Transactions, currentUserID(ctx))
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status": "error", "message": err.Error()})
+        return
+    }
+    results := make([]dto.SyncResult, len(outcomes))
+    counts := map[string]int{"created": 0, "duplicate": 0, "rejected": 0}
+    for i, o := range outcomes {
+        r := dto.SyncResult{ClientUUID: o.ClientUUID}
+        switch {
+        case o.Err != nil:
+            r.Status = "rejected"
+            r.Code = errorStatus(o.Err)
+            r.Message = o.Err.Error()
+        case o.Duplicate:
+            r.Status = "duplicate"
+            r.Code = http.StatusOK
+            r.TransactionID = o.Transaction.ID
+            r.Transaction = o.Transaction
+        default:
+            r.Status = "created"
+            r.Code = http.StatusCreated
+            r.TransactionID = o.Transaction.ID
+            r.Transaction = o.Transaction
+        }
+        counts[r.Status]++
+        results[i] = r
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"results": results, "summary": counts}})
+}
+
+// Catalog returns the catalog changes since the query param since (RFC 3339, the
+// server_time of the previous sync); without it the full catalog
+func (c *SyncController) Catalog(ctx *gin.Context) {
+    var since *time.Time
+    if v := ctx.Query("since"); v != "" {
+        t, err := time.Parse(time.RFC3339, v)
+        if err != nil {
+            ctx.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "since must be an RFC 3339 time"})
+            return
+        }
+        since = &t
+    }
+    ch, err := c.sync.Catalog(since)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status": "error", "message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status": "success", "data": ch})
+}
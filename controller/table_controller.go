@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TableController manages dining tables and their QR codes
+type TableController struct{
+    svc service.TableService
+}
+
+func NewTableController(s service.TableService) *TableController {
+    return &TableController{svc: s}
+}
+
+func (c *TableController) List(ctx *gin.Context) {
+    list, err := c.svc.List()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *TableController) Create(ctx *gin.Context) {
+    var req dto.TableRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t, err := c.svc.Create(&req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": gin.H{"table": t, "link": c.svc.Link(t)}})
+}
+
+func (c *TableController) Update(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req dto.TableRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t, err := c.svc.Update(id, &req)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": t})
+}
+
+func (c *TableController) Delete(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.Delete(id); err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// Link returns the signed token and ordering URL of a table
+func (c *TableController) Link(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    t, err := c.svc.GetByID(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    if t == nil {
+        ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": c.svc.Link(t)})
+}
+
+// RotateToken replaces the token of a table; previously printed QR codes stop working
+func (c *TableController) RotateToken(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    link, err := c.svc.RotateToken(id)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": link})
+}
+
+// QRCode returns the ordering URL of a table as PNG (query param size, default 512 px)
+func (c *TableController) QRCode(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    size, _ := strconv.Atoi(ctx.Query("size"))
+    png, err := c.svc.QRCode(id, size)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.Data(http.StatusOK, "image/png", png)
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TagController manages menu tags and lists the fixed allergens
+type TagController struct{
+    svc service.TagService
+}
+
+func NewTagController(s service.TagService) *TagController {
+    return &TagController{svc: s}
+}
+
+type tagRequest struct {
+    Name string `json:"name" binding:"required"`
+}
+
+func (c *TagController) List(ctx *gin.Context) {
+    list, err := c.svc.List()
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *TagController) Create(ctx *gin.Context) {
+    var req tagRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t, err := c.svc.Create(req.Name)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": t})
+}
+
+func (c *TagController) Update(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req tagRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t, err := c.svc.Update(id, req.Name)
+    if err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": t})
+}
+
+func (c *TagController) Delete(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.Delete(id); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success"})
+}
+
+// Allergens returns the fixed allergen list labelled in the request language
+func (c *TagController) Allergens(ctx *gin.Context) {
+    locale := requestLocale(ctx)
+    out := make([]model.MenuAllergen, 0, len(model.Allergens))
+    for _, a := range model.Allergens {
+        out = append(out, model.MenuAllergen{Code: a.Code, Name: model.AllergenName(a.Code, locale, config.DefaultLocale())})
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": out})
+}
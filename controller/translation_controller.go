@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/gin-gonic/gin"
+)
+
+// TranslationController manages per-locale names and descriptions (admin only)
+type TranslationController struct{
+    svc service.TranslationService
+}
+
+func NewTranslationController(s service.TranslationService) *TranslationController {
+    return &TranslationController{svc: s}
+}
+
+type translationRequest struct {
+    Name        string `json:"name"`
+    Description string `json:"description"`
+}
+
+func parseID(ctx *gin.Context) (uint, bool) {
+    var id uint
+    if _, err := fmt.Sscan(ctx.Param("id"), &id); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"invalid id"})
+        return 0, false
+    }
+    return id, true
+}
+
+func (c *TranslationController) ListMenu(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    list, err := c.svc.ListMenu(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+// SetMenu creates or replaces the translation of a menu for :locale
+func (c *TranslationController) SetMenu(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req translationRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t := model.MenuTranslation{MenuID: id, Locale: ctx.Param("locale"), Name: req.Name, Description: req.Description}
+    if err := c.svc.SetMenu(&t); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": t})
+}
+
+func (c *TranslationController) DeleteMenu(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.DeleteMenu(id, ctx.Param("locale")); err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","message":"deleted"})
+}
+
+func (c *TranslationController) ListCategory(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    list, err := c.svc.ListCategory(id)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": list})
+}
+
+func (c *TranslationController) SetCategory(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    var req translationRequest
+    if err := ctx.ShouldBindJSON(&req); err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    t := model.CategoryTranslation{CategoryID: id, Locale: ctx.Param("locale"), Name: req.Name}
+    if err := c.svc.SetCategory(&t); err != nil {
+        ctx.JSON(errorStatus(err), gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": t})
+}
+
+func (c *TranslationController) DeleteCategory(ctx *gin.Context) {
+    id, ok := parseID(ctx)
+    if !ok {
+        return
+    }
+    if err := c.svc.DeleteCategory(id, ctx.Param("locale")); err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","message":"deleted"})
+}
@@ -1,46 +1,154 @@
 package controller
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/middleware"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/service"
+	"github.com/IndalAwalaikal/warung-pos/backend/storage"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
 	"github.com/gin-gonic/gin"
 )
 
-type UploadController struct{}
+type UploadController struct{
+    store storage.Storage
+    svc   service.UploadService
+}
 
-func NewUploadController() *UploadController { return &UploadController{} }
+func NewUploadController(store storage.Storage, s service.UploadService) *UploadController {
+    return &UploadController{store: store, svc: s}
+}
 
-// Upload handles multipart file upload (field name: file)
-// returns URL path for the uploaded file
+// Upload handles multipart image upload (field name: file).
+// The image is validated by content, EXIF-stripped and stored as original/medium/thumbnail
+// JPEG renditions; "url" in the response is the original rendition.
 func (u *UploadController) Upload(ctx *gin.Context) {
+    opts := config.UploadImageOptions()
+    // allow some room for the multipart envelope on top of the file itself
+    ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, opts.MaxBytes+1<<20)
+
     file, err := ctx.FormFile("file")
     if err != nil {
+        var maxErr *http.MaxBytesError
+        if errors.As(err, &maxErr) {
+            ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"status":"error","message": utils.ErrImageTooLarge.Error()})
+            return
+        }
         ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"file is required"})
         return
     }
+    if file.Size > opts.MaxBytes {
+        ctx.JSON(http.StatusRequestEntityTooLarge, gin.H{"status":"error","message": utils.ErrImageTooLarge.Error()})
+        return
+    }
+    f, err := file.Open()
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"cannot read file"})
+        return
+    }
+    data, err := io.ReadAll(io.LimitReader(f, opts.MaxBytes+1))
+    f.Close()
+    if err != nil {
+        ctx.JSON(http.StatusBadRequest, gin.H{"status":"error","message":"cannot read file"})
+        return
+    }
+
+    images, err := utils.ProcessImage(data, opts)
+    if err != nil {
+        code := http.StatusBadRequest
+        if errors.Is(err, utils.ErrImageTooLarge) || errors.Is(err, utils.ErrImageTooManyPx) {
+            code = http.StatusRequestEntityTooLarge
+        } else if !errors.Is(err, utils.ErrUnsupportedImage) {
+            code = http.StatusInternalServerError
+        }
+        ctx.JSON(code, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+
+    // unique base name, the client's filename and extension are ignored
+    base := fmt.Sprintf("%d", time.Now().UnixNano())
+    renditions := gin.H{}
+    var publicURL string
+    upload := model.Upload{}
+    if v, exists := ctx.Get(middleware.ContextUserKey); exists {
+        if usr, ok := v.(*model.User); ok {
+            upload.OwnerID = &usr.ID
+        }
+    }
+    for _, img := range images {
+        name := base + img.Ext
+        if img.Name != "original" {
+            name = base + "_" + img.Name + img.Ext
+        }
+        if err := u.store.Put(ctx.Request.Context(), name, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType); err != nil {
+            u.discard(upload.Files)
+            ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message":"failed to save file"})
+            return
+        }
+        url := u.store.URL(name)
+        upload.Files = append(upload.Files, model.UploadFile{Rendition: img.Name, Key: name, URL: url, Size: int64(len(img.Data))})
+        renditions[img.Name] = gin.H{"url": url, "width": img.Width, "height": img.Height}
+        if img.Name == "original" {
+            publicURL = url
+        }
+    }
 
-    // ensure uploads dir exists
-    uploadDir := filepath.Join(".", "uploads")
-    if err := os.MkdirAll(uploadDir, 0755); err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message":"cannot create upload dir"})
+    // track the upload so it can be garbage collected if no menu ends up using it
+    if err := u.svc.Create(&upload); err != nil {
+        u.discard(upload.Files)
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
         return
     }
 
-    // sanitize and create unique filename
-    ext := filepath.Ext(file.Filename)
-    name := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-    dst := filepath.Join(uploadDir, name)
+    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": gin.H{"id": upload.ID, "url": publicURL, "renditions": renditions}})
+}
+
+// discard removes already stored files of a failed upload
+func (u *UploadController) discard(files []model.UploadFile) {
+    for _, f := range files {
+        u.store.Delete(context.Background(), f.Key)
+    }
+}
+
+// Orphans reports unattached uploads older than the grace period without deleting them
+func (u *UploadController) Orphans(ctx *gin.Context) {
+    report, err := u.svc.Cleanup(true)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
+        return
+    }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": report})
+}
 
-    if err := ctx.SaveUploadedFile(file, dst); err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message":"failed to save file"})
+// Cleanup deletes unattached uploads older than the grace period (query param dry_run=true to only report)
+func (u *UploadController) Cleanup(ctx *gin.Context) {
+    dryRun, _ := strconv.ParseBool(ctx.Query("dry_run"))
+    report, err := u.svc.Cleanup(dryRun)
+    if err != nil {
+        ctx.JSON(http.StatusInternalServerError, gin.H{"status":"error","message": err.Error()})
         return
     }
+    ctx.JSON(http.StatusOK, gin.H{"status":"success","data": report})
+}
 
-    // return public path
-    publicURL := "/uploads/" + name
-    ctx.JSON(http.StatusCreated, gin.H{"status":"success","data": gin.H{"url": publicURL}})
+// Serve redirects /files/<key> to a short-lived signed URL of the storage backend.
+// It is used when objects live in a private bucket so persisted URLs never expire.
+func (u *UploadController) Serve(ctx *gin.Context) {
+    key := ctx.Param("key")
+    signed, err := u.store.SignedURL(key, 0)
+    if err != nil {
+        ctx.JSON(http.StatusNotFound, gin.H{"status":"error","message":"not found"})
+        return
+    }
+    ctx.Header("Cache-Control", "private, max-age=60")
+    ctx.Redirect(http.StatusFound, signed)
 }
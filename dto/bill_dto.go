@@ -0,0 +1,55 @@
+package dto
+
+type BillItemDTO struct {
+	MenuID   uint   `json:"menu_id" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Seat     int    `json:"seat"`
+	Note     string `json:"note"`
+}
+
+// BillRequest opens a bill, optionally on a table and with the first round of items
+type BillRequest struct {
+	TableID *uint         `json:"table_id"`
+	Name    string        `json:"name"`
+	Guests  int           `json:"guests"`
+	Note    string        `json:"note"`
+	Items   []BillItemDTO `json:"items" binding:"dive"`
+}
+
+type BillItemsRequest struct {
+	Items []BillItemDTO `json:"items" binding:"required,dive"`
+}
+
+type BillTransferRequest struct {
+	TableID uint `json:"table_id" binding:"required"`
+}
+
+type BillMergeRequest struct {
+	// BillID is the bill whose items move into this one
+	BillID uint `json:"bill_id" binding:"required"`
+}
+
+// BillSplitLine moves Quantity units of a bill line (all when 0)
+type BillSplitLine struct {
+	BillItemID uint `json:"bill_item_id" binding:"required"`
+	Quantity   int  `json:"quantity"`
+}
+
+// BillSplitRequest moves the given lines and/or every line of the given seats into a new
+// bill on the same table
+type BillSplitRequest struct {
+	Items []BillSplitLine `json:"items" binding:"dive"`
+	Seats []int           `json:"seats"`
+	Name  string          `json:"name"`
+}
+
+// BillSettleRequest carries the payment collected when the guests pay; Total is compared
+// with the server's like on POST /api/transactions
+type BillSettleRequest struct {
+	PaymentMethod string       `json:"payment_method"`
+	AmountPaid    float64      `json:"amount_paid"`
+	Discount      float64      `json:"discount"`
+	VoucherCodes  []string     `json:"voucher_codes"`
+	Payments      []PaymentDTO `json:"payments"`
+	Total         float64      `json:"total"`
+}
@@ -0,0 +1,37 @@
+package dto
+
+type IngredientRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	Unit        string  `json:"unit"`
+	CostPerUnit float64 `json:"cost_per_unit"`
+}
+
+type RecipeLineRequest struct {
+	IngredientID uint    `json:"ingredient_id" binding:"required"`
+	Quantity     float64 `json:"quantity" binding:"required"`
+}
+
+type RecipeRequest struct {
+	Ingredients []RecipeLineRequest `json:"ingredients" binding:"dive"`
+}
+
+type RecipeLine struct {
+	IngredientID uint    `json:"ingredient_id"`
+	Name         string  `json:"name"`
+	Unit         string  `json:"unit"`
+	Quantity     float64 `json:"quantity"`
+	CostPerUnit  float64 `json:"cost_per_unit"`
+	Cost         float64 `json:"cost"`
+}
+
+// MenuCost is the cost breakdown and margin of one menu
+type MenuCost struct {
+	MenuID      uint         `json:"menu_id"`
+	Name        string       `json:"name"`
+	Price       float64      `json:"price"`
+	CostPrice   float64      `json:"cost_price"`
+	CostMode    string       `json:"cost_mode"`
+	GrossProfit float64      `json:"gross_profit"`
+	Margin      float64      `json:"margin"` // percent of the price
+	Recipe      []RecipeLine `json:"recipe"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type KitchenStationRequest struct {
+	Name string `json:"name" binding:"required"`
+	// CategoryIDs are the categories whose menus the station prepares
+	CategoryIDs []uint `json:"category_ids"`
+	IsDefault   bool   `json:"is_default"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+type TicketStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
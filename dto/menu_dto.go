@@ -18,3 +18,27 @@ type MenuResponse struct {
 	ImageURL    string  `json:"image_url"`
 	IsAvailable bool    `json:"is_available"`
 }
+
+// MenuImportRow is the validation result of one data row in an import file
+type MenuImportRow struct {
+	Row      int      `json:"row"`
+	SKU      string   `json:"sku,omitempty"`
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Action   string   `json:"action"` // create, update or error
+	MenuID   uint     `json:"menu_id,omitempty"`
+	ImageURL string   `json:"-"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// MenuImportReport summarises a menu import (or dry-run)
+type MenuImportReport struct {
+	DryRun            bool            `json:"dry_run"`
+	Committed         bool            `json:"committed"`
+	Total             int             `json:"total"`
+	Created           int             `json:"created"`
+	Updated           int             `json:"updated"`
+	Failed            int             `json:"failed"`
+	CategoriesCreated []string        `json:"categories_created"`
+	Rows              []MenuImportRow `json:"rows"`
+}
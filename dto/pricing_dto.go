@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type PriceRuleRequest struct {
+	Name       string     `json:"name" binding:"required"`
+	MenuID     *uint      `json:"menu_id"`
+	CategoryID *uint      `json:"category_id"`
+	Weekdays   string     `json:"weekdays"`
+	StartTime  string     `json:"start_time"`
+	EndTime    string     `json:"end_time"`
+	ValidFrom  *time.Time `json:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until"`
+	Kind       string     `json:"kind" binding:"required"`
+	Value      float64    `json:"value"`
+	Priority   int        `json:"priority"`
+	IsActive   *bool      `json:"is_active"`
+}
+
+// PriceQuote is the price of a menu at a moment, with the rule that produced it
+type PriceQuote struct {
+	MenuID        uint    `json:"menu_id"`
+	BasePrice     float64 `json:"base_price"`
+	Price         float64 `json:"price"`
+	PriceRuleID   *uint   `json:"price_rule_id"`
+	PriceRuleName string  `json:"price_rule_name,omitempty"`
+}
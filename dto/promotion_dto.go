@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+type PromotionRequest struct {
+	Name        string     `json:"name" binding:"required"`
+	Code        string     `json:"code"`
+	Kind        string     `json:"kind" binding:"required"`
+	Value       float64    `json:"value"`
+	MaxDiscount float64    `json:"max_discount"`
+	BuyQuantity int        `json:"buy_quantity"`
+	GetQuantity int        `json:"get_quantity"`
+	MinSpend    float64    `json:"min_spend"`
+	CategoryID  *uint      `json:"category_id"`
+	Stackable   bool       `json:"stackable"`
+	UsageLimit  int        `json:"usage_limit"`
+	ValidFrom   *time.Time `json:"valid_from"`
+	ValidUntil  *time.Time `json:"valid_until"`
+	IsActive    *bool      `json:"is_active"`
+}
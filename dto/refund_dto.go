@@ -0,0 +1,18 @@
+package dto
+
+type RefundItemDTO struct {
+	TransactionItemID uint `json:"transaction_item_id" binding:"required"`
+	Quantity          int  `json:"quantity" binding:"required"`
+}
+
+// RefundRequest voids or refunds a transaction; without Items everything not yet reversed
+// is. A manager approves with ManagerID and ManagerPIN or their token in the
+// X-Approval-Token header.
+type RefundRequest struct {
+	Reason        string          `json:"reason" binding:"required"`
+	Items         []RefundItemDTO `json:"items"`
+	Method        string          `json:"method"`
+	ManagerID     uint            `json:"manager_id"`
+	ManagerPIN    string          `json:"manager_pin"`
+	ApprovalToken string          `json:"-"`
+}
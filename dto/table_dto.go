@@ -0,0 +1,55 @@
+package dto
+
+type TableRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Seats    int    `json:"seats"`
+	IsActive *bool  `json:"is_active"`
+}
+
+// TableLink is what gets printed on a table: the signed token and the ordering page URL
+type TableLink struct {
+	TableID uint   `json:"table_id"`
+	Name    string `json:"name"`
+	Token   string `json:"token"`
+	URL     string `json:"url"`
+}
+
+// PublicTable is the table as shown to customers
+type PublicTable struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+type SelfOrderItemDTO struct {
+	MenuID   uint   `json:"menu_id" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required"`
+	Note     string `json:"note"`
+}
+
+type SelfOrderRequest struct {
+	CustomerName string             `json:"customer_name"`
+	Note         string             `json:"note"`
+	Items        []SelfOrderItemDTO `json:"items" binding:"required,dive,required"`
+}
+
+// SelfOrderAcceptRequest carries the payment details the cashier collects on accept
+type SelfOrderAcceptRequest struct {
+	PaymentMethod string   `json:"payment_method"`
+	AmountPaid    float64  `json:"amount_paid"`
+	Discount      float64  `json:"discount"`
+	VoucherCodes  []string `json:"voucher_codes"`
+	// Payments splits the payment like on POST /api/transactions
+	Payments      []PaymentDTO `json:"payments"`
+}
+
+type SelfOrderRejectRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SelfOrderStatus is the order as shown to the customer who placed it
+type SelfOrderStatus struct {
+	ID           uint    `json:"id"`
+	Status       string  `json:"status"`
+	Total        float64 `json:"total"`
+	RejectReason string  `json:"reject_reason,omitempty"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+type TaxRuleRequest struct {
+	Name      string  `json:"name" binding:"required"`
+	Kind      string  `json:"kind"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+	Compound  bool    `json:"compound"`
+	Sequence  int     `json:"sequence"`
+	IsActive  *bool   `json:"is_active"`
+}
@@ -1,19 +1,37 @@
 package dto
 
+import "time"
+
 type TransactionItemDTO struct {
 	MenuID   uint    `json:"menu_id" binding:"required"`
 	Quantity int     `json:"quantity" binding:"required"`
 	Price    float64 `json:"price" binding:"required"`
+	Note     string  `json:"note"`
+}
+
+// PaymentDTO is one tender of a split payment; for cash Amount is the cash handed over
+type PaymentDTO struct {
+	Method    string  `json:"method" binding:"required"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
 }
 
+// TransactionCreateRequest is priced by the server. Subtotal, Tax, ServiceCharge and Total
+// are only compared with the server's figures (409 on mismatch); Discount is a manual discount
+// on top of the promotions and VoucherCodes.
 type TransactionCreateRequest struct {
 	Items         []TransactionItemDTO `json:"items" binding:"required,dive,required"`
 	Subtotal      float64               `json:"subtotal"`
 	Tax           float64               `json:"tax"`
+	ServiceCharge float64               `json:"service_charge"`
 	Discount      float64               `json:"discount"`
+	VoucherCodes  []string              `json:"voucher_codes"`
 	Total         float64               `json:"total"`
 	PaymentMethod string                `json:"payment_method"`
 	AmountPaid    float64               `json:"amount_paid"`
+	// Payments splits the payment across tenders; without it PaymentMethod/AmountPaid is
+	// the single tender
+	Payments      []PaymentDTO          `json:"payments"`
 	CashierID     uint                  `json:"cashier_id"`
 }
 
@@ -22,3 +40,27 @@ type TransactionResponse struct {
 	Items interface{} `json:"items"`
 	Total float64     `json:"total"`
 }
+
+// SyncTransaction is a sale recorded by a device while offline. ClientUUID identifies it
+// across retries, CreatedAt is the device's time of sale.
+type SyncTransaction struct {
+	ClientUUID string    `json:"client_uuid"`
+	CreatedAt  time.Time `json:"created_at"`
+	TransactionCreateRequest
+}
+
+// SyncTransactionsRequest uploads offline sales; each one is validated and stored on its own
+type SyncTransactionsRequest struct {
+	Transactions []SyncTransaction `json:"transactions" binding:"required"`
+}
+
+// SyncResult is the outcome of one uploaded sale: created, duplicate (stored by an earlier
+// upload) or rejected, with the HTTP-like Code and Message of the failure
+type SyncResult struct {
+	ClientUUID    string      `json:"client_uuid"`
+	Status        string      `json:"status"`
+	TransactionID uint        `json:"transaction_id,omitempty"`
+	Code          int         `json:"code"`
+	Message       string      `json:"message,omitempty"`
+	Transaction   interface{} `json:"transaction,omitempty"`
+}
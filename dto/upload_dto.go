@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+type UploadCleanupItem struct {
+	ID        uint      `json:"id"`
+	OwnerID   *uint     `json:"owner_id"`
+	URLs      []string  `json:"urls"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UploadCleanupReport lists unattached uploads past the grace period and what was done with them
+type UploadCleanupReport struct {
+	DryRun     bool                `json:"dry_run"`
+	GraceHours int                 `json:"grace_hours"`
+	Before     time.Time           `json:"before"`
+	Candidates []UploadCleanupItem `json:"candidates"`
+	Deleted    int                 `json:"deleted"`
+	Reattached int                 `json:"reattached"`
+	FreedBytes int64               `json:"freed_bytes"`
+	Errors     []string            `json:"errors,omitempty"`
+}
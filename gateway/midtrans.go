@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Midtrans uses the Core API charge endpoint with payment_type qris. Webhooks are verified
+// with signature_key = SHA512(order_id + status_code + gross_amount + server key).
+type Midtrans struct {
+	baseURL   string
+	serverKey string
+	client    *http.Client
+}
+
+func NewMidtrans(baseURL, serverKey string) *Midtrans {
+	return &Midtrans{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		serverKey: serverKey,
+		client:    &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *Midtrans) Name() string {
+	return "midtrans"
+}
+
+// midtransTime is the format of expiry_time (Asia/Jakarta)
+const midtransTime = "2006-01-02 15:04:05"
+
+func (g *Midtrans) CreateQRIS(ctx context.Context, c Charge) (*ChargeResult, error) {
+	minutes := int(math.Ceil(time.Until(c.ExpiresAt).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	req := map[string]interface{}{
+		"payment_type": "qris",
+		"transaction_details": map[string]interface{}{
+			"order_id":     c.OrderID,
+			"gross_amount": int64(math.Round(c.Amount)),
+		},
+		"custom_expiry": map[string]interface{}{
+			"expiry_duration": minutes,
+			"unit":            "minute",
+		},
+	}
+	var res struct {
+		StatusCode    string `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+		TransactionID string `json:"transaction_id"`
+		QRString      string `json:"qr_string"`
+		ExpiryTime    string `json:"expiry_time"`
+	}
+	if err := g.call(ctx, http.MethodPost, "/v2/charge", req, &res); err != nil {
+		return nil, err
+	}
+	if res.StatusCode != "201" {
+		return nil, fmt.Errorf("midtrans charge failed: %s %s", res.StatusCode, res.StatusMessage)
+	}
+	out := &ChargeResult{Reference: res.TransactionID, QRString: res.QRString, ExpiresAt: c.ExpiresAt}
+	if loc, err := time.LoadLocation("Asia/Jakarta"); err == nil {
+		if t, err := time.ParseInLocation(midtransTime, res.ExpiryTime, loc); err == nil {
+			out.ExpiresAt = t
+		}
+	}
+	return out, nil
+}
+
+func (g *Midtrans) Cancel(ctx context.Context, orderID string) error {
+	var res struct {
+		StatusCode    string `json:"status_code"`
+		StatusMessage string `json:"status_message"`
+	}
+	if err := g.call(ctx, http.MethodPost, "/v2/"+orderID+"/cancel", nil, &res); err != nil {
+		return err
+	}
+	// 412: the charge can no longer be cancelled (already expired or settled)
+	if res.StatusCode != "200" && res.StatusCode != "412" {
+		return fmt.Errorf("midtrans cancel failed: %s %s", res.StatusCode, res.StatusMessage)
+	}
+	return nil
+}
+
+func (g *Midtrans) ParseWebhook(header http.Header, body []byte) (*Notification, error) {
+	var n struct {
+		OrderID           string `json:"order_id"`
+		TransactionID     string `json:"transaction_id"`
+		TransactionStatus string `json:"transaction_status"`
+		StatusCode        string `json:"status_code"`
+		GrossAmount       string `json:"gross_amount"`
+		SignatureKey      string `json:"signature_key"`
+	}
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, err
+	}
+	sum := sha512.Sum512([]byte(n.OrderID + n.StatusCode + n.GrossAmount + g.serverKey))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(n.SignatureKey)) != 1 {
+		return nil, ErrSignature
+	}
+	amount, err := strconv.ParseFloat(n.GrossAmount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gross_amount %q", n.GrossAmount)
+	}
+	status := StatusFailed
+	switch n.TransactionStatus {
+	case "settlement", "capture":
+		status = StatusSettled
+	case "pending":
+		status = StatusPending
+	case "expire":
+		status = StatusExpired
+	case "cancel":
+		status = StatusCancelled
+	}
+	return &Notification{OrderID: n.OrderID, Reference: n.TransactionID, Status: status, Amount: amount}, nil
+}
+
+func (g *Midtrans) call(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.serverKey, "")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	res, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 500 {
+		return fmt.Errorf("midtrans: %s", res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
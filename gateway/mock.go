@@ -0,0 +1,71 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// Mock issues real QRIS payloads but never contacts a provider. Payments are settled by
+// posting a notification signed with the webhook secret (see Sign).
+type Mock struct {
+	merchant Merchant
+	secret   []byte
+}
+
+func NewMock(m Merchant, secret []byte) *Mock {
+	return &Mock{merchant: m, secret: secret}
+}
+
+// mockNotification is the webhook body of the mock gateway
+type mockNotification struct {
+	OrderID   string  `json:"order_id"`
+	Reference string  `json:"reference"`
+	Status    string  `json:"status"`
+	Amount    float64 `json:"amount"`
+}
+
+func (g *Mock) Name() string {
+	return "mock"
+}
+
+func (g *Mock) CreateQRIS(ctx context.Context, c Charge) (*ChargeResult, error) {
+	return &ChargeResult{
+		Reference: "MOCK-" + c.OrderID,
+		QRString:  QRISPayload(g.merchant, c.Amount, c.OrderID),
+		ExpiresAt: c.ExpiresAt,
+	}, nil
+}
+
+func (g *Mock) Cancel(ctx context.Context, orderID string) error {
+	return nil
+}
+
+// Sign builds a webhook body for n and its X-Signature header value
+func (g *Mock) Sign(n Notification) ([]byte, string, error) {
+	body, err := json.Marshal(mockNotification{OrderID: n.OrderID, Reference: n.Reference, Status: n.Status, Amount: n.Amount})
+	if err != nil {
+		return nil, "", err
+	}
+	return body, g.signature(body), nil
+}
+
+func (g *Mock) signature(body []byte) string {
+	mac := hmac.New(sha256.New, g.secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (g *Mock) ParseWebhook(header http.Header, body []byte) (*Notification, error) {
+	if !hmac.Equal([]byte(header.Get("X-Signature")), []byte(g.signature(body))) {
+		return nil, ErrSignature
+	}
+	var n mockNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, err
+	}
+	return &Notification{OrderID: n.OrderID, Reference: n.Reference, Status: n.Status, Amount: n.Amount}, nil
+}
@@ -0,0 +1,60 @@
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Merchant identifies the shop in QRIS payloads
+type Merchant struct {
+	Name string
+	City string
+	// ID is the national merchant id (NMID)
+	ID string
+}
+
+// QRISPayload builds a dynamic QRIS (EMVCo merchant-presented) payload for amount with
+// reference as bill number
+func QRISPayload(m Merchant, amount float64, reference string) string {
+	var b strings.Builder
+	b.WriteString(emvField("00", "01"))
+	b.WriteString(emvField("01", "12")) // dynamic: one code per payment
+	b.WriteString(emvField("26", emvField("00", "ID.CO.QRIS.WWW")+emvField("02", m.ID)+emvField("03", "UMI")))
+	b.WriteString(emvField("52", "5812")) // eating places and restaurants
+	b.WriteString(emvField("53", "360"))  // IDR
+	b.WriteString(emvField("54", strconv.FormatFloat(amount, 'f', -1, 64)))
+	b.WriteString(emvField("58", "ID"))
+	b.WriteString(emvField("59", truncate(m.Name, 25)))
+	b.WriteString(emvField("60", truncate(m.City, 15)))
+	b.WriteString(emvField("62", emvField("01", truncate(reference, 25))))
+	b.WriteString("6304")
+	return b.String() + fmt.Sprintf("%04X", crc16(b.String()))
+}
+
+func emvField(tag, value string) string {
+	return fmt.Sprintf("%s%02d%s", tag, len(value), value)
+}
+
+// crc16 is CRC-16/CCITT-FALSE, the checksum QRIS payloads end with
+func crc16(s string) uint16 {
+	crc := uint16(0xFFFF)
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -20,7 +20,7 @@ func main() {
     // auto-migrate models
     db := config.DB
     if db != nil {
-        db.AutoMigrate(&model.User{}, &model.Category{}, &model.Menu{}, &model.Transaction{}, &model.TransactionItem{})
+        db.AutoMigrate(&model.User{}, &model.Category{}, &model.Menu{}, &model.MenuBarcode{}, &model.Transaction{}, &model.TransactionItem{}, &model.Upload{}, &model.UploadFile{}, &model.MenuTranslation{}, &model.CategoryTranslation{}, &model.CatalogState{}, &model.DiningTable{}, &model.SelfOrder{}, &model.SelfOrderItem{}, &model.Ingredient{}, &model.MenuIngredient{}, &model.Tag{}, &model.MenuAllergen{}, &model.PriceRule{}, &model.TaxRule{}, &model.TransactionTax{}, &model.Promotion{}, &model.TransactionPromotion{}, &model.Payment{}, &model.Refund{}, &model.RefundItem{}, &model.IdempotencyKey{}, &model.Tombstone{}, &model.Bill{}, &model.BillItem{}, &model.KitchenStation{}, &model.KitchenTicket{}, &model.KitchenTicketItem{}, &model.InvoiceSequence{})
     }
 
     // seed admin user if not present (use env ADMIN_EMAIL / ADMIN_PASSWORD)
@@ -0,0 +1,180 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+    IdempotencyHeader = "Idempotency-Key"
+    // ReplayedHeader is set on responses repeated from an earlier request
+    ReplayedHeader = "Idempotent-Replayed"
+    // a running request renews its lock for this long every third of it, however long the
+    // handler takes; a lock that was not renewed is assumed lost (e.g. the server restarted)
+    // and the request may be run again
+    idempotencyLockTimeout = time.Minute
+    idempotencyInProgress  = "a request with this Idempotency-Key is in progress, retry later"
+)
+
+// idempotencyWriter keeps a copy of the response body
+type idempotencyWriter struct {
+    gin.ResponseWriter
+    body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(b []byte) (int, error) {
+    w.body.Write(b)
+    return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyWriter) WriteString(s string) (int, error) {
+    w.body.WriteString(s)
+    return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes mutating requests carrying an Idempotency-Key header safe to retry:
+// the response of the first request is stored for retention and replayed for a retry with
+// the same key and payload. Bodies above maxBody are rejected with 413 before being read. A reused key with a different payload is rejected with 422,
+// a retry while the first request still runs with 409. Server errors (5xx) are not stored
+// so the request can be retried. Keys are scoped to the authenticated user, so the
+// middleware has to run after AuthRequired on protected routes.
+func Idempotency(repo repository.IdempotencyRepository, retention time.Duration, maxBody int64) gin.HandlerFunc {
+    return func(c *gin.Context) {
+        key := c.GetHeader(IdempotencyHeader)
+        if key == "" || c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+            c.Next()
+            return
+        }
+        if len(key) > 100 {
+            c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": "Idempotency-Key must be at most 100 characters"})
+            c.Abort()
+            return
+        }
+        c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+        body, err := io.ReadAll(c.Request.Body)
+        if err != nil {
+            var maxErr *http.MaxBytesError
+            if errors.As(err, &maxErr) {
+                c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": "error", "message": fmt.Sprintf("request body must be at most %d bytes", maxBody)})
+                c.Abort()
+                return
+            }
+            c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+            c.Abort()
+            return
+        }
+        c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+        scope := "public"
+        if v, ok := c.Get(ContextUserKey); ok {
+            if u, ok := v.(*model.User); ok {
+                scope = fmt.Sprintf("user:%d", u.ID)
+            }
+        }
+        h := sha256.New()
+        h.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+        h.Write(body)
+        hash := hex.EncodeToString(h.Sum(nil))
+
+        now := time.Now()
+        rec := &model.IdempotencyKey{Scope: scope, Key: key, RequestHash: hash, CreatedAt: now, LockedUntil: now.Add(idempotencyLockTimeout), ExpiresAt: now.Add(retention)}
+        reserved, err := repo.Reserve(rec)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+            c.Abort()
+            return
+        }
+        if !reserved {
+            prev, err := repo.Find(scope, key)
+            if err != nil {
+                c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+                c.Abort()
+                return
+            }
+            switch {
+            case prev == nil:
+                // removed in the meantime
+                c.JSON(http.StatusConflict, gin.H{"status": "error", "message": idempotencyInProgress})
+                c.Abort()
+                return
+            case prev.ExpiresAt.Before(now) || (!prev.Completed && prev.LockedUntil.Before(now)):
+                // expired or abandoned: start over
+                if err := repo.Delete(prev.ID); err != nil {
+                    c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": err.Error()})
+                    c.Abort()
+                    return
+                }
+                rec.ID = 0
+                if reserved, err = repo.Reserve(rec); err != nil || !reserved {
+                    c.JSON(http.StatusConflict, gin.H{"status": "error", "message": idempotencyInProgress})
+                    c.Abort()
+                    return
+                }
+            case prev.RequestHash != hash:
+                c.JSON(http.StatusUnprocessableEntity, gin.H{"status": "error", "message": "Idempotency-Key was already used for a different request"})
+                c.Abort()
+                return
+            case !prev.Completed:
+                c.JSON(http.StatusConflict, gin.H{"status": "error", "message": idempotencyInProgress})
+                c.Abort()
+                return
+            default:
+                c.Header(ReplayedHeader, "true")
+                c.Data(prev.StatusCode, prev.ContentType, prev.Response)
+                c.Abort()
+                return
+            }
+        }
+
+        done := make(chan struct{})
+        defer close(done)
+        go renewLock(repo, rec.ID, done)
+        w := &idempotencyWriter{ResponseWriter: c.Writer}
+        c.Writer = w
+        c.Next()
+
+        status := w.Status()
+        if status >= http.StatusInternalServerError {
+            // let the client retry with the same key
+            if err := repo.Delete(rec.ID); err != nil {
+                log.Printf("idempotency key %d: %v", rec.ID, err)
+            }
+            return
+        }
+        if err := repo.Complete(rec.ID, status, w.Header().Get("Content-Type"), w.body.Bytes()); err != nil {
+            // the response is lost but the request ran: keep the key locked so a retry is
+            // refused with 409 instead of running it again
+            log.Printf("idempotency key %d: store response: %v", rec.ID, err)
+            if err := repo.Extend(rec.ID, rec.ExpiresAt); err != nil {
+                log.Printf("idempotency key %d: %v", rec.ID, err)
+            }
+        }
+    }
+}
+
+// renewLock extends the lock of a running request until done is closed
+func renewLock(repo repository.IdempotencyRepository, id uint, done <-chan struct{}) {
+    tick := time.NewTicker(idempotencyLockTimeout / 3)
+    defer tick.Stop()
+    for {
+        select {
+        case <-done:
+            return
+        case <-tick.C:
+            if err := repo.Extend(id, time.Now().Add(idempotencyLockTimeout)); err != nil {
+                log.Printf("idempotency key %d: renew lock: %v", id, err)
+            }
+        }
+    }
+}
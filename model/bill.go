@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Bill states: items are added to an open bill over several rounds; settling it creates the
+// Transaction. While a QRIS payment of that transaction is pending the bill is settling; it
+// is settled once paid and open again when the payment fails, expires or is cancelled. A
+// merged bill moved its items into MergedInto.
+const (
+    BillOpen      = "open"
+    BillSettling  = "settling"
+    BillSettled   = "settled"
+    BillMerged    = "merged"
+    BillCancelled = "cancelled"
+)
+
+// Bill is an open order (tab) of dine-in guests, usually attached to a table. A table with
+// an open or settling bill is occupied.
+type Bill struct {
+    ID            uint         `gorm:"primaryKey" json:"id"`
+    TableID       *uint        `gorm:"index" json:"table_id"`
+    Table         *DiningTable `gorm:"foreignKey:TableID;constraint:OnDelete:SET NULL" json:"table,omitempty"`
+    Name          string       `gorm:"size:100" json:"name"`
+    Guests        int          `json:"guests"`
+    Note          string       `gorm:"size:500" json:"note"`
+    Status        string       `gorm:"size:20;index;default:open" json:"status"`
+    // Total is an estimate (taxes included) at the current prices; the bill is priced
+    // again when settled
+    Total         float64      `json:"total"`
+    Items         []BillItem   `gorm:"foreignKey:BillID;constraint:OnDelete:CASCADE" json:"items"`
+    OpenedBy      *uint        `json:"opened_by"`
+    SettledBy     *uint        `json:"settled_by"`
+    TransactionID *uint        `gorm:"index" json:"transaction_id"`
+    MergedInto    *uint        `json:"merged_into,omitempty"`
+    SettledAt     *time.Time   `json:"settled_at"`
+    CreatedAt     time.Time    `json:"created_at"`
+    UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// BillItem is one ordered line. Seat numbers the guest it belongs to (0 = shared), so the
+// bill can be split by seats.
+type BillItem struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    BillID    uint      `gorm:"index" json:"bill_id"`
+    MenuID    *uint     `json:"menu_id"`
+    Menu      Menu      `gorm:"foreignKey:MenuID;constraint:OnDelete:SET NULL" json:"menu,omitempty"`
+    Quantity  int       `json:"quantity"`
+    Seat      int       `json:"seat"`
+    Note      string    `gorm:"size:255" json:"note"`
+    AddedBy   *uint     `json:"added_by"`
+    CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// CatalogState is a single-row table holding the catalog version. The version is bumped on
+// every menu/category change so all API instances agree on cache validity.
+type CatalogState struct {
+    ID        uint      `gorm:"primaryKey" json:"-"`
+    Version   uint64    `gorm:"not null;default:1" json:"version"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
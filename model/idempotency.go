@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// IdempotencyKey remembers a mutating request sent with an Idempotency-Key header so a
+// retry gets the original response instead of running again. Scope keeps the keys of
+// different users apart; Completed is false while the first request is still running, which
+// keeps renewing LockedUntil until it is done.
+type IdempotencyKey struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    Scope       string    `gorm:"size:50;uniqueIndex:uq_idempotency_scope_key" json:"scope"`
+    Key         string    `gorm:"size:100;uniqueIndex:uq_idempotency_scope_key" json:"key"`
+    // RequestHash is the SHA-256 of method, URL and body
+    RequestHash string    `gorm:"size:64" json:"request_hash"`
+    Completed   bool      `json:"completed"`
+    StatusCode  int       `json:"status_code"`
+    ContentType string    `gorm:"size:100" json:"content_type"`
+    Response    []byte    `gorm:"type:mediumblob" json:"-"`
+    CreatedAt   time.Time `json:"created_at"`
+    LockedUntil time.Time `json:"locked_until"`
+    ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Menu cost modes
+const (
+    CostModeManual = "manual"
+    CostModeRecipe = "recipe"
+)
+
+// Ingredient is a purchased ingredient with its cost per unit (e.g. per gram or per piece)
+type Ingredient struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    Name        string    `gorm:"size:100;uniqueIndex" json:"name"`
+    Unit        string    `gorm:"size:20" json:"unit"`
+    CostPerUnit float64   `json:"cost_per_unit"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// MenuIngredient is one recipe line: how much of an ingredient goes into one portion
+type MenuIngredient struct {
+    ID           uint       `gorm:"primaryKey" json:"id"`
+    MenuID       uint       `gorm:"index" json:"menu_id"`
+    IngredientID uint       `gorm:"index" json:"ingredient_id"`
+    Ingredient   Ingredient `gorm:"foreignKey:IngredientID;constraint:OnDelete:RESTRICT" json:"ingredient"`
+    Quantity     float64    `json:"quantity"`
+}
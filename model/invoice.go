@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// InvoiceSequence holds the last invoice number handed out in a scope: the prefix and
+// period of the numbers, e.g. "WRG-20261018"
+type InvoiceSequence struct {
+    Scope      string    `gorm:"primaryKey;size:60" json:"scope"`
+    LastNumber int       `json:"last_number"`
+    UpdatedAt  time.Time `json:"updated_at"`
+}
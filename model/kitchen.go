@@ -0,0 +1,61 @@
+package model
+
+import "time"
+
+// Kitchen ticket states, in order. A ticket whose sale is cancelled or voided (or whose
+// lines were all taken back) is cancelled instead.
+const (
+    TicketQueued    = "queued"
+    TicketPreparing = "preparing"
+    TicketReady     = "ready"
+    TicketServed    = "served"
+    TicketCancelled = "cancelled"
+)
+
+// TicketStates lists the ticket states in the order a ticket goes through them
+var TicketStates = []string{TicketQueued, TicketPreparing, TicketReady, TicketServed}
+
+// KitchenStation prepares the menus of its categories (e.g. drinks at the bar). The default
+// station gets the items no other station claims.
+type KitchenStation struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    Name       string     `gorm:"size:50;uniqueIndex" json:"name"`
+    IsDefault  bool       `json:"is_default"`
+    IsActive   bool       `gorm:"default:true" json:"is_active"`
+    Categories []Category `gorm:"many2many:kitchen_station_categories;constraint:OnDelete:CASCADE" json:"categories"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// KitchenTicket is what one station has to prepare for a sale or a round of an open bill.
+// The timestamps record when it entered each state, for prep-time reports.
+type KitchenTicket struct {
+    ID            uint                `gorm:"primaryKey" json:"id"`
+    StationID     uint                `gorm:"index" json:"station_id"`
+    StationName   string              `gorm:"size:50" json:"station_name"`
+    TransactionID *uint               `gorm:"index" json:"transaction_id"`
+    BillID        *uint               `gorm:"index" json:"bill_id"`
+    // Label tells the staff where the order goes, e.g. the table or the receipt number
+    Label         string              `gorm:"size:100" json:"label"`
+    Status        string              `gorm:"size:20;index;default:queued" json:"status"`
+    Items         []KitchenTicketItem `gorm:"foreignKey:TicketID;constraint:OnDelete:CASCADE" json:"items"`
+    CreatedAt     time.Time           `gorm:"index" json:"created_at"`
+    StartedAt     *time.Time          `json:"started_at"`
+    ReadyAt       *time.Time          `json:"ready_at"`
+    ServedAt      *time.Time          `json:"served_at"`
+    CancelledAt   *time.Time          `json:"cancelled_at,omitempty"`
+}
+
+// KitchenTicketItem is one line of a ticket; it points back to the sale or bill line it
+// was made from, so the line can be taken back when that one is voided or removed.
+type KitchenTicketItem struct {
+    ID                uint   `gorm:"primaryKey" json:"id"`
+    TicketID          uint   `gorm:"index" json:"ticket_id"`
+    TransactionItemID *uint  `gorm:"index" json:"transaction_item_id,omitempty"`
+    BillItemID        *uint  `gorm:"index" json:"bill_item_id,omitempty"`
+    MenuID            *uint  `json:"menu_id"`
+    Name              string `gorm:"size:150" json:"name"`
+    Quantity          int    `json:"quantity"`
+    Seat              int    `json:"seat,omitempty"`
+    Note              string `gorm:"size:255" json:"note,omitempty"`
+}
@@ -3,14 +3,32 @@ package model
 import "time"
 
 type Menu struct {
-    ID          uint      `gorm:"primaryKey" json:"id"`
-    Name        string    `gorm:"size:150" json:"name"`
-    Description string    `gorm:"size:500" json:"description"`
-    Price       float64   `json:"price"`
-    CategoryID  *uint     `json:"category_id"`
-    Category    Category  `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-    ImageURL    string    `gorm:"size:512" json:"image_url"`
-    IsAvailable bool      `gorm:"default:true" json:"is_available"`
-    CreatedAt   time.Time `json:"created_at"`
-    UpdatedAt   time.Time `json:"updated_at"`
+    ID          uint          `gorm:"primaryKey" json:"id"`
+    Name        string        `gorm:"size:150" json:"name"`
+    Description string        `gorm:"size:500" json:"description"`
+    Price       float64       `json:"price"`
+    // CostPrice is what one portion costs us; entered manually or derived from the recipe
+    // (CostMode "recipe"). Not exposed on public endpoints.
+    CostPrice   float64       `json:"cost_price,omitempty"`
+    CostMode    string        `gorm:"size:10;default:manual" json:"cost_mode,omitempty"`
+    // SKU is optional but unique; nil (not "") when unset so the unique index allows many
+    SKU         *string       `gorm:"size:64;uniqueIndex" json:"sku"`
+    CategoryID  *uint         `json:"category_id"`
+    Category    Category      `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+    Barcodes    []MenuBarcode `gorm:"foreignKey:MenuID;constraint:OnDelete:CASCADE" json:"barcodes"`
+    Recipe      []MenuIngredient `gorm:"foreignKey:MenuID;constraint:OnDelete:CASCADE" json:"-"`
+    Tags        []Tag         `gorm:"many2many:menu_tags;constraint:OnDelete:CASCADE" json:"tags"`
+    Allergens   []MenuAllergen `gorm:"foreignKey:MenuID;constraint:OnDelete:CASCADE" json:"allergens"`
+    ImageURL    string        `gorm:"size:512" json:"image_url"`
+    IsAvailable bool          `gorm:"default:true" json:"is_available"`
+    CreatedAt   time.Time     `json:"created_at"`
+    UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// MenuBarcode is one scannable code of a menu (packaged goods often carry several)
+type MenuBarcode struct {
+    ID        uint   `gorm:"primaryKey" json:"id"`
+    MenuID    uint   `gorm:"index" json:"menu_id"`
+    Code      string `gorm:"size:64;uniqueIndex" json:"code"`
+    Symbology string `gorm:"size:20" json:"symbology"`
 }
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// Payment statuses
+const (
+    PaymentPaid      = "paid"
+    // PaymentPending waits for the gateway to confirm settlement (QRIS)
+    PaymentPending   = "pending"
+    PaymentExpired   = "expired"
+    PaymentCancelled = "cancelled"
+    PaymentFailed    = "failed"
+)
+
+// Payment is one tender of a transaction. Amount is the part of the total it settled;
+// Tendered is what was handed over (more than Amount for cash with change).
+type Payment struct {
+    ID            uint       `gorm:"primaryKey" json:"id"`
+    TransactionID uint       `gorm:"index" json:"transaction_id"`
+    Method        string     `gorm:"size:20;index" json:"method"`
+    Amount        float64    `json:"amount"`
+    Tendered      float64    `json:"tendered"`
+    // Reference is e.g. the QRIS/EDC approval code, or the gateway's transaction id
+    Reference     string     `gorm:"size:100" json:"reference,omitempty"`
+    Status        string     `gorm:"size:20;index" json:"status"`
+    // gateway payments: OrderID identifies the charge at the provider, QRString is the
+    // dynamic QRIS payload to show until ExpiresAt
+    Gateway       string     `gorm:"size:20" json:"gateway,omitempty"`
+    OrderID       *string    `gorm:"size:64;uniqueIndex" json:"order_id,omitempty"`
+    QRString      string     `gorm:"type:text" json:"qr_string,omitempty"`
+    ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+    PaidAt        *time.Time `json:"paid_at,omitempty"`
+    CreatedAt     time.Time  `json:"created_at"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Price rule kinds
+const (
+    PriceRuleFixed   = "fixed"   // Value is the price
+    PriceRulePercent = "percent" // Value is the discount in percent of the menu price
+)
+
+// PriceRule changes menu prices during a time window, e.g. a happy hour. It targets one
+// menu, a category, or every menu when both are empty.
+type PriceRule struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    Name       string     `gorm:"size:100" json:"name"`
+    MenuID     *uint      `gorm:"index" json:"menu_id"`
+    CategoryID *uint      `gorm:"index" json:"category_id"`
+    // Weekdays lists the days the rule applies as numbers 0 (Sunday) .. 6, comma separated;
+    // empty means every day
+    Weekdays   string     `gorm:"size:20" json:"weekdays"`
+    // StartTime/EndTime ("HH:MM", local time) bound the daily window; empty means all day.
+    // A window ending before it starts runs past midnight.
+    StartTime  string     `gorm:"size:5" json:"start_time"`
+    EndTime    string     `gorm:"size:5" json:"end_time"`
+    // optional date range the rule is valid in
+    ValidFrom  *time.Time `json:"valid_from"`
+    ValidUntil *time.Time `json:"valid_until"`
+    Kind       string     `gorm:"size:10" json:"kind"`
+    Value      float64    `json:"value"`
+    // Priority decides between matching rules (higher wins, ties go to the lower price)
+    Priority   int        `json:"priority"`
+    IsActive   bool       `gorm:"default:true" json:"is_active"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at"`
+}
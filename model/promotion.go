@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// Promotion kinds
+const (
+    PromotionPercent = "percent" // Value percent off the eligible items, capped by MaxDiscount
+    PromotionFixed   = "fixed"   // Value off the order (at most the eligible amount)
+    PromotionBuyGet  = "bxgy"    // buy BuyQuantity, get GetQuantity of the cheapest eligible units free
+)
+
+// Promotion is an order discount. Promotions with a Code are vouchers the customer has to
+// present; without a code they apply automatically whenever the order qualifies.
+type Promotion struct {
+    ID          uint       `gorm:"primaryKey" json:"id"`
+    Name        string     `gorm:"size:100" json:"name"`
+    // Code is stored upper-case; nil for automatic promotions
+    Code        *string    `gorm:"size:50;uniqueIndex" json:"code"`
+    Kind        string     `gorm:"size:10" json:"kind"`
+    Value       float64    `json:"value"`
+    MaxDiscount float64    `json:"max_discount"`
+    BuyQuantity int        `json:"buy_quantity"`
+    GetQuantity int        `json:"get_quantity"`
+    // MinSpend is compared with the order subtotal
+    MinSpend    float64    `json:"min_spend"`
+    // CategoryID restricts the discount to menus of one category
+    CategoryID  *uint      `gorm:"index" json:"category_id"`
+    // Stackable promotions combine with each other; a non-stackable one applies alone
+    Stackable   bool       `json:"stackable"`
+    // UsageLimit caps the number of transactions using the promotion (0 = unlimited)
+    UsageLimit  int        `json:"usage_limit"`
+    UsageCount  int        `json:"usage_count"`
+    ValidFrom   *time.Time `json:"valid_from"`
+    ValidUntil  *time.Time `json:"valid_until"`
+    IsActive    bool       `gorm:"default:true" json:"is_active"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TransactionPromotion records a promotion applied to a transaction and its discount
+type TransactionPromotion struct {
+    ID            uint    `gorm:"primaryKey" json:"id"`
+    TransactionID uint    `gorm:"index" json:"transaction_id"`
+    PromotionID   *uint   `gorm:"index" json:"promotion_id"`
+    Code          string  `gorm:"size:50" json:"code,omitempty"`
+    Name          string  `gorm:"size:100" json:"name"`
+    Kind          string  `gorm:"size:10" json:"kind"`
+    Amount        float64 `json:"amount"`
+}
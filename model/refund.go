@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// Refund kinds
+const (
+    // RefundVoid reverses a mistaken sale on the day it was made
+    RefundVoid   = "void"
+    // RefundReturn gives money back for returned items
+    RefundReturn = "refund"
+)
+
+// Refund reverses all or part of a transaction. Amount is the money given back.
+type Refund struct {
+    ID            uint         `gorm:"primaryKey" json:"id"`
+    TransactionID uint         `gorm:"index" json:"transaction_id"`
+    Kind          string       `gorm:"size:10" json:"kind"`
+    Reason        string       `gorm:"size:255" json:"reason"`
+    Amount        float64      `json:"amount"`
+    // Method is how the money was given back (tunai or qris)
+    Method        string       `gorm:"size:20" json:"method"`
+    RequestedBy   *uint        `json:"requested_by"`
+    ApprovedBy    *uint        `json:"approved_by"`
+    Items         []RefundItem `gorm:"foreignKey:RefundID" json:"items"`
+    CreatedAt     time.Time    `json:"created_at"`
+}
+
+// RefundItem is the quantity of one transaction line a refund reversed
+type RefundItem struct {
+    ID                uint    `gorm:"primaryKey" json:"id"`
+    RefundID          uint    `gorm:"index" json:"refund_id"`
+    TransactionItemID uint    `gorm:"index" json:"transaction_item_id"`
+    MenuID            *uint   `json:"menu_id"`
+    Quantity          int     `json:"quantity"`
+    Amount            float64 `json:"amount"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Self order states: customers submit pending orders, a cashier accepts (turning the order
+// into a Transaction) or rejects them
+const (
+    SelfOrderPending  = "pending"
+    SelfOrderAccepted = "accepted"
+    SelfOrderRejected = "rejected"
+)
+
+// SelfOrder is an order a customer submitted from a table QR code, waiting for the cashier
+type SelfOrder struct {
+    ID            uint            `gorm:"primaryKey" json:"id"`
+    TableID       uint            `gorm:"index" json:"table_id"`
+    Table         DiningTable     `gorm:"foreignKey:TableID;constraint:OnDelete:CASCADE" json:"table,omitempty"`
+    CustomerName  string          `gorm:"size:100" json:"customer_name"`
+    Note          string          `gorm:"size:500" json:"note"`
+    Status        string          `gorm:"size:20;index;default:pending" json:"status"`
+    // Total is an estimate (taxes included) at the prices of submission; the transaction is
+    // priced again on accept
+    Total         float64         `json:"total"`
+    Items         []SelfOrderItem `gorm:"foreignKey:SelfOrderID;constraint:OnDelete:CASCADE" json:"items"`
+    TransactionID *uint           `json:"transaction_id"`
+    HandledBy     *uint           `json:"handled_by"`
+    RejectReason  string          `gorm:"size:255" json:"reject_reason,omitempty"`
+    CreatedAt     time.Time       `json:"created_at"`
+    UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+type SelfOrderItem struct {
+    ID          uint    `gorm:"primaryKey" json:"id"`
+    SelfOrderID uint    `gorm:"index" json:"self_order_id"`
+    MenuID      *uint   `json:"menu_id"`
+    Menu        Menu    `gorm:"foreignKey:MenuID;constraint:OnDelete:SET NULL" json:"menu,omitempty"`
+    Quantity    int     `json:"quantity"`
+    Price       float64 `json:"price"`
+    Note        string  `gorm:"size:255" json:"note"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// DiningTable is a table customers can order from by scanning its QR code
+type DiningTable struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    Name      string    `gorm:"size:50;uniqueIndex" json:"name"`
+    Seats     int       `json:"seats"`
+    IsActive  bool      `gorm:"default:true" json:"is_active"`
+    // TokenVersion is signed into the QR token; bumping it invalidates printed codes
+    TokenVersion uint   `gorm:"not null;default:1" json:"-"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// Tag is a free-form menu label such as "pedas" or "vegetarian"
+type Tag struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    Name      string    `gorm:"size:50;uniqueIndex" json:"name"`
+    // Slug is the lower-case, dash separated name used in ?tag= filters
+    Slug      string    `gorm:"size:60;uniqueIndex" json:"slug"`
+    CreatedAt time.Time `json:"-"`
+}
+
+// MenuAllergen marks a menu as containing one of the fixed Allergens
+type MenuAllergen struct {
+    ID     uint   `gorm:"primaryKey" json:"-"`
+    MenuID uint   `gorm:"uniqueIndex:idx_menu_allergen" json:"-"`
+    Code   string `gorm:"size:30;uniqueIndex:idx_menu_allergen" json:"code"`
+    // Name is the label in the requested language, filled when menus are localized
+    Name   string `gorm:"-" json:"name"`
+}
+
+// Allergen is an entry of the fixed allergen list with its label per locale
+type Allergen struct {
+    Code  string            `json:"code"`
+    Names map[string]string `json:"-"`
+}
+
+// Allergens is the fixed list menus can declare (the EU 14 major allergens)
+var Allergens = []Allergen{
+    {"gluten", map[string]string{"id": "Gluten", "en": "Gluten"}},
+    {"crustacean", map[string]string{"id": "Udang/kepiting", "en": "Crustaceans"}},
+    {"egg", map[string]string{"id": "Telur", "en": "Eggs"}},
+    {"fish", map[string]string{"id": "Ikan", "en": "Fish"}},
+    {"peanut", map[string]string{"id": "Kacang tanah", "en": "Peanuts"}},
+    {"soy", map[string]string{"id": "Kedelai", "en": "Soy"}},
+    {"milk", map[string]string{"id": "Susu", "en": "Milk"}},
+    {"tree_nut", map[string]string{"id": "Kacang pohon", "en": "Tree nuts"}},
+    {"celery", map[string]string{"id": "Seledri", "en": "Celery"}},
+    {"mustard", map[string]string{"id": "Mustard", "en": "Mustard"}},
+    {"sesame", map[string]string{"id": "Wijen", "en": "Sesame"}},
+    {"sulphite", map[string]string{"id": "Sulfit", "en": "Sulphites"}},
+    {"lupin", map[string]string{"id": "Lupin", "en": "Lupin"}},
+    {"mollusc", map[string]string{"id": "Kerang/cumi", "en": "Molluscs"}},
+}
+
+// IsAllergen reports whether code is in the fixed allergen list
+func IsAllergen(code string) bool {
+    for _, a := range Allergens {
+        if a.Code == code {
+            return true
+        }
+    }
+    return false
+}
+
+// AllergenName returns the label of code in locale, or in fallback when untranslated
+func AllergenName(code, locale, fallback string) string {
+    for _, a := range Allergens {
+        if a.Code != code {
+            continue
+        }
+        if n, ok := a.Names[locale]; ok {
+            return n
+        }
+        if n, ok := a.Names[fallback]; ok {
+            return n
+        }
+    }
+    return code
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+// Tax rule kinds
+const (
+    TaxKindTax     = "tax"     // e.g. PB1 (restaurant tax) or PPN
+    TaxKindService = "service" // service charge
+)
+
+// TaxRule is a percentage charged on the order after discounts. Inclusive rules are already
+// part of the menu prices and are only broken out; exclusive rules are added to the total.
+type TaxRule struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    Name      string    `gorm:"size:100" json:"name"`
+    Kind      string    `gorm:"size:10" json:"kind"`
+    Rate      float64   `json:"rate"`
+    Inclusive bool      `json:"inclusive"`
+    // Compound exclusive rules are charged on the base plus the exclusive rules before them
+    // (e.g. PB1 on top of the service charge)
+    Compound  bool      `json:"compound"`
+    // Sequence orders the rules; compound rules see the ones with a lower sequence
+    Sequence  int       `json:"sequence"`
+    IsActive  bool      `gorm:"default:true" json:"is_active"`
+    CreatedAt time.Time `json:"created_at"`
+    UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TransactionTax records one rule as it was applied to a transaction
+type TransactionTax struct {
+    ID            uint    `gorm:"primaryKey" json:"id"`
+    TransactionID uint    `gorm:"index" json:"transaction_id"`
+    TaxRuleID     *uint   `json:"tax_rule_id"`
+    Name          string  `gorm:"size:100" json:"name"`
+    Kind          string  `gorm:"size:10" json:"kind"`
+    Rate          float64 `json:"rate"`
+    Inclusive     bool    `json:"inclusive"`
+    Base          float64 `json:"base"`
+    Amount        float64 `json:"amount"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Tombstone records a deleted catalog record so offline devices syncing changes since an
+// earlier time learn about the deletion
+type Tombstone struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    Entity    string    `gorm:"size:30;index:idx_tombstones_entity" json:"entity"`
+    EntityID  uint      `json:"entity_id"`
+    DeletedAt time.Time `gorm:"index:idx_tombstones_entity" json:"deleted_at"`
+}
+
+// TombstoneMenu is the entity of deleted menus
+const TombstoneMenu = "menu"
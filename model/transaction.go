@@ -2,15 +2,70 @@ package model
 
 import "time"
 
+// Payment methods
+const (
+    PaymentCash = "tunai"
+    PaymentQRIS = "qris"
+    // PaymentSplit is Transaction.PaymentMethod when several methods paid one transaction
+    PaymentSplit = "split"
+)
+
+// Transaction statuses
+const (
+    TransactionCompleted = "completed"
+    // TransactionPendingPayment waits for a QRIS payment to be confirmed by the gateway
+    TransactionPendingPayment = "pending_payment"
+    // TransactionCancelled: the QRIS payment expired or was cancelled, nothing was sold
+    TransactionCancelled = "cancelled"
+    // fully reversed by a void or a refund, or partly (some items)
+    TransactionVoided            = "voided"
+    TransactionRefunded          = "refunded"
+    TransactionPartiallyVoided   = "partially_voided"
+    TransactionPartiallyRefunded = "partially_refunded"
+)
+
 type Transaction struct {
     ID        uint              `gorm:"primaryKey" json:"id"`
+    // InvoiceNumber is the number shown to customers, e.g. WRG-20261018-0042; numbers run
+    // without gaps per prefix and period (sales stored before numbering have none)
+    InvoiceNumber *string         `gorm:"size:60;uniqueIndex" json:"invoice_number"`
     Total       float64           `json:"total"`
     Subtotal    float64           `json:"subtotal"`
-    Tax         float64           `json:"tax"`
+    // Tax and ServiceCharge sum the applied tax rules of each kind; IncludedTax is the part
+    // of both that is already contained in Subtotal (inclusive pricing)
+    Tax           float64          `json:"tax"`
+    ServiceCharge float64          `json:"service_charge"`
+    IncludedTax   float64          `json:"included_tax"`
     Discount    float64           `json:"discount"`
+    // RoundingAdjustment is the cash rounding included in Total (negative when rounded down)
+    RoundingAdjustment float64    `json:"rounding_adjustment"`
+    // PaymentMethod is the method of a single tender, or split; see Payments
     PaymentMethod string          `json:"payment_method"`
+    // AmountPaid is everything handed over across the tenders
     AmountPaid  float64           `json:"amount_paid"`
+    // Change is the cash handed back: AmountPaid - Total
+    Change      float64           `gorm:"column:change_amount" json:"change"`
     CashierID   *uint             `json:"cashier_id"`
+    // ClientUUID identifies a sale recorded offline by a device (see POST /api/sync/transactions);
+    // SyncedAt is when it reached the server, CreatedAt keeps the device's time of sale
+    ClientUUID  *string           `gorm:"size:36;uniqueIndex" json:"client_uuid,omitempty"`
+    SyncedAt    *time.Time        `json:"synced_at,omitempty"`
+    // PriceReview flags an offline sale stored with the device's prices because they differed
+    // from the server's pricing at its time of sale; ServerTotal is what the server would
+    // have charged and PriceReviewNote lists the differences
+    PriceReview     bool          `gorm:"index" json:"price_review,omitempty"`
+    ServerTotal     *float64      `json:"server_total,omitempty"`
+    PriceReviewNote string        `gorm:"size:500" json:"price_review_note,omitempty"`
+    // ReceiptToken opens the public receipt page /r/<token>; cleared when the sale is voided
+    ReceiptToken *string          `gorm:"size:32;uniqueIndex" json:"-"`
+    Status      string            `gorm:"size:20;default:completed;index" json:"status"`
+    // RefundedAmount is the money given back by voids and refunds, see Refunds
+    RefundedAmount float64        `json:"refunded_amount"`
     Items       []TransactionItem `gorm:"foreignKey:TransactionID" json:"items"`
+    Taxes       []TransactionTax  `gorm:"foreignKey:TransactionID" json:"taxes"`
+    // Promotions lists the promotions in Discount; the rest of it was a manual discount
+    Promotions  []TransactionPromotion `gorm:"foreignKey:TransactionID" json:"promotions"`
+    Payments    []Payment         `gorm:"foreignKey:TransactionID" json:"payments"`
+    Refunds     []Refund          `gorm:"foreignKey:TransactionID" json:"refunds,omitempty"`
     CreatedAt   time.Time         `json:"created_at"`
 }
@@ -5,6 +5,18 @@ type TransactionItem struct {
     TransactionID uint    `json:"transaction_id"`
     MenuID        *uint   `json:"menu_id"`
     Quantity      int     `json:"quantity"`
+    // RefundedQuantity counts the units voided or refunded since the sale
+    RefundedQuantity int  `json:"refunded_quantity"`
     Price         float64 `json:"price"`
+    // BasePrice is the menu price before a price rule; PriceRuleID/PriceRuleName record the
+    // rule that set Price, if any
+    BasePrice     float64 `json:"base_price"`
+    PriceRuleID   *uint   `json:"price_rule_id"`
+    PriceRuleName string  `gorm:"size:100" json:"price_rule_name,omitempty"`
+    // CostPrice is the menu cost at the time of sale, so later cost changes keep old margins;
+    // only the admin reports show it
+    CostPrice     float64 `json:"-"`
+    // Note is the customer's wish for the kitchen (e.g. "tidak pedas")
+    Note          string  `gorm:"size:255" json:"note,omitempty"`
     Menu          Menu    `gorm:"foreignKey:MenuID" json:"menu,omitempty"`
 }
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// MenuTranslation holds the name/description of a menu in a non-default locale
+type MenuTranslation struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    MenuID      uint      `gorm:"uniqueIndex:idx_menu_translation_locale" json:"menu_id"`
+    Locale      string    `gorm:"size:10;uniqueIndex:idx_menu_translation_locale" json:"locale"`
+    Name        string    `gorm:"size:150" json:"name"`
+    Description string    `gorm:"size:500" json:"description"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CategoryTranslation struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    CategoryID uint      `gorm:"uniqueIndex:idx_category_translation_locale" json:"category_id"`
+    Locale     string    `gorm:"size:10;uniqueIndex:idx_category_translation_locale" json:"locale"`
+    Name       string    `gorm:"size:100" json:"name"`
+    CreatedAt  time.Time `json:"created_at"`
+    UpdatedAt  time.Time `json:"updated_at"`
+}
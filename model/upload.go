@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Upload is one uploaded image and its renditions. It is attached when an entity
+// (currently a menu) references one of its file URLs; unattached uploads older than the
+// grace period are garbage collected.
+type Upload struct {
+    ID         uint         `gorm:"primaryKey" json:"id"`
+    OwnerID    *uint        `json:"owner_id"`
+    EntityType string       `gorm:"size:50;index:idx_uploads_entity" json:"entity_type"`
+    EntityID   *uint        `gorm:"index:idx_uploads_entity" json:"entity_id"`
+    AttachedAt *time.Time   `gorm:"index" json:"attached_at"`
+    Files      []UploadFile `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE" json:"files"`
+    CreatedAt  time.Time    `gorm:"index" json:"created_at"`
+}
+
+type UploadFile struct {
+    ID        uint   `gorm:"primaryKey" json:"id"`
+    UploadID  uint   `gorm:"index" json:"upload_id"`
+    Rendition string `gorm:"size:30" json:"rendition"`
+    Key       string `gorm:"size:255;uniqueIndex" json:"key"`
+    URL       string `gorm:"size:512;index" json:"url"`
+    Size      int64  `json:"size"`
+}
@@ -8,6 +8,13 @@ type User struct {
     Email     string    `gorm:"size:100;uniqueIndex" json:"email"`
     Password  string    `gorm:"size:255" json:"-"`
     Role      string    `gorm:"size:20" json:"role"`
+    // PIN (hashed) lets a manager approve voids and refunds on the cashier's device
+    PIN       string    `gorm:"size:255" json:"-"`
     CreatedAt time.Time `json:"created_at"`
     UpdatedAt time.Time `json:"updated_at"`
 }
+
+// CanApprove reports whether the user may approve voids and refunds
+func (u *User) CanApprove() bool {
+    return u.Role == "admin" || u.Role == "manager"
+}
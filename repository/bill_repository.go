@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BillRepository interface {
+    Create(b *model.Bill) error
+    GetByID(id uint) (*model.Bill, error)
+    // Lock is GetByID that also locks the bill until the surrounding database transaction
+    // ends, so changes to one bill run one after another
+    Lock(id uint) (*model.Bill, error)
+    // List returns bills with the given status (all when empty), oldest first
+    List(status string) ([]model.Bill, error)
+    // ListActive returns the open and settling bills, which occupy their tables
+    ListActive() ([]model.Bill, error)
+    // ListActiveByTable returns the open and settling bills of a table
+    ListActiveByTable(tableID uint) ([]model.Bill, error)
+    // GetByTransaction returns the bill settled (or being settled) by a transaction
+    GetByTransaction(transactionID uint) (*model.Bill, error)
+    AddItems(items []model.BillItem) error
+    UpdateItemQuantity(itemID uint, quantity int) error
+    DeleteItem(itemID uint) error
+    // MoveItems moves lines to another bill
+    MoveItems(itemIDs []uint, toBillID uint) error
+    Update(id uint, updates map[string]interface{}) error
+    // Transition applies updates only if the bill is still in status from and reports
+    // whether it did
+    Transition(id uint, from string, updates map[string]interface{}) (bool, error)
+    WithTx(tx *gorm.DB) BillRepository
+}
+
+type billRepo struct{
+    db *gorm.DB
+}
+
+func NewBillRepository() BillRepository {
+    return &billRepo{db: config.DB}
+}
+
+func (r *billRepo) WithTx(tx *gorm.DB) BillRepository {
+    return &billRepo{db: tx}
+}
+
+func (r *billRepo) Create(b *model.Bill) error {
+    return r.db.Omit("Items.Menu", "Table").Create(b).Error
+}
+
+func (r *billRepo) GetByID(id uint) (*model.Bill, error) {
+    return r.get(r.db, id)
+}
+
+func (r *billRepo) Lock(id uint) (*model.Bill, error) {
+    return r.get(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func (r *billRepo) get(db *gorm.DB, id uint) (*model.Bill, error) {
+    var b model.Bill
+    if err := db.Preload("Table").Preload("Items", func(db *gorm.DB) *gorm.DB {
+        return db.Order("id")
+    }).Preload("Items.Menu", withoutCosts).First(&b, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &b, nil
+}
+
+func (r *billRepo) List(status string) ([]model.Bill, error) {
+    q := r.db.Preload("Table").Preload("Items.Menu", withoutCosts).Order("id")
+    if status != "" {
+        q = q.Where("status = ?", status)
+    }
+    var list []model.Bill
+    if err := q.Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *billRepo) ListActive() ([]model.Bill, error) {
+    var list []model.Bill
+    if err := r.db.Preload("Table").Preload("Items.Menu", withoutCosts).Where("status IN ?", activeBillStates).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *billRepo) ListActiveByTable(tableID uint) ([]model.Bill, error) {
+    var list []model.Bill
+    if err := r.db.Where("table_id = ? AND status IN ?", tableID, activeBillStates).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+// activeBillStates are the states of bills that occupy their table
+var activeBillStates = []string{model.BillOpen, model.BillSettling}
+
+func (r *billRepo) GetByTransaction(transactionID uint) (*model.Bill, error) {
+    var b model.Bill
+    if err := r.db.Where("transaction_id = ?", transactionID).First(&b).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &b, nil
+}
+
+func (r *billRepo) AddItems(items []model.BillItem) error {
+    return r.db.Omit("Menu").Create(&items).Error
+}
+
+func (r *billRepo) UpdateItemQuantity(itemID uint, quantity int) error {
+    return r.db.Model(&model.BillItem{}).Where("id = ?", itemID).Update("quantity", quantity).Error
+}
+
+func (r *billRepo) DeleteItem(itemID uint) error {
+    return r.db.Delete(&model.BillItem{}, itemID).Error
+}
+
+func (r *billRepo) MoveItems(itemIDs []uint, toBillID uint) error {
+    if len(itemIDs) == 0 {
+        return nil
+    }
+    return r.db.Model(&model.BillItem{}).Where("id IN ?", itemIDs).Update("bill_id", toBillID).Error
+}
+
+func (r *billRepo) Update(id uint, updates map[string]interface{}) error {
+    return r.db.Model(&model.Bill{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *billRepo) Transition(id uint, from string, updates map[string]interface{}) (bool, error) {
+    res := r.db.Model(&model.Bill{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
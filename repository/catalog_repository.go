@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const catalogStateID = 1
+
+type CatalogRepository interface {
+    Current() (*model.CatalogState, error)
+    // Bump increments the catalog version and returns the new state
+    Bump() (*model.CatalogState, error)
+}
+
+type catalogRepo struct{
+    db *gorm.DB
+}
+
+func NewCatalogRepository() CatalogRepository {
+    return &catalogRepo{db: config.DB}
+}
+
+func (r *catalogRepo) Current() (*model.CatalogState, error) {
+    var st model.CatalogState
+    err := r.db.First(&st, catalogStateID).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        st = model.CatalogState{ID: catalogStateID, Version: 1, UpdatedAt: time.Now()}
+        err = r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&st).Error
+        if err == nil {
+            err = r.db.First(&st, catalogStateID).Error
+        }
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &st, nil
+}
+
+func (r *catalogRepo) Bump() (*model.CatalogState, error) {
+    res := r.db.Model(&model.CatalogState{}).Where("id = ?", catalogStateID).
+        Updates(map[string]interface{}{"version": gorm.Expr("version + 1"), "updated_at": time.Now()})
+    if res.Error != nil {
+        return nil, res.Error
+    }
+    if res.RowsAffected == 0 {
+        // first bump creates the row
+        if _, err := r.Current(); err != nil {
+            return nil, err
+        }
+        return r.Bump()
+    }
+    return r.Current()
+}
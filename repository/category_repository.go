@@ -9,6 +9,8 @@ import (
 type CategoryRepository interface {
     Create(cat *model.Category) error
     List() ([]model.Category, error)
+    FindByName(name string) (*model.Category, error)
+    WithTx(tx *gorm.DB) CategoryRepository
 }
 
 type categoryRepo struct{
@@ -30,3 +32,18 @@ func (r *categoryRepo) List() ([]model.Category, error) {
     }
     return list, nil
 }
+
+func (r *categoryRepo) FindByName(name string) (*model.Category, error) {
+    var c model.Category
+    if err := r.db.Where("name = ?", name).First(&c).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &c, nil
+}
+
+func (r *categoryRepo) WithTx(tx *gorm.DB) CategoryRepository {
+    return &categoryRepo{db: tx}
+}
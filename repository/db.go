@@ -0,0 +1,12 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"gorm.io/gorm"
+)
+
+// Transaction runs fn inside a single database transaction. Repositories bound to the
+// transaction are obtained with their WithTx method.
+func Transaction(fn func(tx *gorm.DB) error) error {
+    return config.DB.Transaction(fn)
+}
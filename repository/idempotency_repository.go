@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+    Find(scope, key string) (*model.IdempotencyKey, error)
+    // Reserve stores a new, not yet completed key and reports false when the key exists
+    Reserve(k *model.IdempotencyKey) (bool, error)
+    Complete(id uint, status int, contentType string, response []byte) error
+    // Extend keeps the lock of a running request until t
+    Extend(id uint, t time.Time) error
+    Delete(id uint) error
+    // DeleteExpired removes keys whose retention ended before t
+    DeleteExpired(t time.Time) (int64, error)
+}
+
+type idempotencyRepo struct{
+    db *gorm.DB
+}
+
+func NewIdempotencyRepository() IdempotencyRepository {
+    return &idempotencyRepo{db: config.DB}
+}
+
+func (r *idempotencyRepo) Find(scope, key string) (*model.IdempotencyKey, error) {
+    var k model.IdempotencyKey
+    if err := r.db.Where("scope = ? AND `key` = ?", scope, key).First(&k).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &k, nil
+}
+
+func (r *idempotencyRepo) Reserve(k *model.IdempotencyKey) (bool, error) {
+    res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(k)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected > 0, nil
+}
+
+func (r *idempotencyRepo) Complete(id uint, status int, contentType string, response []byte) error {
+    return r.db.Model(&model.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+        "completed":    true,
+        "status_code":  status,
+        "content_type": contentType,
+        "response":     response,
+    }).Error
+}
+
+func (r *idempotencyRepo) Extend(id uint, t time.Time) error {
+    return r.db.Model(&model.IdempotencyKey{}).Where("id = ? AND completed = ?", id, false).Update("locked_until", t).Error
+}
+
+func (r *idempotencyRepo) Delete(id uint) error {
+    return r.db.Delete(&model.IdempotencyKey{}, id).Error
+}
+
+func (r *idempotencyRepo) DeleteExpired(t time.Time) (int64, error) {
+    res := r.db.Where("expires_at < ?", t).Delete(&model.IdempotencyKey{})
+    return res.RowsAffected, res.Error
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type IngredientRepository interface {
+    Create(i *model.Ingredient) error
+    Update(i *model.Ingredient) error
+    Delete(id uint) error
+    List() ([]model.Ingredient, error)
+    GetByID(id uint) (*model.Ingredient, error)
+    FindByName(name string) (*model.Ingredient, error)
+    // RecipeMenuIDs returns the menus whose cost is derived from a recipe using the ingredient
+    RecipeMenuIDs(ingredientID uint) ([]uint, error)
+    // CountUsage returns how many recipe lines use the ingredient
+    CountUsage(ingredientID uint) (int64, error)
+}
+
+type ingredientRepo struct{
+    db *gorm.DB
+}
+
+func NewIngredientRepository() IngredientRepository {
+    return &ingredientRepo{db: config.DB}
+}
+
+func (r *ingredientRepo) Create(i *model.Ingredient) error {
+    return r.db.Create(i).Error
+}
+
+func (r *ingredientRepo) Update(i *model.Ingredient) error {
+    return r.db.Save(i).Error
+}
+
+func (r *ingredientRepo) Delete(id uint) error {
+    return r.db.Delete(&model.Ingredient{}, id).Error
+}
+
+func (r *ingredientRepo) List() ([]model.Ingredient, error) {
+    var list []model.Ingredient
+    if err := r.db.Order("name").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *ingredientRepo) GetByID(id uint) (*model.Ingredient, error) {
+    var i model.Ingredient
+    if err := r.db.First(&i, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &i, nil
+}
+
+func (r *ingredientRepo) FindByName(name string) (*model.Ingredient, error) {
+    var i model.Ingredient
+    if err := r.db.Where("name = ?", name).First(&i).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &i, nil
+}
+
+func (r *ingredientRepo) RecipeMenuIDs(ingredientID uint) ([]uint, error) {
+    var ids []uint
+    err := r.db.Model(&model.MenuIngredient{}).
+        Joins("JOIN menus ON menus.id = menu_ingredients.menu_id").
+        Where("menu_ingredients.ingredient_id = ? AND menus.cost_mode = ?", ingredientID, model.CostModeRecipe).
+        Distinct().Pluck("menu_ingredients.menu_id", &ids).Error
+    return ids, err
+}
+
+func (r *ingredientRepo) CountUsage(ingredientID uint) (int64, error) {
+    var n int64
+    err := r.db.Model(&model.MenuIngredient{}).Where("ingredient_id = ?", ingredientID).Count(&n).Error
+    return n, err
+}
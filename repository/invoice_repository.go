@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InvoiceRepository interface {
+    // Next increments and returns the sequence of scope. Call it on a repository bound to the
+    // database transaction storing the invoice: the sequence stays locked until it commits,
+    // and a rollback gives the number back, so numbers have no gaps.
+    Next(scope string) (int, error)
+    WithTx(tx *gorm.DB) InvoiceRepository
+}
+
+type invoiceRepo struct{
+    db *gorm.DB
+}
+
+func NewInvoiceRepository() InvoiceRepository {
+    return &invoiceRepo{db: config.DB}
+}
+
+func (r *invoiceRepo) WithTx(tx *gorm.DB) InvoiceRepository {
+    return &invoiceRepo{db: tx}
+}
+
+func (r *invoiceRepo) Next(scope string) (int, error) {
+    if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.InvoiceSequence{Scope: scope}).Error; err != nil {
+        return 0, err
+    }
+    var seq model.InvoiceSequence
+    if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("scope = ?", scope).First(&seq).Error; err != nil {
+        return 0, err
+    }
+    seq.LastNumber++
+    if err := r.db.Model(&model.InvoiceSequence{}).Where("scope = ?", scope).Update("last_number", seq.LastNumber).Error; err != nil {
+        return 0, err
+    }
+    return seq.LastNumber, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type KitchenRepository interface {
+    ListStations() ([]model.KitchenStation, error)
+    GetStation(id uint) (*model.KitchenStation, error)
+    CreateStation(st *model.KitchenStation) error
+    // UpdateStation saves the station and replaces its categories
+    UpdateStation(st *model.KitchenStation) error
+    DeleteStation(id uint) error
+    CreateTicket(t *model.KitchenTicket) error
+    GetTicket(id uint) (*model.KitchenTicket, error)
+    // ListTickets returns the tickets of a station (all when 0) in the given states (all
+    // when empty), oldest first
+    ListTickets(stationID uint, statuses []string) ([]model.KitchenTicket, error)
+    // ListTicketsBetween returns the tickets created in [start, end)
+    ListTicketsBetween(start, end time.Time) ([]model.KitchenTicket, error)
+    // LockTicketsOfTransaction returns the tickets of a sale in the given states with their
+    // lines and locks them until the surrounding database transaction ends
+    LockTicketsOfTransaction(transactionID uint, statuses []string) ([]model.KitchenTicket, error)
+    // LockTicketsOfBillItem is LockTicketsOfTransaction for the tickets with a line of a bill item
+    LockTicketsOfBillItem(billItemID uint, statuses []string) ([]model.KitchenTicket, error)
+    UpdateTicketItemQuantity(itemID uint, quantity int) error
+    DeleteTicketItem(itemID uint) error
+    // Transition applies updates only if the ticket is still in status from and reports
+    // whether it did
+    Transition(id uint, from string, updates map[string]interface{}) (bool, error)
+    WithTx(tx *gorm.DB) KitchenRepository
+}
+
+type kitchenRepo struct{
+    db *gorm.DB
+}
+
+func NewKitchenRepository() KitchenRepository {
+    return &kitchenRepo{db: config.DB}
+}
+
+func (r *kitchenRepo) WithTx(tx *gorm.DB) KitchenRepository {
+    return &kitchenRepo{db: tx}
+}
+
+func (r *kitchenRepo) ListStations() ([]model.KitchenStation, error) {
+    var list []model.KitchenStation
+    if err := r.db.Preload("Categories").Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *kitchenRepo) GetStation(id uint) (*model.KitchenStation, error) {
+    var st model.KitchenStation
+    if err := r.db.Preload("Categories").First(&st, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &st, nil
+}
+
+// CreateStation inserts the station with references to its (existing) categories
+func (r *kitchenRepo) CreateStation(st *model.KitchenStation) error {
+    return r.db.Omit("Categories.*").Create(st).Error
+}
+
+func (r *kitchenRepo) UpdateStation(st *model.KitchenStation) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Omit("Categories").Save(st).Error; err != nil {
+            return err
+        }
+        return tx.Model(st).Omit("Categories.*").Association("Categories").Replace(st.Categories)
+    })
+}
+
+func (r *kitchenRepo) DeleteStation(id uint) error {
+    return r.db.Select("Categories").Delete(&model.KitchenStation{ID: id}).Error
+}
+
+func (r *kitchenRepo) CreateTicket(t *model.KitchenTicket) error {
+    return r.db.Create(t).Error
+}
+
+func (r *kitchenRepo) GetTicket(id uint) (*model.KitchenTicket, error) {
+    var t model.KitchenTicket
+    if err := r.db.Preload("Items").First(&t, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *kitchenRepo) ListTickets(stationID uint, statuses []string) ([]model.KitchenTicket, error) {
+    q := r.db.Preload("Items").Order("id")
+    if stationID != 0 {
+        q = q.Where("station_id = ?", stationID)
+    }
+    if len(statuses) > 0 {
+        q = q.Where("status IN ?", statuses)
+    }
+    var list []model.KitchenTicket
+    if err := q.Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *kitchenRepo) ListTicketsBetween(start, end time.Time) ([]model.KitchenTicket, error) {
+    var list []model.KitchenTicket
+    if err := r.db.Where("created_at >= ? AND created_at < ?", start, end).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *kitchenRepo) LockTicketsOfTransaction(transactionID uint, statuses []string) ([]model.KitchenTicket, error) {
+    return r.lockTickets(r.db.Where("transaction_id = ?", transactionID), statuses)
+}
+
+func (r *kitchenRepo) LockTicketsOfBillItem(billItemID uint, statuses []string) ([]model.KitchenTicket, error) {
+    lines := r.db.Model(&model.KitchenTicketItem{}).Select("ticket_id").Where("bill_item_id = ?", billItemID)
+    return r.lockTickets(r.db.Where("id IN (?)", lines), statuses)
+}
+
+func (r *kitchenRepo) lockTickets(q *gorm.DB, statuses []string) ([]model.KitchenTicket, error) {
+    var list []model.KitchenTicket
+    q = q.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items", func(db *gorm.DB) *gorm.DB {
+        return db.Order("id")
+    }).Where("status IN ?", statuses).Order("id")
+    if err := q.Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *kitchenRepo) UpdateTicketItemQuantity(itemID uint, quantity int) error {
+    return r.db.Model(&model.KitchenTicketItem{}).Where("id = ?", itemID).Update("quantity", quantity).Error
+}
+
+func (r *kitchenRepo) DeleteTicketItem(itemID uint) error {
+    return r.db.Delete(&model.KitchenTicketItem{}, itemID).Error
+}
+
+func (r *kitchenRepo) Transition(id uint, from string, updates map[string]interface{}) (bool, error) {
+    res := r.db.Model(&model.KitchenTicket{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
@@ -1,17 +1,82 @@
 package repository
 
 import (
+	"strings"
+	"time"
+
 	"github.com/IndalAwalaikal/warung-pos/backend/config"
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
 	"gorm.io/gorm"
 )
 
+// MenuFilter holds the menu-specific search options of GET /api/menus
+type MenuFilter struct {
+    Search      string
+    // Locale also matches Search against translations in this locale
+    Locale      string
+    CategoryIDs []uint
+    MinPrice    *float64
+    MaxPrice    *float64
+    IsAvailable *bool
+    // TagSlugs keeps menus carrying every one of the tags
+    TagSlugs    []string
+    // ExcludeAllergens drops menus containing any of the allergen codes
+    ExcludeAllergens []string
+}
+
+// MenuListOptions is the sort/filter whitelist for menu listing
+var MenuListOptions = utils.ListOptions{
+    DefaultLimit: 100,
+    MaxLimit:     500,
+    Unpaginated:  true,
+    DefaultSort:  "name",
+    SortFields: map[string]utils.Field{
+        "name":       {Column: "menus.name", Kind: utils.KindString},
+        "price":      {Column: "menus.price", Kind: utils.KindNumber},
+        "created_at": {Column: "menus.created_at", Kind: utils.KindTime},
+        "updated_at": {Column: "menus.updated_at", Kind: utils.KindTime},
+    },
+    FilterFields: map[string]utils.Field{
+        "id":           {Column: "menus.id", Kind: utils.KindNumber},
+        "name":         {Column: "menus.name", Kind: utils.KindString},
+        "price":        {Column: "menus.price", Kind: utils.KindNumber},
+        "category_id":  {Column: "menus.category_id", Kind: utils.KindNumber},
+        "is_available": {Column: "menus.is_available", Kind: utils.KindBool},
+        "created_at":   {Column: "menus.created_at", Kind: utils.KindTime},
+        "updated_at":   {Column: "menus.updated_at", Kind: utils.KindTime},
+    },
+}
+
 type MenuRepository interface {
     Create(m *model.Menu) error
     List() ([]model.Menu, error)
+    Search(f MenuFilter, q utils.ListQuery) ([]model.Menu, utils.PageMeta, error)
     GetByID(id uint) (*model.Menu, error)
     Update(m *model.Menu) error
     Delete(id uint) error
+    FindByName(name string) (*model.Menu, error)
+    FindByImageURLs(urls []string) ([]model.Menu, error)
+    FindBySKU(sku string) (*model.Menu, error)
+    // FindByBarcode returns the menu owning any of the given codes
+    FindByBarcode(codes []string) (*model.Menu, error)
+    ListByIDs(ids []uint) ([]model.Menu, error)
+    // ReplaceBarcodes makes codes the exact barcode set of the menu
+    ReplaceBarcodes(menuID uint, codes []model.MenuBarcode) error
+    // Recipe returns the recipe lines of a menu with their ingredients
+    Recipe(menuID uint) ([]model.MenuIngredient, error)
+    // ReplaceRecipe makes lines the exact recipe of the menu
+    ReplaceRecipe(menuID uint, lines []model.MenuIngredient) error
+    // ReplaceTags makes tags (existing records) the exact tag set of the menu
+    ReplaceTags(menuID uint, tags []model.Tag) error
+    // ReplaceAllergens makes codes the exact allergen set of the menu
+    ReplaceAllergens(menuID uint, codes []string) error
+    // SetCost stores the cost price and mode without touching other columns
+    SetCost(menuID uint, cost float64, mode string) error
+    // SetAvailable switches is_available without touching other columns
+    SetAvailable(menuID uint, available bool) error
+    // WithTx returns a repository that runs its queries inside tx
+    WithTx(tx *gorm.DB) MenuRepository
 }
 
 type menuRepo struct{
@@ -22,21 +87,90 @@ func NewMenuRepository() MenuRepository {
     return &menuRepo{db: config.DB}
 }
 
+// Create inserts the menu with its barcodes, allergens and references to the (existing) tags
 func (r *menuRepo) Create(m *model.Menu) error {
-    return r.db.Create(m).Error
+    return r.db.Omit("Tags.*").Create(m).Error
 }
 
 func (r *menuRepo) List() ([]model.Menu, error) {
     var list []model.Menu
-    if err := r.db.Preload("Category").Find(&list).Error; err != nil {
+    if err := r.db.Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens").Find(&list).Error; err != nil {
         return nil, err
     }
     return list, nil
 }
 
+// Search returns one page (or, unpaginated, all) of the menus matching the filter together with pagination metadata
+func (r *menuRepo) Search(f MenuFilter, q utils.ListQuery) ([]model.Menu, utils.PageMeta, error) {
+    base := r.db.Model(&model.Menu{})
+    if s := strings.TrimSpace(f.Search); s != "" {
+        // match every word in either name or description
+        for _, w := range strings.Fields(strings.ToLower(s)) {
+            like := "%" + utils.EscapeLike(w) + "%"
+            if f.Locale != "" {
+                base = base.Where("(LOWER(menus.name) LIKE ? OR LOWER(menus.description) LIKE ? OR EXISTS (SELECT 1 FROM menu_translations mt WHERE mt.menu_id = menus.id AND mt.locale = ? AND (LOWER(mt.name) LIKE ? OR LOWER(mt.description) LIKE ?)))", like, like, f.Locale, like, like)
+            } else {
+                base = base.Where("(LOWER(menus.name) LIKE ? OR LOWER(menus.description) LIKE ?)", like, like)
+            }
+        }
+    }
+    if len(f.CategoryIDs) > 0 {
+        base = base.Where("menus.category_id IN ?", f.CategoryIDs)
+    }
+    if f.MinPrice != nil {
+        base = base.Where("menus.price >= ?", *f.MinPrice)
+    }
+    if f.MaxPrice != nil {
+        base = base.Where("menus.price <= ?", *f.MaxPrice)
+    }
+    if f.IsAvailable != nil {
+        base = base.Where("menus.is_available = ?", *f.IsAvailable)
+    }
+    for _, slug := range f.TagSlugs {
+        base = base.Where("EXISTS (SELECT 1 FROM menu_tags mtg JOIN tags ON tags.id = mtg.tag_id WHERE mtg.menu_id = menus.id AND tags.slug = ?)", slug)
+    }
+    if len(f.ExcludeAllergens) > 0 {
+        base = base.Where("NOT EXISTS (SELECT 1 FROM menu_allergens ma WHERE ma.menu_id = menus.id AND ma.code IN ?)", f.ExcludeAllergens)
+    }
+    base = q.ApplyFilters(base)
+
+    var total int64
+    if err := base.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+        return nil, utils.PageMeta{}, err
+    }
+
+    var list []model.Menu
+    if err := q.ApplyPage(base.Session(&gorm.Session{}), "menus").Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens").Find(&list).Error; err != nil {
+        return nil, utils.PageMeta{}, err
+    }
+    fetched := len(list)
+    if q.Limit > 0 && fetched > q.Limit {
+        list = list[:q.Limit]
+    }
+    meta := q.Meta(total, fetched, func() (interface{}, uint) {
+        last := list[len(list)-1]
+        return menuSortValue(last, q.Sort.Name), last.ID
+    })
+    return list, meta, nil
+}
+
+func menuSortValue(m model.Menu, field string) interface{} {
+    switch field {
+    case "name":
+        return m.Name
+    case "price":
+        return m.Price
+    case "created_at":
+        return m.CreatedAt
+    case "updated_at":
+        return m.UpdatedAt
+    }
+    return m.ID
+}
+
 func (r *menuRepo) GetByID(id uint) (*model.Menu, error) {
     var m model.Menu
-    if err := r.db.Preload("Category").First(&m, id).Error; err != nil {
+    if err := r.db.Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens").First(&m, id).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
             return nil, nil
         }
@@ -45,10 +179,164 @@ func (r *menuRepo) GetByID(id uint) (*model.Menu, error) {
     return &m, nil
 }
 
+// Update saves the menu columns; barcodes, recipe, tags and allergens are changed through
+// their Replace methods
 func (r *menuRepo) Update(m *model.Menu) error {
-    return r.db.Save(m).Error
+    return r.db.Omit("Barcodes", "Recipe", "Tags", "Allergens").Save(m).Error
 }
 
+// Delete removes the menu and leaves a tombstone for offline devices
 func (r *menuRepo) Delete(id uint) error {
-    return r.db.Delete(&model.Menu{}, id).Error
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        res := tx.Delete(&model.Menu{}, id)
+        if res.Error != nil || res.RowsAffected == 0 {
+            return res.Error
+        }
+        return tx.Create(&model.Tombstone{Entity: model.TombstoneMenu, EntityID: id, DeletedAt: time.Now()}).Error
+    })
+}
+
+func (r *menuRepo) FindByName(name string) (*model.Menu, error) {
+    var m model.Menu
+    if err := r.db.Where("name = ?", name).First(&m).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &m, nil
+}
+
+func (r *menuRepo) FindByImageURLs(urls []string) ([]model.Menu, error) {
+    var list []model.Menu
+    if len(urls) == 0 {
+        return list, nil
+    }
+    if err := r.db.Where("image_url IN ?", urls).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *menuRepo) FindBySKU(sku string) (*model.Menu, error) {
+    var m model.Menu
+    if err := r.db.Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens").Where("sku = ?", sku).First(&m).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &m, nil
+}
+
+func (r *menuRepo) FindByBarcode(codes []string) (*model.Menu, error) {
+    var b model.MenuBarcode
+    if err := r.db.Where("code IN ?", codes).First(&b).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return r.GetByID(b.MenuID)
+}
+
+func (r *menuRepo) ListByIDs(ids []uint) ([]model.Menu, error) {
+    var list []model.Menu
+    if err := r.db.Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens").Where("id IN ?", ids).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *menuRepo) ReplaceBarcodes(menuID uint, codes []model.MenuBarcode) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        keep := []string{}
+        for _, c := range codes {
+            keep = append(keep, c.Code)
+        }
+        del := tx.Where("menu_id = ?", menuID)
+        if len(keep) > 0 {
+            del = del.Where("code NOT IN ?", keep)
+        }
+        if err := del.Delete(&model.MenuBarcode{}).Error; err != nil {
+            return err
+        }
+        var existing []model.MenuBarcode
+        if err := tx.Where("menu_id = ?", menuID).Find(&existing).Error; err != nil {
+            return err
+        }
+        have := map[string]model.MenuBarcode{}
+        for _, e := range existing {
+            have[e.Code] = e
+        }
+        for _, c := range codes {
+            if e, ok := have[c.Code]; ok {
+                if e.Symbology != c.Symbology {
+                    if err := tx.Model(&e).Update("symbology", c.Symbology).Error; err != nil {
+                        return err
+                    }
+                }
+                continue
+            }
+            nb := model.MenuBarcode{MenuID: menuID, Code: c.Code, Symbology: c.Symbology}
+            if err := tx.Create(&nb).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (r *menuRepo) Recipe(menuID uint) ([]model.MenuIngredient, error) {
+    var lines []model.MenuIngredient
+    if err := r.db.Preload("Ingredient").Where("menu_id = ?", menuID).Order("id").Find(&lines).Error; err != nil {
+        return nil, err
+    }
+    return lines, nil
+}
+
+func (r *menuRepo) ReplaceRecipe(menuID uint, lines []model.MenuIngredient) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("menu_id = ?", menuID).Delete(&model.MenuIngredient{}).Error; err != nil {
+            return err
+        }
+        for _, l := range lines {
+            nl := model.MenuIngredient{MenuID: menuID, IngredientID: l.IngredientID, Quantity: l.Quantity}
+            if err := tx.Omit("Ingredient").Create(&nl).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (r *menuRepo) ReplaceTags(menuID uint, tags []model.Tag) error {
+    m := model.Menu{ID: menuID}
+    return r.db.Model(&m).Omit("Tags.*").Association("Tags").Replace(tags)
+}
+
+func (r *menuRepo) ReplaceAllergens(menuID uint, codes []string) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("menu_id = ?", menuID).Delete(&model.MenuAllergen{}).Error; err != nil {
+            return err
+        }
+        for _, c := range codes {
+            if err := tx.Create(&model.MenuAllergen{MenuID: menuID, Code: c}).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+func (r *menuRepo) SetCost(menuID uint, cost float64, mode string) error {
+    return r.db.Model(&model.Menu{}).Where("id = ?", menuID).UpdateColumns(map[string]interface{}{"cost_price": cost, "cost_mode": mode}).Error
+}
+
+func (r *menuRepo) SetAvailable(menuID uint, available bool) error {
+    return r.db.Model(&model.Menu{}).Where("id = ?", menuID).Update("is_available", available).Error
+}
+
+func (r *menuRepo) WithTx(tx *gorm.DB) MenuRepository {
+    return &menuRepo{db: tx}
 }
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type PaymentRepository interface {
+    GetByID(id uint) (*model.Payment, error)
+    GetByOrderID(orderID string) (*model.Payment, error)
+    ListByTransaction(transactionID uint) ([]model.Payment, error)
+    // ListExpired returns pending payments whose QR code expired before t
+    ListExpired(t time.Time) ([]model.Payment, error)
+    Update(id uint, updates map[string]interface{}) error
+    // Transition applies updates only if the payment is still in status from and reports
+    // whether it did, so a repeated webhook is applied once
+    Transition(id uint, from string, updates map[string]interface{}) (bool, error)
+    WithTx(tx *gorm.DB) PaymentRepository
+}
+
+type paymentRepo struct{
+    db *gorm.DB
+}
+
+func NewPaymentRepository() PaymentRepository {
+    return &paymentRepo{db: config.DB}
+}
+
+func (r *paymentRepo) WithTx(tx *gorm.DB) PaymentRepository {
+    return &paymentRepo{db: tx}
+}
+
+func (r *paymentRepo) GetByID(id uint) (*model.Payment, error) {
+    var p model.Payment
+    if err := r.db.First(&p, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *paymentRepo) GetByOrderID(orderID string) (*model.Payment, error) {
+    var p model.Payment
+    if err := r.db.Where("order_id = ?", orderID).First(&p).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *paymentRepo) ListByTransaction(transactionID uint) ([]model.Payment, error) {
+    var list []model.Payment
+    if err := r.db.Where("transaction_id = ?", transactionID).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *paymentRepo) ListExpired(t time.Time) ([]model.Payment, error) {
+    var list []model.Payment
+    err := r.db.Where("status = ? AND expires_at IS NOT NULL AND expires_at < ?", model.PaymentPending, t).
+        Order("id").Find(&list).Error
+    if err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *paymentRepo) Update(id uint, updates map[string]interface{}) error {
+    return r.db.Model(&model.Payment{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *paymentRepo) Transition(id uint, from string, updates map[string]interface{}) (bool, error) {
+    res := r.db.Model(&model.Payment{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
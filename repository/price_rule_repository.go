@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type PriceRuleRepository interface {
+    Create(r *model.PriceRule) error
+    Update(r *model.PriceRule) error
+    Delete(id uint) error
+    List() ([]model.PriceRule, error)
+    // ListActive returns the enabled rules; time conditions are evaluated by the caller
+    ListActive() ([]model.PriceRule, error)
+    GetByID(id uint) (*model.PriceRule, error)
+}
+
+type priceRuleRepo struct{
+    db *gorm.DB
+}
+
+func NewPriceRuleRepository() PriceRuleRepository {
+    return &priceRuleRepo{db: config.DB}
+}
+
+func (r *priceRuleRepo) Create(pr *model.PriceRule) error {
+    active := pr.IsActive
+    if err := r.db.Create(pr).Error; err != nil {
+        return err
+    }
+    // is_active has a database default, gorm skips the false value on insert
+    if !active {
+        pr.IsActive = false
+        return r.db.Model(pr).Update("is_active", false).Error
+    }
+    return nil
+}
+
+func (r *priceRuleRepo) Update(pr *model.PriceRule) error {
+    // Select("*") so is_active=false and cleared targets are written too
+    return r.db.Model(pr).Select("*").Omit("CreatedAt").Updates(pr).Error
+}
+
+func (r *priceRuleRepo) Delete(id uint) error {
+    return r.db.Delete(&model.PriceRule{}, id).Error
+}
+
+func (r *priceRuleRepo) List() ([]model.PriceRule, error) {
+    var list []model.PriceRule
+    if err := r.db.Order("priority DESC, id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *priceRuleRepo) ListActive() ([]model.PriceRule, error) {
+    var list []model.PriceRule
+    if err := r.db.Where("is_active = ?", true).Order("priority DESC, id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *priceRuleRepo) GetByID(id uint) (*model.PriceRule, error) {
+    var pr model.PriceRule
+    if err := r.db.First(&pr, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &pr, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type PromotionRepository interface {
+    Create(p *model.Promotion) error
+    Update(p *model.Promotion) error
+    Delete(id uint) error
+    List() ([]model.Promotion, error)
+    GetByID(id uint) (*model.Promotion, error)
+    GetByCode(code string) (*model.Promotion, error)
+    // ListAutomatic returns the enabled promotions without a voucher code
+    ListAutomatic() ([]model.Promotion, error)
+    // Redeem counts one use of a promotion. It reports false when the usage limit is
+    // already reached; the check and the increment are a single statement.
+    Redeem(id uint) (bool, error)
+    // Release gives back one use of a promotion whose transaction did not go through
+    Release(id uint) error
+    // WithTx returns a repository bound to the given database transaction
+    WithTx(tx *gorm.DB) PromotionRepository
+}
+
+type promotionRepo struct{
+    db *gorm.DB
+}
+
+func NewPromotionRepository() PromotionRepository {
+    return &promotionRepo{db: config.DB}
+}
+
+func (r *promotionRepo) WithTx(tx *gorm.DB) PromotionRepository {
+    return &promotionRepo{db: tx}
+}
+
+func (r *promotionRepo) Create(p *model.Promotion) error {
+    active := p.IsActive
+    if err := r.db.Omit("UsageCount").Create(p).Error; err != nil {
+        return err
+    }
+    // is_active has a database default, gorm skips the false value on insert
+    if !active {
+        p.IsActive = false
+        return r.db.Model(p).Update("is_active", false).Error
+    }
+    return nil
+}
+
+func (r *promotionRepo) Update(p *model.Promotion) error {
+    // the usage counter is only changed by Redeem/Release
+    return r.db.Model(p).Select("*").Omit("CreatedAt", "UsageCount").Updates(p).Error
+}
+
+func (r *promotionRepo) Delete(id uint) error {
+    return r.db.Delete(&model.Promotion{}, id).Error
+}
+
+func (r *promotionRepo) List() ([]model.Promotion, error) {
+    var list []model.Promotion
+    if err := r.db.Order("id DESC").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *promotionRepo) GetByID(id uint) (*model.Promotion, error) {
+    var p model.Promotion
+    if err := r.db.First(&p, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *promotionRepo) GetByCode(code string) (*model.Promotion, error) {
+    var p model.Promotion
+    if err := r.db.Where("code = ?", code).First(&p).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &p, nil
+}
+
+func (r *promotionRepo) ListAutomatic() ([]model.Promotion, error) {
+    var list []model.Promotion
+    if err := r.db.Where("code IS NULL AND is_active = ?", true).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *promotionRepo) Redeem(id uint) (bool, error) {
+    res := r.db.Model(&model.Promotion{}).
+        Where("id = ? AND (usage_limit = 0 OR usage_count < usage_limit)", id).
+        UpdateColumn("usage_count", gorm.Expr("usage_count + 1"))
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
+
+func (r *promotionRepo) Release(id uint) error {
+    return r.db.Model(&model.Promotion{}).
+        Where("id = ? AND usage_count > 0", id).
+        UpdateColumn("usage_count", gorm.Expr("usage_count - 1")).Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type RefundRepository interface {
+    Create(r *model.Refund) error
+    ListByTransaction(transactionID uint) ([]model.Refund, error)
+    // ReverseItem adds quantity to the refunded units of a transaction line unless that
+    // would exceed the units sold; it reports whether it did
+    ReverseItem(itemID uint, quantity int) (bool, error)
+    WithTx(tx *gorm.DB) RefundRepository
+}
+
+type refundRepo struct{
+    db *gorm.DB
+}
+
+func NewRefundRepository() RefundRepository {
+    return &refundRepo{db: config.DB}
+}
+
+func (r *refundRepo) WithTx(tx *gorm.DB) RefundRepository {
+    return &refundRepo{db: tx}
+}
+
+func (r *refundRepo) Create(rf *model.Refund) error {
+    return r.db.Create(rf).Error
+}
+
+func (r *refundRepo) ListByTransaction(transactionID uint) ([]model.Refund, error) {
+    var list []model.Refund
+    if err := r.db.Preload("Items").Where("transaction_id = ?", transactionID).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *refundRepo) ReverseItem(itemID uint, quantity int) (bool, error) {
+    res := r.db.Model(&model.TransactionItem{}).
+        Where("id = ? AND refunded_quantity + ? <= quantity", itemID, quantity).
+        UpdateColumn("refunded_quantity", gorm.Expr("refunded_quantity + ?", quantity))
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type SelfOrderRepository interface {
+    Create(o *model.SelfOrder) error
+    GetByID(id uint) (*model.SelfOrder, error)
+    // List returns orders with the given status (all when empty), oldest first
+    List(status string) ([]model.SelfOrder, error)
+    CountPending(tableID uint) (int64, error)
+    // Transition applies updates only if the order is still in status from and reports
+    // whether it did, so two cashiers cannot handle the same order
+    Transition(id uint, from string, updates map[string]interface{}) (bool, error)
+    WithTx(tx *gorm.DB) SelfOrderRepository
+}
+
+type selfOrderRepo struct{
+    db *gorm.DB
+}
+
+func NewSelfOrderRepository() SelfOrderRepository {
+    return &selfOrderRepo{db: config.DB}
+}
+
+func (r *selfOrderRepo) WithTx(tx *gorm.DB) SelfOrderRepository {
+    return &selfOrderRepo{db: tx}
+}
+
+func (r *selfOrderRepo) Create(o *model.SelfOrder) error {
+    return r.db.Omit("Items.Menu", "Table").Create(o).Error
+}
+
+func (r *selfOrderRepo) GetByID(id uint) (*model.SelfOrder, error) {
+    var o model.SelfOrder
+    if err := r.db.Preload("Table").Preload("Items.Menu", withoutCosts).First(&o, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &o, nil
+}
+
+func (r *selfOrderRepo) List(status string) ([]model.SelfOrder, error) {
+    var list []model.SelfOrder
+    q := r.db.Preload("Table").Preload("Items.Menu", withoutCosts).Order("created_at, id")
+    if status != "" {
+        q = q.Where("status = ?", status)
+    }
+    if err := q.Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *selfOrderRepo) CountPending(tableID uint) (int64, error) {
+    var n int64
+    err := r.db.Model(&model.SelfOrder{}).Where("table_id = ? AND status = ?", tableID, model.SelfOrderPending).Count(&n).Error
+    return n, err
+}
+
+func (r *selfOrderRepo) Transition(id uint, from string, updates map[string]interface{}) (bool, error) {
+    res := r.db.Model(&model.SelfOrder{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+// SyncRepository answers what changed in the catalog for offline devices
+type SyncRepository interface {
+    // MenusChangedSince returns menus created or updated at or after t (all when t is nil)
+    MenusChangedSince(t *time.Time) ([]model.Menu, error)
+    CategoriesChangedSince(t *time.Time) ([]model.Category, error)
+    // DeletedSince returns the ids of entity records deleted at or after t
+    DeletedSince(entity string, t time.Time) ([]uint, error)
+    // PurgeTombstones removes tombstones older than t
+    PurgeTombstones(t time.Time) (int64, error)
+}
+
+type syncRepo struct{
+    db *gorm.DB
+}
+
+func NewSyncRepository() SyncRepository {
+    return &syncRepo{db: config.DB}
+}
+
+func (r *syncRepo) MenusChangedSince(t *time.Time) ([]model.Menu, error) {
+    q := r.db.Preload("Category").Preload("Barcodes").Preload("Tags").Preload("Allergens")
+    if t != nil {
+        q = q.Where("updated_at >= ?", *t)
+    }
+    var list []model.Menu
+    if err := q.Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *syncRepo) CategoriesChangedSince(t *time.Time) ([]model.Category, error) {
+    q := r.db
+    if t != nil {
+        q = q.Where("updated_at >= ?", *t)
+    }
+    var list []model.Category
+    if err := q.Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *syncRepo) DeletedSince(entity string, t time.Time) ([]uint, error) {
+    ids := []uint{}
+    err := r.db.Model(&model.Tombstone{}).Where("entity = ? AND deleted_at >= ?", entity, t).
+        Distinct().Pluck("entity_id", &ids).Error
+    if err != nil {
+        return nil, err
+    }
+    return ids, nil
+}
+
+func (r *syncRepo) PurgeTombstones(t time.Time) (int64, error) {
+    res := r.db.Where("deleted_at < ?", t).Delete(&model.Tombstone{})
+    return res.RowsAffected, res.Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type TableRepository interface {
+    Create(t *model.DiningTable) error
+    Update(t *model.DiningTable) error
+    Delete(id uint) error
+    List() ([]model.DiningTable, error)
+    GetByID(id uint) (*model.DiningTable, error)
+    FindByName(name string) (*model.DiningTable, error)
+}
+
+type tableRepo struct{
+    db *gorm.DB
+}
+
+func NewTableRepository() TableRepository {
+    return &tableRepo{db: config.DB}
+}
+
+func (r *tableRepo) Create(t *model.DiningTable) error {
+    return r.db.Create(t).Error
+}
+
+func (r *tableRepo) Update(t *model.DiningTable) error {
+    // Select("*") so is_active=false is written too
+    return r.db.Model(t).Select("*").Omit("CreatedAt").Updates(t).Error
+}
+
+func (r *tableRepo) Delete(id uint) error {
+    return r.db.Delete(&model.DiningTable{}, id).Error
+}
+
+func (r *tableRepo) List() ([]model.DiningTable, error) {
+    var list []model.DiningTable
+    if err := r.db.Order("name").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *tableRepo) GetByID(id uint) (*model.DiningTable, error) {
+    var t model.DiningTable
+    if err := r.db.First(&t, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *tableRepo) FindByName(name string) (*model.DiningTable, error) {
+    var t model.DiningTable
+    if err := r.db.Where("name = ?", name).First(&t).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
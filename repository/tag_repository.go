@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type TagRepository interface {
+    Create(t *model.Tag) error
+    Update(t *model.Tag) error
+    Delete(id uint) error
+    // List returns all tags with the number of menus using each
+    List() ([]TagUsage, error)
+    GetByID(id uint) (*model.Tag, error)
+    FindBySlug(slug string) (*model.Tag, error)
+}
+
+// TagUsage is a tag with the number of menus carrying it
+type TagUsage struct {
+    model.Tag
+    MenuCount int64 `json:"menu_count"`
+}
+
+type tagRepo struct{
+    db *gorm.DB
+}
+
+func NewTagRepository() TagRepository {
+    return &tagRepo{db: config.DB}
+}
+
+func (r *tagRepo) Create(t *model.Tag) error {
+    return r.db.Create(t).Error
+}
+
+func (r *tagRepo) Update(t *model.Tag) error {
+    return r.db.Save(t).Error
+}
+
+func (r *tagRepo) Delete(id uint) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Exec("DELETE FROM menu_tags WHERE tag_id = ?", id).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&model.Tag{}, id).Error
+    })
+}
+
+func (r *tagRepo) List() ([]TagUsage, error) {
+    var list []TagUsage
+    err := r.db.Model(&model.Tag{}).
+        Select("tags.*, (SELECT COUNT(*) FROM menu_tags WHERE menu_tags.tag_id = tags.id) AS menu_count").
+        Order("tags.name").Scan(&list).Error
+    if err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *tagRepo) GetByID(id uint) (*model.Tag, error) {
+    var t model.Tag
+    if err := r.db.First(&t, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *tagRepo) FindBySlug(slug string) (*model.Tag, error) {
+    var t model.Tag
+    if err := r.db.Where("slug = ?", slug).First(&t).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type TaxRuleRepository interface {
+    Create(r *model.TaxRule) error
+    Update(r *model.TaxRule) error
+    Delete(id uint) error
+    List() ([]model.TaxRule, error)
+    // ListActive returns the enabled rules in the order they are applied
+    ListActive() ([]model.TaxRule, error)
+    GetByID(id uint) (*model.TaxRule, error)
+}
+
+type taxRuleRepo struct{
+    db *gorm.DB
+}
+
+func NewTaxRuleRepository() TaxRuleRepository {
+    return &taxRuleRepo{db: config.DB}
+}
+
+func (r *taxRuleRepo) Create(tr *model.TaxRule) error {
+    active := tr.IsActive
+    if err := r.db.Create(tr).Error; err != nil {
+        return err
+    }
+    // is_active has a database default, gorm skips the false value on insert
+    if !active {
+        tr.IsActive = false
+        return r.db.Model(tr).Update("is_active", false).Error
+    }
+    return nil
+}
+
+func (r *taxRuleRepo) Update(tr *model.TaxRule) error {
+    return r.db.Model(tr).Select("*").Omit("CreatedAt").Updates(tr).Error
+}
+
+func (r *taxRuleRepo) Delete(id uint) error {
+    return r.db.Delete(&model.TaxRule{}, id).Error
+}
+
+func (r *taxRuleRepo) List() ([]model.TaxRule, error) {
+    var list []model.TaxRule
+    if err := r.db.Order("sequence, id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *taxRuleRepo) ListActive() ([]model.TaxRule, error) {
+    var list []model.TaxRule
+    if err := r.db.Where("is_active = ?", true).Order("sequence, id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *taxRuleRepo) GetByID(id uint) (*model.TaxRule, error) {
+    var tr model.TaxRule
+    if err := r.db.First(&tr, id).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &tr, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"strings"
+
 	"github.com/IndalAwalaikal/warung-pos/backend/config"
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"gorm.io/gorm"
@@ -10,23 +12,68 @@ type TransactionRepository interface {
     Create(tx *model.Transaction) error
     List() ([]model.Transaction, error)
     GetByID(id uint) (*model.Transaction, error)
+    // ListByInvoice returns the transactions whose invoice number starts with prefix, e.g. a
+    // full number or WRG-20261018 for a day
+    ListByInvoice(prefix string) ([]model.Transaction, error)
+    // ListPriceReview returns the offline sales flagged for a price review, oldest first
+    ListPriceReview() ([]model.Transaction, error)
+    // GetByClientUUID finds a sale uploaded by an offline device
+    GetByClientUUID(uuid string) (*model.Transaction, error)
+    // GetByReceiptToken finds the sale of a public receipt link
+    GetByReceiptToken(token string) (*model.Transaction, error)
+    // SetReceiptToken gives a transaction without a receipt token this one and reports
+    // whether it did
+    SetReceiptToken(id uint, token string) (bool, error)
+    // Transition applies updates only if the transaction is still in status from and
+    // reports whether it did
+    Transition(id uint, from string, updates map[string]interface{}) (bool, error)
+    // WithTx returns a repository bound to the given database transaction
+    WithTx(tx *gorm.DB) TransactionRepository
 }
 
 type transactionRepo struct{
     db *gorm.DB
 }
 
+// withoutCosts loads the menus of sale, bill and order lines without their cost prices, so
+// they do not leak through those endpoints; cost reports read TransactionItem.CostPrice
+func withoutCosts(db *gorm.DB) *gorm.DB {
+    return db.Omit("cost_price", "cost_mode")
+}
+
 func NewTransactionRepository() TransactionRepository {
     return &transactionRepo{db: config.DB}
 }
 
+func (r *transactionRepo) WithTx(tx *gorm.DB) TransactionRepository {
+    return &transactionRepo{db: tx}
+}
+
 func (r *transactionRepo) Create(tx *model.Transaction) error {
     return r.db.Create(tx).Error
 }
 
 func (r *transactionRepo) List() ([]model.Transaction, error) {
     var list []model.Transaction
-    if err := r.db.Preload("Items.Menu").Find(&list).Error; err != nil {
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Preload("Refunds.Items").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *transactionRepo) ListByInvoice(prefix string) ([]model.Transaction, error) {
+    var list []model.Transaction
+    // escape LIKE wildcards so they match literally
+    pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Preload("Refunds.Items").Where("invoice_number LIKE ?", pattern).Order("invoice_number").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *transactionRepo) ListPriceReview() ([]model.Transaction, error) {
+    var list []model.Transaction
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Preload("Refunds.Items").Where("price_review = ?", true).Order("id").Find(&list).Error; err != nil {
         return nil, err
     }
     return list, nil
@@ -34,7 +81,7 @@ func (r *transactionRepo) List() ([]model.Transaction, error) {
 
 func (r *transactionRepo) GetByID(id uint) (*model.Transaction, error) {
     var t model.Transaction
-    if err := r.db.Preload("Items.Menu").First(&t, id).Error; err != nil {
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Preload("Refunds.Items").First(&t, id).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
             return nil, nil
         }
@@ -42,3 +89,41 @@ func (r *transactionRepo) GetByID(id uint) (*model.Transaction, error) {
     }
     return &t, nil
 }
+
+func (r *transactionRepo) GetByClientUUID(uuid string) (*model.Transaction, error) {
+    var t model.Transaction
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Where("client_uuid = ?", uuid).First(&t).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *transactionRepo) GetByReceiptToken(token string) (*model.Transaction, error) {
+    var t model.Transaction
+    if err := r.db.Preload("Items.Menu", withoutCosts).Preload("Taxes").Preload("Promotions").Preload("Payments").Where("receipt_token = ?", token).First(&t).Error; err != nil {
+        if err == gorm.ErrRecordNotFound {
+            return nil, nil
+        }
+        return nil, err
+    }
+    return &t, nil
+}
+
+func (r *transactionRepo) SetReceiptToken(id uint, token string) (bool, error) {
+    res := r.db.Model(&model.Transaction{}).Where("id = ? AND receipt_token IS NULL", id).Update("receipt_token", token)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
+
+func (r *transactionRepo) Transition(id uint, from string, updates map[string]interface{}) (bool, error) {
+    res := r.db.Model(&model.Transaction{}).Where("id = ? AND status = ?", id, from).Updates(updates)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
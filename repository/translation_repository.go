@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TranslationRepository interface {
+    MenuTranslations(menuIDs []uint, locale string) ([]model.MenuTranslation, error)
+    CategoryTranslations(categoryIDs []uint, locale string) ([]model.CategoryTranslation, error)
+    ListMenu(menuID uint) ([]model.MenuTranslation, error)
+    ListCategory(categoryID uint) ([]model.CategoryTranslation, error)
+    UpsertMenu(t *model.MenuTranslation) error
+    UpsertCategory(t *model.CategoryTranslation) error
+    DeleteMenu(menuID uint, locale string) error
+    DeleteCategory(categoryID uint, locale string) error
+}
+
+type translationRepo struct{
+    db *gorm.DB
+}
+
+func NewTranslationRepository() TranslationRepository {
+    return &translationRepo{db: config.DB}
+}
+
+func (r *translationRepo) MenuTranslations(menuIDs []uint, locale string) ([]model.MenuTranslation, error) {
+    var list []model.MenuTranslation
+    if len(menuIDs) == 0 {
+        return list, nil
+    }
+    if err := r.db.Where("menu_id IN ? AND locale = ?", menuIDs, locale).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *translationRepo) CategoryTranslations(categoryIDs []uint, locale string) ([]model.CategoryTranslation, error) {
+    var list []model.CategoryTranslation
+    if len(categoryIDs) == 0 {
+        return list, nil
+    }
+    if err := r.db.Where("category_id IN ? AND locale = ?", categoryIDs, locale).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *translationRepo) ListMenu(menuID uint) ([]model.MenuTranslation, error) {
+    var list []model.MenuTranslation
+    if err := r.db.Where("menu_id = ?", menuID).Order("locale").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *translationRepo) ListCategory(categoryID uint) ([]model.CategoryTranslation, error) {
+    var list []model.CategoryTranslation
+    if err := r.db.Where("category_id = ?", categoryID).Order("locale").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *translationRepo) UpsertMenu(t *model.MenuTranslation) error {
+    return r.db.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "menu_id"}, {Name: "locale"}},
+        DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+    }).Create(t).Error
+}
+
+func (r *translationRepo) UpsertCategory(t *model.CategoryTranslation) error {
+    return r.db.Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "category_id"}, {Name: "locale"}},
+        DoUpdates: clause.AssignmentColumns([]string{"name", "updated_at"}),
+    }).Create(t).Error
+}
+
+func (r *translationRepo) DeleteMenu(menuID uint, locale string) error {
+    return r.db.Where("menu_id = ? AND locale = ?", menuID, locale).Delete(&model.MenuTranslation{}).Error
+}
+
+func (r *translationRepo) DeleteCategory(categoryID uint, locale string) error {
+    return r.db.Where("category_id = ? AND locale = ?", categoryID, locale).Delete(&model.CategoryTranslation{}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"gorm.io/gorm"
+)
+
+type UploadRepository interface {
+    Create(u *model.Upload) error
+    // Sync attaches the uploads owning the given urls to the entity and detaches every
+    // other upload previously attached to it
+    Sync(entityType string, entityID uint, urls []string) error
+    ListUnattached(before time.Time) ([]model.Upload, error)
+    Delete(id uint) error
+    WithTx(tx *gorm.DB) UploadRepository
+}
+
+type uploadRepo struct{
+    db *gorm.DB
+}
+
+func NewUploadRepository() UploadRepository {
+    return &uploadRepo{db: config.DB}
+}
+
+func (r *uploadRepo) Create(u *model.Upload) error {
+    return r.db.Create(u).Error
+}
+
+func (r *uploadRepo) Sync(entityType string, entityID uint, urls []string) error {
+    var ids []uint
+    if len(urls) > 0 {
+        if err := r.db.Model(&model.UploadFile{}).Where("url IN ?", urls).Distinct().Pluck("upload_id", &ids).Error; err != nil {
+            return err
+        }
+    }
+
+    detach := r.db.Model(&model.Upload{}).Where("entity_type = ? AND entity_id = ?", entityType, entityID)
+    if len(ids) > 0 {
+        detach = detach.Where("id NOT IN ?", ids)
+    }
+    if err := detach.Updates(map[string]interface{}{"entity_type": "", "entity_id": nil, "attached_at": nil}).Error; err != nil {
+        return err
+    }
+    if len(ids) == 0 {
+        return nil
+    }
+    return r.db.Model(&model.Upload{}).Where("id IN ?", ids).
+        Updates(map[string]interface{}{"entity_type": entityType, "entity_id": entityID, "attached_at": time.Now()}).Error
+}
+
+func (r *uploadRepo) ListUnattached(before time.Time) ([]model.Upload, error) {
+    var list []model.Upload
+    if err := r.db.Preload("Files").Where("attached_at IS NULL AND created_at < ?", before).Order("id").Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *uploadRepo) Delete(id uint) error {
+    return r.db.Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where("upload_id = ?", id).Delete(&model.UploadFile{}).Error; err != nil {
+            return err
+        }
+        return tx.Delete(&model.Upload{}, id).Error
+    })
+}
+
+func (r *uploadRepo) WithTx(tx *gorm.DB) UploadRepository {
+    return &uploadRepo{db: tx}
+}
@@ -12,6 +12,9 @@ type UserRepository interface {
     Create(user *model.User) error
     FindByEmail(email string) (*model.User, error)
     FindByID(id uint) (*model.User, error)
+    // ListApprovers returns the admins and managers that have set a PIN
+    ListApprovers() ([]model.User, error)
+    UpdatePIN(id uint, hash string) error
 }
 
 type userRepo struct{
@@ -47,3 +50,15 @@ func (r *userRepo) FindByID(id uint) (*model.User, error) {
     }
     return &u, nil
 }
+
+func (r *userRepo) ListApprovers() ([]model.User, error) {
+    var list []model.User
+    if err := r.db.Where("role IN ? AND pin <> ''", []string{"admin", "manager"}).Find(&list).Error; err != nil {
+        return nil, err
+    }
+    return list, nil
+}
+
+func (r *userRepo) UpdatePIN(id uint, hash string) error {
+    return r.db.Model(&model.User{}).Where("id = ?", id).Update("pin", hash).Error
+}
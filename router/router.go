@@ -1,11 +1,14 @@
 package router
 
 import (
+	"log"
 	"time"
 
 	"github.com/IndalAwalaikal/warung-pos/backend/config"
 	"github.com/IndalAwalaikal/warung-pos/backend/controller"
+	"github.com/IndalAwalaikal/warung-pos/backend/gateway"
 	"github.com/IndalAwalaikal/warung-pos/backend/middleware"
+	"github.com/IndalAwalaikal/warung-pos/backend/storage"
 	crepo "github.com/IndalAwalaikal/warung-pos/backend/repository"
 	cservice "github.com/IndalAwalaikal/warung-pos/backend/service"
 	"github.com/gin-contrib/cors"
@@ -19,34 +22,136 @@ func SetupRouter() *gin.Engine {
     corsCfg := cors.Config{
         AllowOrigins:     []string{frontendOrigin},
         AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-        ExposeHeaders:    []string{"Content-Length"},
+        AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "Accept-Language", "If-None-Match", "If-Modified-Since", "X-Approval-Token", "Idempotency-Key"},
+        ExposeHeaders:    []string{"Content-Length", "ETag", "Last-Modified", "Content-Language", "Idempotent-Replayed"},
         AllowCredentials: true,
         MaxAge:           12 * time.Hour,
     }
     r.Use(cors.New(corsCfg))
-    // serve uploaded files
-    r.Static("/uploads", "./uploads")
+    // upload storage (local directory or S3-compatible bucket)
+    store, err := storage.FromEnv()
+    if err != nil {
+        log.Fatalf("failed to configure storage: %v", err)
+    }
+    // QRIS payment gateway (none, mock or midtrans)
+    gw, err := gateway.FromEnv()
+    if err != nil {
+        log.Fatalf("failed to configure payment gateway: %v", err)
+    }
 
     // repositories
     userRepo := crepo.NewUserRepository()
     catRepo := crepo.NewCategoryRepository()
     menuRepo := crepo.NewMenuRepository()
     txRepo := crepo.NewTransactionRepository()
+    uploadRepo := crepo.NewUploadRepository()
+    translationRepo := crepo.NewTranslationRepository()
+    catalogRepo := crepo.NewCatalogRepository()
+    tableRepo := crepo.NewTableRepository()
+    selfOrderRepo := crepo.NewSelfOrderRepository()
+    ingredientRepo := crepo.NewIngredientRepository()
+    tagRepo := crepo.NewTagRepository()
+    priceRuleRepo := crepo.NewPriceRuleRepository()
+    taxRuleRepo := crepo.NewTaxRuleRepository()
+    promotionRepo := crepo.NewPromotionRepository()
+    paymentRepo := crepo.NewPaymentRepository()
+    refundRepo := crepo.NewRefundRepository()
+    idempotencyRepo := crepo.NewIdempotencyRepository()
+    syncRepo := crepo.NewSyncRepository()
+    billRepo := crepo.NewBillRepository()
+    kitchenRepo := crepo.NewKitchenRepository()
+    invoiceRepo := crepo.NewInvoiceRepository()
 
     // services
     authSvc := cservice.NewAuthService(userRepo)
-    catSvc := cservice.NewCategoryService(catRepo)
-    menuSvc := cservice.NewMenuService(menuRepo)
-    txSvc := cservice.NewTransactionService(txRepo)
-    reportSvc := cservice.NewReportService(txRepo)
+    catalogSvc := cservice.NewCatalogService(catalogRepo)
+    catSvc := cservice.NewCategoryService(catRepo, catalogSvc)
+    translationSvc := cservice.NewTranslationService(translationRepo, catalogSvc)
+    tagSvc := cservice.NewTagService(tagRepo, catalogSvc)
+    uploadGrace := time.Duration(config.GetEnvInt("UPLOAD_GC_GRACE_HOURS", 24)) * time.Hour
+    uploadSvc := cservice.NewUploadService(uploadRepo, menuRepo, store, uploadGrace)
+    menuSvc := cservice.NewMenuService(menuRepo, uploadSvc, catalogSvc, tagSvc)
+    menuImportSvc := cservice.NewMenuImportService(menuRepo, catRepo, uploadRepo, catalogSvc)
+    pricingSvc := cservice.NewPricingService(priceRuleRepo, menuRepo)
+    taxSvc := cservice.NewTaxService(taxRuleRepo)
+    promotionSvc := cservice.NewPromotionService(promotionRepo)
+    kitchenSvc := cservice.NewKitchenService(kitchenRepo, menuRepo, catRepo)
+    paymentSvc := cservice.NewPaymentService(gw, paymentRepo, txRepo, billRepo, promotionSvc, kitchenSvc)
+    txSvc := cservice.NewTransactionService(txRepo, menuRepo, pricingSvc, taxSvc, promotionSvc, paymentSvc, kitchenSvc, invoiceRepo)
+    refundSvc := cservice.NewRefundService(refundRepo, txRepo, authSvc, promotionSvc, kitchenSvc)
+    syncSvc := cservice.NewSyncService(syncRepo, catalogSvc, tagSvc, pricingSvc, taxSvc, promotionSvc)
+    reportSvc := cservice.NewReportService(txRepo, kitchenRepo)
+    tableSvc := cservice.NewTableService(tableRepo)
+    ingredientSvc := cservice.NewIngredientService(ingredientRepo, menuRepo)
+    selfOrderSvc := cservice.NewSelfOrderService(selfOrderRepo, menuRepo, tableSvc, txSvc, kitchenSvc)
+    receiptSvc := cservice.NewReceiptService(txRepo, menuRepo, userRepo)
+    billSvc := cservice.NewBillService(billRepo, menuRepo, tableSvc, txSvc, kitchenSvc)
 
     // controllers
     authCtrl := controller.NewAuthController(authSvc)
-    catCtrl := controller.NewCategoryController(catSvc)
-    menuCtrl := controller.NewMenuController(menuSvc)
+    catCtrl := controller.NewCategoryController(catSvc, translationSvc, catalogSvc)
+    menuCtrl := controller.NewMenuController(menuSvc, menuImportSvc, translationSvc, catalogSvc)
+    translationCtrl := controller.NewTranslationController(translationSvc)
     txCtrl := controller.NewTransactionController(txSvc)
-    uploadCtrl := controller.NewUploadController()
+    uploadCtrl := controller.NewUploadController(store, uploadSvc)
+    tableCtrl := controller.NewTableController(tableSvc)
+    selfOrderCtrl := controller.NewSelfOrderController(selfOrderSvc, tableSvc)
+    ingredientCtrl := controller.NewIngredientController(ingredientSvc)
+    tagCtrl := controller.NewTagController(tagSvc)
+    pricingCtrl := controller.NewPricingController(pricingSvc)
+    taxCtrl := controller.NewTaxController(taxSvc)
+    promotionCtrl := controller.NewPromotionController(promotionSvc)
+    paymentCtrl := controller.NewPaymentController(paymentSvc)
+    refundCtrl := controller.NewRefundController(refundSvc)
+    syncCtrl := controller.NewSyncController(txSvc, syncSvc)
+    billCtrl := controller.NewBillController(billSvc)
+    kitchenCtrl := controller.NewKitchenController(kitchenSvc)
+    receiptCtrl := controller.NewReceiptController(receiptSvc)
+
+    switch s := store.(type) {
+    case *storage.Local:
+        // serve uploaded files
+        r.Static("/uploads", s.Dir)
+    case *storage.S3:
+        // private buckets are reached through short-lived signed redirects
+        r.GET("/files/*key", uploadCtrl.Serve)
+    }
+
+    // public receipt pages (links shared with customers)
+    r.GET("/r/:token", receiptCtrl.Public)
+
+    // background jobs
+    uploadGCInterval := time.Duration(config.GetEnvInt("UPLOAD_GC_INTERVAL_MINUTES", 60)) * time.Minute
+    go cservice.RunEvery("upload-gc", uploadGCInterval, func() error {
+        report, err := uploadSvc.Cleanup(false)
+        if err == nil && report.Deleted > 0 {
+            log.Printf("upload-gc: deleted %d unattached uploads (%d bytes)", report.Deleted, report.FreedBytes)
+        }
+        return err
+    })
+
+    go cservice.RunEvery("idempotency-cleanup", time.Hour, func() error {
+        _, err := idempotencyRepo.DeleteExpired(time.Now())
+        return err
+    })
+
+    go cservice.RunEvery("tombstone-cleanup", 24*time.Hour, func() error {
+        _, err := syncSvc.PurgeTombstones()
+        return err
+    })
+
+    if paymentSvc.Online() {
+        go cservice.RunEvery("payment-expiry", time.Minute, func() error {
+            n, err := paymentSvc.ExpireOverdue()
+            if n > 0 {
+                log.Printf("payment-expiry: cancelled %d expired QRIS payments", n)
+            }
+            return err
+        })
+    }
+
+    // retries of mutating requests with an Idempotency-Key get the first response
+    idempotent := middleware.Idempotency(idempotencyRepo, config.IdempotencyRetention(), config.IdempotencyMaxBody())
 
     api := r.Group("/api")
     {
@@ -59,16 +164,44 @@ func SetupRouter() *gin.Engine {
     // public
     api.GET("/categories", catCtrl.List)
     api.GET("/menus", menuCtrl.List)
+    api.GET("/menus/lookup", menuCtrl.Lookup)
+    api.GET("/menus/prices", pricingCtrl.Current)
     api.GET("/menus/:id", menuCtrl.Get)
-    api.GET("/transactions", txCtrl.List)
-    api.GET("/transactions/:id", txCtrl.Get)
+    api.GET("/tags", tagCtrl.List)
+    api.GET("/allergens", tagCtrl.Allergens)
+
+        // customer self-ordering, authorized by the signed table token of the QR code
+        public := api.Group("/public")
+        public.Use(idempotent)
+        {
+            public.GET("/tables/:token", selfOrderCtrl.Table)
+            public.POST("/tables/:token/orders", selfOrderCtrl.Submit)
+            public.GET("/tables/:token/orders/:id", selfOrderCtrl.Status)
+            // payment gateway notifications (signed by the gateway)
+            public.POST("/payments/webhook", paymentCtrl.Webhook)
+        }
 
         // protected: need auth
         authRequired := api.Group("")
-        authRequired.Use(middleware.AuthRequired(userRepo))
+        authRequired.Use(middleware.AuthRequired(userRepo), idempotent)
         {
             authRequired.GET("/auth/me", authCtrl.Me)
+            authRequired.PUT("/auth/pin", authCtrl.SetPIN)
+            authRequired.GET("/auth/approvers", authCtrl.Approvers)
+            authRequired.GET("/transactions", txCtrl.List)
+            authRequired.GET("/transactions/:id", txCtrl.Get)
             authRequired.POST("/transactions", txCtrl.Create)
+            authRequired.POST("/transactions/quote", txCtrl.Quote)
+            authRequired.POST("/transactions/:id/cancel-payment", paymentCtrl.Cancel)
+            authRequired.POST("/transactions/:id/void", refundCtrl.Void)
+            authRequired.POST("/transactions/:id/refund", refundCtrl.Refund)
+            authRequired.GET("/transactions/:id/refunds", refundCtrl.List)
+            authRequired.GET("/transactions/:id/receipt", receiptCtrl.Print)
+            authRequired.GET("/transactions/:id/receipt-link", receiptCtrl.Link)
+            authRequired.GET("/payments/:id/qr", paymentCtrl.QRCode)
+            // offline devices
+            authRequired.POST("/sync/transactions", syncCtrl.Transactions)
+            authRequired.GET("/sync/catalog", syncCtrl.Catalog)
                 // notifications (SSE)
                 notifCtrl := controller.NewNotificationController()
                 authRequired.GET("/notifications/stream", notifCtrl.Stream)
@@ -78,19 +211,93 @@ func SetupRouter() *gin.Engine {
             authRequired.GET("/reports/aggregate", reportCtrl.Aggregate)
             authRequired.GET("/reports/daily/pdf", reportCtrl.ExportPDF)
             authRequired.GET("/reports/daily/excel", reportCtrl.ExportExcel)
-            // toggle availability for menu (admin could also use update)
-            authRequired.PATCH("/menus/:id/availability", menuCtrl.Update)
+            // staff mark menus sold out; every other menu change is admin only
+            authRequired.PATCH("/menus/:id/availability", menuCtrl.SetAvailability)
             authRequired.POST("/uploads", uploadCtrl.Upload)
+            authRequired.GET("/menus/labels", menuCtrl.Labels)
+            // tables and the self-order queue
+            authRequired.GET("/tables", tableCtrl.List)
+            authRequired.GET("/self-orders", selfOrderCtrl.List)
+            authRequired.GET("/self-orders/:id", selfOrderCtrl.Get)
+            authRequired.POST("/self-orders/:id/accept", selfOrderCtrl.Accept)
+            authRequired.POST("/self-orders/:id/reject", selfOrderCtrl.Reject)
+            // open bills of dine-in tables
+            authRequired.GET("/tables/status", billCtrl.Tables)
+            authRequired.GET("/bills", billCtrl.List)
+            authRequired.POST("/bills", billCtrl.Open)
+            authRequired.GET("/bills/:id", billCtrl.Get)
+            authRequired.POST("/bills/:id/items", billCtrl.AddItems)
+            authRequired.DELETE("/bills/:id/items/:item_id", billCtrl.RemoveItem)
+            authRequired.POST("/bills/:id/transfer", billCtrl.Transfer)
+            authRequired.POST("/bills/:id/merge", billCtrl.Merge)
+            authRequired.POST("/bills/:id/split", billCtrl.Split)
+            authRequired.POST("/bills/:id/settle", billCtrl.Settle)
+            authRequired.POST("/bills/:id/cancel", billCtrl.Cancel)
+            // kitchen display
+            authRequired.GET("/kitchen/stations", kitchenCtrl.ListStations)
+            authRequired.GET("/kitchen/stations/:id/stream", kitchenCtrl.Stream)
+            authRequired.GET("/kitchen/tickets", kitchenCtrl.Tickets)
+            authRequired.POST("/kitchen/tickets/:id/status", kitchenCtrl.Advance)
         }
 
         // admin-only routes
         admin := api.Group("")
-        admin.Use(middleware.AuthRequired(userRepo), middleware.AdminRequired())
+        admin.Use(middleware.AuthRequired(userRepo), middleware.AdminRequired(), idempotent)
         {
             admin.POST("/categories", catCtrl.Create)
             admin.POST("/menus", menuCtrl.Create)
             admin.PUT("/menus/:id", menuCtrl.Update)
             admin.DELETE("/menus/:id", menuCtrl.Delete)
+            admin.GET("/menus/export", menuCtrl.Export)
+            admin.POST("/menus/import", menuCtrl.Import)
+            // translations
+            admin.GET("/menus/:id/translations", translationCtrl.ListMenu)
+            admin.PUT("/menus/:id/translations/:locale", translationCtrl.SetMenu)
+            admin.DELETE("/menus/:id/translations/:locale", translationCtrl.DeleteMenu)
+            admin.GET("/categories/:id/translations", translationCtrl.ListCategory)
+            admin.PUT("/categories/:id/translations/:locale", translationCtrl.SetCategory)
+            admin.DELETE("/categories/:id/translations/:locale", translationCtrl.DeleteCategory)
+            admin.POST("/payments/:id/simulate", paymentCtrl.Simulate)
+            admin.GET("/uploads/orphans", uploadCtrl.Orphans)
+            admin.POST("/uploads/cleanup", uploadCtrl.Cleanup)
+            // tags
+            admin.POST("/tags", tagCtrl.Create)
+            admin.PUT("/tags/:id", tagCtrl.Update)
+            admin.DELETE("/tags/:id", tagCtrl.Delete)
+            // promotions and vouchers
+            admin.GET("/promotions", promotionCtrl.List)
+            admin.GET("/promotions/:id", promotionCtrl.Get)
+            admin.POST("/promotions", promotionCtrl.Create)
+            admin.PUT("/promotions/:id", promotionCtrl.Update)
+            admin.DELETE("/promotions/:id", promotionCtrl.Delete)
+            // taxes and service charges
+            admin.GET("/tax-rules", taxCtrl.List)
+            admin.POST("/tax-rules", taxCtrl.Create)
+            admin.PUT("/tax-rules/:id", taxCtrl.Update)
+            admin.DELETE("/tax-rules/:id", taxCtrl.Delete)
+            // price rules (happy hours, weekday discounts)
+            admin.GET("/price-rules", pricingCtrl.List)
+            admin.POST("/price-rules", pricingCtrl.Create)
+            admin.PUT("/price-rules/:id", pricingCtrl.Update)
+            admin.DELETE("/price-rules/:id", pricingCtrl.Delete)
+            // cost prices: ingredients and recipes
+            admin.GET("/ingredients", ingredientCtrl.List)
+            admin.POST("/ingredients", ingredientCtrl.Create)
+            admin.PUT("/ingredients/:id", ingredientCtrl.Update)
+            admin.DELETE("/ingredients/:id", ingredientCtrl.Delete)
+            admin.GET("/menus/:id/cost", ingredientCtrl.MenuCost)
+            admin.PUT("/menus/:id/recipe", ingredientCtrl.SetRecipe)
+            // tables
+            admin.POST("/tables", tableCtrl.Create)
+            admin.PUT("/tables/:id", tableCtrl.Update)
+            admin.DELETE("/tables/:id", tableCtrl.Delete)
+            admin.GET("/tables/:id/link", tableCtrl.Link)
+            admin.GET("/tables/:id/qr", tableCtrl.QRCode)
+            admin.POST("/tables/:id/rotate-token", tableCtrl.RotateToken)
+            // kitchen stations
+            admin.POST("/kitchen/stations", kitchenCtrl.CreateStation)
+            admin.PUT("/kitchen/stations/:id", kitchenCtrl.UpdateStation)
+            admin.DELETE("/kitchen/stations/:id", kitchenCtrl.DeleteStation)
         }
     }
 
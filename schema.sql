@@ -14,6 +14,7 @@ CREATE TABLE IF NOT EXISTS users (
   email VARCHAR(255) NOT NULL UNIQUE,
   password VARCHAR(255) NOT NULL,
   role VARCHAR(30) NOT NULL DEFAULT 'kasir',
+  pin VARCHAR(255) NULL, -- hashed approval PIN of admins/managers
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   PRIMARY KEY (id)
@@ -34,6 +35,9 @@ CREATE TABLE IF NOT EXISTS menus (
   name VARCHAR(200) NOT NULL,
   description TEXT,
   price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  cost_price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  cost_mode VARCHAR(10) NOT NULL DEFAULT 'manual',
+  sku VARCHAR(64) NULL UNIQUE,
   category_id BIGINT UNSIGNED NULL,
   image_url VARCHAR(512),
   is_available TINYINT(1) NOT NULL DEFAULT 1,
@@ -46,24 +50,202 @@ CREATE TABLE IF NOT EXISTS menus (
     ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 4b) Menu barcodes (packaged goods may have several)
+CREATE TABLE IF NOT EXISTS menu_barcodes (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  menu_id BIGINT UNSIGNED NOT NULL,
+  code VARCHAR(64) NOT NULL UNIQUE,
+  symbology VARCHAR(20) NOT NULL DEFAULT 'code128',
+  PRIMARY KEY (id),
+  INDEX idx_menu_barcodes_menu (menu_id),
+  CONSTRAINT fk_menu_barcodes_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 4c) Translations (the default locale lives on menus/categories themselves)
+CREATE TABLE IF NOT EXISTS menu_translations (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  menu_id BIGINT UNSIGNED NOT NULL,
+  locale VARCHAR(10) NOT NULL,
+  name VARCHAR(150) NOT NULL DEFAULT '',
+  description VARCHAR(500) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_menu_translation_locale (menu_id, locale),
+  CONSTRAINT fk_menu_translations_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS category_translations (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  category_id BIGINT UNSIGNED NOT NULL,
+  locale VARCHAR(10) NOT NULL,
+  name VARCHAR(100) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_category_translation_locale (category_id, locale),
+  CONSTRAINT fk_category_translations_category
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 4d) Ingredients and recipes (menu cost price derived from ingredients)
+CREATE TABLE IF NOT EXISTS ingredients (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(100) NOT NULL UNIQUE,
+  unit VARCHAR(20) NOT NULL DEFAULT '',
+  cost_per_unit DECIMAL(14,4) NOT NULL DEFAULT 0,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS menu_ingredients (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  menu_id BIGINT UNSIGNED NOT NULL,
+  ingredient_id BIGINT UNSIGNED NOT NULL,
+  quantity DECIMAL(12,4) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_menu_ingredients_menu (menu_id),
+  INDEX idx_menu_ingredients_ingredient (ingredient_id),
+  CONSTRAINT fk_menu_ingredients_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_menu_ingredients_ingredient
+    FOREIGN KEY (ingredient_id) REFERENCES ingredients(id)
+    ON DELETE RESTRICT
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 4e) Tags and allergens
+CREATE TABLE IF NOT EXISTS tags (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(50) NOT NULL UNIQUE,
+  slug VARCHAR(60) NOT NULL UNIQUE,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS menu_tags (
+  menu_id BIGINT UNSIGNED NOT NULL,
+  tag_id BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY (menu_id, tag_id),
+  INDEX idx_menu_tags_tag (tag_id),
+  CONSTRAINT fk_menu_tags_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_menu_tags_tag
+    FOREIGN KEY (tag_id) REFERENCES tags(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- allergen codes come from the fixed list in model/tag.go (gluten, peanut, milk, ...)
+CREATE TABLE IF NOT EXISTS menu_allergens (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  menu_id BIGINT UNSIGNED NOT NULL,
+  code VARCHAR(30) NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_menu_allergen (menu_id, code),
+  CONSTRAINT fk_menu_allergens_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 4f) Catalog version (single row, bumped on every menu/category change; used for ETags)
+CREATE TABLE IF NOT EXISTS catalog_states (
+  id BIGINT UNSIGNED NOT NULL,
+  version BIGINT UNSIGNED NOT NULL DEFAULT 1,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+INSERT IGNORE INTO catalog_states (id, version) VALUES (1, 1);
+
+-- 4g) Price rules (happy hours, weekday discounts)
+CREATE TABLE IF NOT EXISTS price_rules (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(100) NOT NULL,
+  menu_id BIGINT UNSIGNED NULL,
+  category_id BIGINT UNSIGNED NULL,
+  weekdays VARCHAR(20) NULL,           -- 0 (Sunday) .. 6, comma separated; empty = every day
+  start_time VARCHAR(5) NULL,          -- HH:MM
+  end_time VARCHAR(5) NULL,            -- HH:MM, before start_time = runs past midnight
+  valid_from DATETIME NULL,
+  valid_until DATETIME NULL,
+  kind ENUM('fixed','percent') NOT NULL DEFAULT 'percent',
+  value DECIMAL(12,2) NOT NULL DEFAULT 0,
+  priority INT NOT NULL DEFAULT 0,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_price_rules_menu (menu_id),
+  INDEX idx_price_rules_category (category_id),
+  CONSTRAINT fk_price_rules_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_price_rules_category
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 4h) Tombstones of deleted catalog records (for offline catalog sync)
+CREATE TABLE IF NOT EXISTS tombstones (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  entity VARCHAR(30) NOT NULL, -- menu
+  entity_id BIGINT UNSIGNED NOT NULL,
+  deleted_at DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  INDEX idx_tombstones_entity (entity, deleted_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- 5) Transactions
 CREATE TABLE IF NOT EXISTS transactions (
   id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  invoice_number VARCHAR(60) NULL, -- e.g. WRG-20261018-0042, see invoice_sequences
   total DECIMAL(14,2) NOT NULL DEFAULT 0,
   subtotal DECIMAL(14,2) NOT NULL DEFAULT 0,
   tax DECIMAL(14,2) NOT NULL DEFAULT 0,
+  service_charge DECIMAL(14,2) NOT NULL DEFAULT 0,
+  included_tax DECIMAL(14,2) NOT NULL DEFAULT 0, -- part of tax/service_charge already in subtotal
   discount DECIMAL(14,2) NOT NULL DEFAULT 0,
-  payment_method ENUM('tunai','qris') NOT NULL DEFAULT 'tunai',
+  rounding_adjustment DECIMAL(14,2) NOT NULL DEFAULT 0, -- cash rounding included in total
+  payment_method ENUM('tunai','qris','split') NOT NULL DEFAULT 'tunai',
   amount_paid DECIMAL(14,2) NOT NULL DEFAULT 0,
+  change_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
   cashier_id BIGINT UNSIGNED NULL,
+  client_uuid CHAR(36) NULL,  -- sales uploaded by offline devices
+  synced_at DATETIME NULL,
+  price_review TINYINT(1) NOT NULL DEFAULT 0, -- offline sale kept the device's prices
+  server_total DECIMAL(14,2) NULL,            -- what the server would have charged
+  price_review_note VARCHAR(500) NOT NULL DEFAULT '',
+  receipt_token VARCHAR(32) NULL, -- public receipt link /r/<token>, cleared on void
+  status VARCHAR(20) NOT NULL DEFAULT 'completed', -- completed, pending_payment, cancelled, (partially_)voided, (partially_)refunded
+  refunded_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
   PRIMARY KEY (id),
+  UNIQUE KEY uq_transactions_invoice_number (invoice_number),
+  UNIQUE KEY uq_transactions_client_uuid (client_uuid),
+  UNIQUE KEY uq_transactions_receipt_token (receipt_token),
   INDEX idx_transactions_cashier (cashier_id),
+  INDEX idx_transactions_status (status),
+  INDEX idx_transactions_price_review (price_review),
   CONSTRAINT fk_transactions_cashier
     FOREIGN KEY (cashier_id) REFERENCES users(id)
     ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 5a) Invoice number sequences (one row per prefix and period, locked while a sale is stored)
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+  scope VARCHAR(60) NOT NULL, -- e.g. WRG-20261018
+  last_number INT NOT NULL DEFAULT 0,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (scope)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- 6) Transaction items
 CREATE TABLE IF NOT EXISTS transaction_items (
   id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
@@ -71,6 +253,12 @@ CREATE TABLE IF NOT EXISTS transaction_items (
   menu_id BIGINT UNSIGNED NULL,
   quantity INT NOT NULL DEFAULT 1,
   price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  base_price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  price_rule_id BIGINT UNSIGNED NULL,
+  price_rule_name VARCHAR(100) NULL,
+  cost_price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  refunded_quantity INT NOT NULL DEFAULT 0,
+  note VARCHAR(255) NULL,             -- kitchen wish of the customer
   PRIMARY KEY (id),
   INDEX idx_titems_tx (transaction_id),
   INDEX idx_titems_menu (menu_id),
@@ -82,6 +270,356 @@ CREATE TABLE IF NOT EXISTS transaction_items (
     ON DELETE SET NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+-- 6a) Payments (one row per tender; split payments have several)
+CREATE TABLE IF NOT EXISTS payments (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  transaction_id BIGINT UNSIGNED NOT NULL,
+  method VARCHAR(20) NOT NULL,
+  amount DECIMAL(14,2) NOT NULL DEFAULT 0,   -- part of the total settled
+  tendered DECIMAL(14,2) NOT NULL DEFAULT 0, -- handed over (cash includes the change)
+  reference VARCHAR(100) NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'paid', -- paid, pending, expired, cancelled, failed
+  gateway VARCHAR(20) NULL,
+  order_id VARCHAR(64) NULL,                  -- charge id sent to the payment gateway
+  qr_string TEXT NULL,                        -- dynamic QRIS payload
+  expires_at DATETIME NULL,
+  paid_at DATETIME NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_payments_order (order_id),
+  INDEX idx_payments_tx (transaction_id),
+  INDEX idx_payments_method (method),
+  INDEX idx_payments_status (status),
+  CONSTRAINT fk_payments_tx
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6a1) Tax and service charge rules, and what each transaction was charged
+CREATE TABLE IF NOT EXISTS tax_rules (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(100) NOT NULL,
+  kind ENUM('tax','service') NOT NULL DEFAULT 'tax',
+  rate DECIMAL(5,2) NOT NULL,          -- percent
+  inclusive TINYINT(1) NOT NULL DEFAULT 0, -- already part of menu prices
+  compound TINYINT(1) NOT NULL DEFAULT 0,  -- charged on top of earlier exclusive rules
+  sequence INT NOT NULL DEFAULT 0,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS transaction_taxes (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  transaction_id BIGINT UNSIGNED NOT NULL,
+  tax_rule_id BIGINT UNSIGNED NULL,
+  name VARCHAR(100) NOT NULL,
+  kind VARCHAR(10) NOT NULL,
+  rate DECIMAL(5,2) NOT NULL,
+  inclusive TINYINT(1) NOT NULL DEFAULT 0,
+  base DECIMAL(14,2) NOT NULL DEFAULT 0,
+  amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_transaction_taxes_tx (transaction_id),
+  CONSTRAINT fk_transaction_taxes_tx
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6a2) Promotions and vouchers (vouchers have a code, automatic promotions do not)
+CREATE TABLE IF NOT EXISTS promotions (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(100) NOT NULL,
+  code VARCHAR(50) NULL,
+  kind ENUM('percent','fixed','bxgy') NOT NULL,
+  value DECIMAL(12,2) NOT NULL DEFAULT 0,
+  max_discount DECIMAL(12,2) NOT NULL DEFAULT 0, -- cap for percent promotions, 0 = none
+  buy_quantity INT NOT NULL DEFAULT 0,
+  get_quantity INT NOT NULL DEFAULT 0,
+  min_spend DECIMAL(12,2) NOT NULL DEFAULT 0,
+  category_id BIGINT UNSIGNED NULL,
+  stackable TINYINT(1) NOT NULL DEFAULT 0,
+  usage_limit INT NOT NULL DEFAULT 0,            -- 0 = unlimited
+  usage_count INT NOT NULL DEFAULT 0,
+  valid_from DATETIME NULL,
+  valid_until DATETIME NULL,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_promotions_code (code),
+  INDEX idx_promotions_category (category_id),
+  CONSTRAINT fk_promotions_category
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS transaction_promotions (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  transaction_id BIGINT UNSIGNED NOT NULL,
+  promotion_id BIGINT UNSIGNED NULL,
+  code VARCHAR(50) NULL,
+  name VARCHAR(100) NOT NULL,
+  kind VARCHAR(10) NOT NULL,
+  amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_transaction_promotions_tx (transaction_id),
+  INDEX idx_transaction_promotions_promo (promotion_id),
+  CONSTRAINT fk_transaction_promotions_tx
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_transaction_promotions_promo
+    FOREIGN KEY (promotion_id) REFERENCES promotions(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6a3) Voids and refunds (approved by a manager)
+CREATE TABLE IF NOT EXISTS refunds (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  transaction_id BIGINT UNSIGNED NOT NULL,
+  kind ENUM('void','refund') NOT NULL,
+  reason VARCHAR(255) NOT NULL,
+  amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+  method VARCHAR(20) NOT NULL,
+  requested_by BIGINT UNSIGNED NULL,
+  approved_by BIGINT UNSIGNED NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_refunds_tx (transaction_id),
+  CONSTRAINT fk_refunds_tx
+    FOREIGN KEY (transaction_id) REFERENCES transactions(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_refunds_requested_by
+    FOREIGN KEY (requested_by) REFERENCES users(id)
+    ON DELETE SET NULL,
+  CONSTRAINT fk_refunds_approved_by
+    FOREIGN KEY (approved_by) REFERENCES users(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS refund_items (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  refund_id BIGINT UNSIGNED NOT NULL,
+  transaction_item_id BIGINT UNSIGNED NOT NULL,
+  menu_id BIGINT UNSIGNED NULL,
+  quantity INT NOT NULL DEFAULT 1,
+  amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_refund_items_refund (refund_id),
+  INDEX idx_refund_items_titem (transaction_item_id),
+  CONSTRAINT fk_refund_items_refund
+    FOREIGN KEY (refund_id) REFERENCES refunds(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_refund_items_titem
+    FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6a4) Idempotency keys (responses replayed to retried requests, purged after retention)
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  scope VARCHAR(50) NOT NULL,         -- user:<id> or public
+  `key` VARCHAR(100) NOT NULL,
+  request_hash CHAR(64) NOT NULL,     -- SHA-256 of method, URL and body
+  completed TINYINT(1) NOT NULL DEFAULT 0,
+  status_code INT NOT NULL DEFAULT 0,
+  content_type VARCHAR(100) NULL,
+  response MEDIUMBLOB NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  locked_until DATETIME NULL,         -- renewed while the first request runs
+  expires_at DATETIME NOT NULL,
+  PRIMARY KEY (id),
+  UNIQUE KEY uq_idempotency_scope_key (scope, `key`),
+  INDEX idx_idempotency_keys_expires (expires_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6b) Uploads (garbage collected when not attached to a menu)
+CREATE TABLE IF NOT EXISTS uploads (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  owner_id BIGINT UNSIGNED NULL,
+  entity_type VARCHAR(50) NOT NULL DEFAULT '',
+  entity_id BIGINT UNSIGNED NULL,
+  attached_at DATETIME NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_uploads_entity (entity_type, entity_id),
+  INDEX idx_uploads_attached_at (attached_at),
+  INDEX idx_uploads_created_at (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS upload_files (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  upload_id BIGINT UNSIGNED NOT NULL,
+  rendition VARCHAR(30) NOT NULL DEFAULT '',
+  `key` VARCHAR(255) NOT NULL UNIQUE,
+  url VARCHAR(512) NOT NULL,
+  size BIGINT NOT NULL DEFAULT 0,
+  PRIMARY KEY (id),
+  INDEX idx_upload_files_upload (upload_id),
+  INDEX idx_upload_files_url (url),
+  CONSTRAINT fk_upload_files_upload
+    FOREIGN KEY (upload_id) REFERENCES uploads(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6c) Dining tables and customer self-orders (QR code ordering)
+CREATE TABLE IF NOT EXISTS dining_tables (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(50) NOT NULL UNIQUE,
+  seats INT NOT NULL DEFAULT 0,
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  token_version INT UNSIGNED NOT NULL DEFAULT 1,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS self_orders (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  table_id BIGINT UNSIGNED NOT NULL,
+  customer_name VARCHAR(100) NOT NULL DEFAULT '',
+  note VARCHAR(500) NOT NULL DEFAULT '',
+  status VARCHAR(20) NOT NULL DEFAULT 'pending',
+  total DECIMAL(14,2) NOT NULL DEFAULT 0,
+  transaction_id BIGINT UNSIGNED NULL,
+  handled_by BIGINT UNSIGNED NULL,
+  reject_reason VARCHAR(255) NOT NULL DEFAULT '',
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_self_orders_table (table_id),
+  INDEX idx_self_orders_status (status),
+  CONSTRAINT fk_self_orders_table
+    FOREIGN KEY (table_id) REFERENCES dining_tables(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS self_order_items (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  self_order_id BIGINT UNSIGNED NOT NULL,
+  menu_id BIGINT UNSIGNED NULL,
+  quantity INT NOT NULL DEFAULT 1,
+  price DECIMAL(12,2) NOT NULL DEFAULT 0,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (id),
+  INDEX idx_self_order_items_order (self_order_id),
+  CONSTRAINT fk_self_order_items_order
+    FOREIGN KEY (self_order_id) REFERENCES self_orders(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_self_order_items_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6d) Open bills of dine-in tables (settled into a transaction)
+CREATE TABLE IF NOT EXISTS bills (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  table_id BIGINT UNSIGNED NULL,
+  name VARCHAR(100) NOT NULL DEFAULT '',
+  guests INT NOT NULL DEFAULT 0,
+  note VARCHAR(500) NOT NULL DEFAULT '',
+  status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, settling, settled, merged, cancelled
+  total DECIMAL(14,2) NOT NULL DEFAULT 0,     -- estimate until settled
+  opened_by BIGINT UNSIGNED NULL,
+  settled_by BIGINT UNSIGNED NULL,
+  transaction_id BIGINT UNSIGNED NULL,
+  merged_into BIGINT UNSIGNED NULL,
+  settled_at DATETIME NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_bills_table (table_id),
+  INDEX idx_bills_status (status),
+  INDEX idx_bills_transaction (transaction_id),
+  CONSTRAINT fk_bills_table
+    FOREIGN KEY (table_id) REFERENCES dining_tables(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS bill_items (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  bill_id BIGINT UNSIGNED NOT NULL,
+  menu_id BIGINT UNSIGNED NULL,
+  quantity INT NOT NULL DEFAULT 1,
+  seat INT NOT NULL DEFAULT 0, -- guest the line belongs to, 0 = shared
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  added_by BIGINT UNSIGNED NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  INDEX idx_bill_items_bill (bill_id),
+  CONSTRAINT fk_bill_items_bill
+    FOREIGN KEY (bill_id) REFERENCES bills(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_bill_items_menu
+    FOREIGN KEY (menu_id) REFERENCES menus(id)
+    ON DELETE SET NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 6e) Kitchen stations and tickets (kitchen display; items routed by category)
+CREATE TABLE IF NOT EXISTS kitchen_stations (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  name VARCHAR(50) NOT NULL,
+  is_default TINYINT(1) NOT NULL DEFAULT 0, -- gets items no other station claims
+  is_active TINYINT(1) NOT NULL DEFAULT 1,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (id),
+  UNIQUE KEY idx_kitchen_stations_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS kitchen_station_categories (
+  kitchen_station_id BIGINT UNSIGNED NOT NULL,
+  category_id BIGINT UNSIGNED NOT NULL,
+  PRIMARY KEY (kitchen_station_id, category_id),
+  CONSTRAINT fk_ksc_station
+    FOREIGN KEY (kitchen_station_id) REFERENCES kitchen_stations(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_ksc_category
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS kitchen_tickets (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  station_id BIGINT UNSIGNED NOT NULL,
+  station_name VARCHAR(50) NOT NULL DEFAULT '',
+  transaction_id BIGINT UNSIGNED NULL,
+  bill_id BIGINT UNSIGNED NULL,
+  label VARCHAR(100) NOT NULL DEFAULT '',     -- table or receipt number
+  status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, preparing, ready, served, cancelled
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  started_at DATETIME NULL,
+  ready_at DATETIME NULL,
+  served_at DATETIME NULL,
+  cancelled_at DATETIME NULL,
+  PRIMARY KEY (id),
+  INDEX idx_kitchen_tickets_station (station_id),
+  INDEX idx_kitchen_tickets_transaction (transaction_id),
+  INDEX idx_kitchen_tickets_bill (bill_id),
+  INDEX idx_kitchen_tickets_status (status),
+  INDEX idx_kitchen_tickets_created (created_at)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS kitchen_ticket_items (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  ticket_id BIGINT UNSIGNED NOT NULL,
+  transaction_item_id BIGINT UNSIGNED NULL,
+  bill_item_id BIGINT UNSIGNED NULL,
+  menu_id BIGINT UNSIGNED NULL,
+  name VARCHAR(150) NOT NULL,
+  quantity INT NOT NULL DEFAULT 1,
+  seat INT NOT NULL DEFAULT 0,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (id),
+  INDEX idx_kitchen_ticket_items_ticket (ticket_id),
+  INDEX idx_kitchen_ticket_items_transaction_item (transaction_item_id),
+  INDEX idx_kitchen_ticket_items_bill_item (bill_item_id),
+  CONSTRAINT fk_kitchen_ticket_items_ticket
+    FOREIGN KEY (ticket_id) REFERENCES kitchen_tickets(id)
+    ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 -- 7) Sample inserts (AMAN JIKA DIJALANKAN BERULANG)
 INSERT INTO users (name, email, password, role)
 VALUES ('Admin Warung', 'admin@warung.com', '$2a$10$Z1q7...', 'admin')
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/IndalAwalaikal/warung-pos/backend/config"
@@ -14,14 +17,32 @@ type AuthService interface {
     Register(u *model.User) error
     // Login returns token and the authenticated user (or nil if invalid credentials)
     Login(email, password string) (string, *model.User, error)
+    // SetPIN stores the approval PIN of an admin or manager
+    SetPIN(u *model.User, pin string) error
+    // Approvers lists the admins and managers that can approve by PIN
+    Approvers() ([]model.User, error)
+    // Approve identifies the manager approving a sensitive action of requestedBy, either by
+    // their own login token (second token) or by their id and PIN. Wrong PINs are counted
+    // per cashier and per manager; too many lock approvals by PIN for a while. Nobody
+    // approves their own request.
+    Approve(token string, approverID uint, pin string, requestedBy *uint) (*model.User, error)
 }
 
 type authService struct{
     userRepo repository.UserRepository
+
+    mu       sync.Mutex
+    failures map[string]*pinFailures
+}
+
+// pinFailures counts the wrong PINs of a cashier or for a manager
+type pinFailures struct {
+    count       int
+    lockedUntil time.Time
 }
 
 func NewAuthService(ur repository.UserRepository) AuthService {
-    return &authService{userRepo: ur}
+    return &authService{userRepo: ur, failures: map[string]*pinFailures{}}
 }
 
 func (s *authService) Register(u *model.User) error {
@@ -30,9 +51,8 @@ func (s *authService) Register(u *model.User) error {
         return err
     }
     u.Password = hashed
-    if u.Role == "" {
-        u.Role = "user"
-    }
+    // self-registered accounts are cashiers; managers and admins are appointed
+    u.Role = "user"
     return s.userRepo.Create(u)
 }
 
@@ -63,3 +83,112 @@ func (s *authService) Login(email, password string) (string, *model.User, error)
     }
     return signed, user, nil
 }
+
+func (s *authService) SetPIN(u *model.User, pin string) error {
+    if !u.CanApprove() {
+        return ErrForbidden
+    }
+    if len(pin) < 4 || len(pin) > 8 || strings.Trim(pin, "0123456789") != "" {
+        return validationErrorf("pin must be 4 to 8 digits")
+    }
+    hashed, err := utils.HashPassword(pin)
+    if err != nil {
+        return err
+    }
+    return s.userRepo.UpdatePIN(u.ID, hashed)
+}
+
+func (s *authService) Approvers() ([]model.User, error) {
+    return s.userRepo.ListApprovers()
+}
+
+func (s *authService) Approve(token string, approverID uint, pin string, requestedBy *uint) (*model.User, error) {
+    switch {
+    case token != "":
+        t, err := jwt.ParseWithClaims(token, &config.Claims{}, func(t *jwt.Token) (interface{}, error) {
+            return config.JwtSecret(), nil
+        })
+        if err != nil || !t.Valid {
+            return nil, validationErrorf("invalid approval token")
+        }
+        u, err := s.userRepo.FindByID(t.Claims.(*config.Claims).UserID)
+        if err != nil {
+            return nil, err
+        }
+        if u == nil || !u.CanApprove() {
+            return nil, ErrForbidden
+        }
+        if requestedBy != nil && u.ID == *requestedBy {
+            return nil, errSelfApproval
+        }
+        return u, nil
+    case pin != "":
+        if approverID == 0 {
+            return nil, validationErrorf("manager_id is required with manager_pin")
+        }
+        if requestedBy != nil && approverID == *requestedBy {
+            return nil, errSelfApproval
+        }
+        keys := []string{fmt.Sprintf("approver:%d", approverID)}
+        if requestedBy != nil {
+            keys = append(keys, fmt.Sprintf("cashier:%d", *requestedBy))
+        }
+        if until := s.lockedUntil(keys); !until.IsZero() {
+            return nil, fmt.Errorf("%w, try again after %s", ErrTooManyAttempts, until.Format("15:04"))
+        }
+        u, err := s.userRepo.FindByID(approverID)
+        if err != nil {
+            return nil, err
+        }
+        if u == nil || !u.CanApprove() || u.PIN == "" || !utils.CheckPassword(u.PIN, pin) {
+            s.fail(keys)
+            return nil, ErrForbidden
+        }
+        s.reset(keys)
+        return u, nil
+    }
+    return nil, validationErrorf("manager approval required (X-Approval-Token header or manager_id and manager_pin)")
+}
+
+// errSelfApproval refuses a manager approving their own void or refund
+var errSelfApproval = fmt.Errorf("%w: another manager has to approve your own request", ErrForbidden)
+
+// lockedUntil returns the end of the latest lockout of keys (zero when none is locked)
+func (s *authService) lockedUntil(keys []string) time.Time {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    var until time.Time
+    for _, k := range keys {
+        if f, ok := s.failures[k]; ok && f.lockedUntil.After(time.Now()) && f.lockedUntil.After(until) {
+            until = f.lockedUntil
+        }
+    }
+    return until
+}
+
+// fail counts a wrong PIN for keys and locks those that reached the limit
+func (s *authService) fail(keys []string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, k := range keys {
+        f, ok := s.failures[k]
+        if !ok {
+            f = &pinFailures{}
+            s.failures[k] = f
+        }
+        f.count++
+        if f.count >= config.PINMaxAttempts() {
+            f.count = 0
+            f.lockedUntil = time.Now().Add(config.PINLockout())
+        }
+    }
+}
+
+// reset forgets the wrong PINs of keys after a correct one
+func (s *authService) reset(keys []string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, k := range keys {
+        delete(s.failures, k)
+    }
+}
@@ -0,0 +1,647 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
+)
+
+type BillService interface {
+    // List returns bills with the given status (all when empty)
+    List(status string) ([]model.Bill, error)
+    GetByID(id uint) (*model.Bill, error)
+    // Open starts a bill, on a table when TableID is set
+    Open(req *dto.BillRequest, userID *uint) (*model.Bill, error)
+    // AddItems adds a round of items to an open bill and sends it to the kitchen
+    AddItems(id uint, items []dto.BillItemDTO, userID *uint) (*model.Bill, error)
+    // RemoveItem takes quantity units off a line (the whole line when quantity is 0) and off
+    // its kitchen ticket
+    RemoveItem(id, itemID uint, quantity int) (*model.Bill, error)
+    // Transfer moves an open bill to another, free table
+    Transfer(id, tableID uint) (*model.Bill, error)
+    // Merge moves every item of the open bill otherID into bill id; otherID becomes merged
+    Merge(id, otherID uint) (*model.Bill, error)
+    // Split moves lines (or parts of them) and the lines of whole seats into a new bill
+    // on the same table and returns the new bill
+    Split(id uint, req *dto.BillSplitRequest, userID *uint) (*model.Bill, error)
+    // Settle prices the bill like a checkout, stores the transaction and closes the bill; a
+    // bill waiting for its QRIS payment stays settling until the payment service settles or
+    // reopens it. When the payment cannot be started the bill is open again and the
+    // cancelled transaction is returned with ErrPaymentNotStarted.
+    Settle(id uint, req *dto.BillSettleRequest, cashierID *uint) (*model.Transaction, error)
+    // Cancel closes an empty bill
+    Cancel(id uint) error
+    // Tables returns every table with its open and settling bills
+    Tables() ([]TableStatus, error)
+}
+
+// TableStatus is a table with its open and settling bills, as shown on the floor plan
+type TableStatus struct {
+    TableID  uint         `json:"table_id"`
+    Name     string       `json:"name"`
+    Seats    int          `json:"seats"`
+    IsActive bool         `json:"is_active"`
+    Occupied bool         `json:"occupied"`
+    Bills    []model.Bill `json:"bills"`
+}
+
+type billService struct{
+    repo     repository.BillRepository
+    menuRepo repository.MenuRepository
+    tables   TableService
+    txSvc    TransactionService
+    kitchen  KitchenService
+}
+
+func NewBillService(r repository.BillRepository, menuRepo repository.MenuRepository, tables TableService, txSvc TransactionService, kitchen KitchenService) BillService {
+    return &billService{repo: r, menuRepo: menuRepo, tables: tables, txSvc: txSvc, kitchen: kitchen}
+}
+
+func (s *billService) List(status string) ([]model.Bill, error) {
+    return s.repo.List(status)
+}
+
+func (s *billService) GetByID(id uint) (*model.Bill, error) {
+    return s.repo.GetByID(id)
+}
+
+func (s *billService) Open(req *dto.BillRequest, userID *uint) (*model.Bill, error) {
+    if req.Guests < 0 {
+        return nil, validationErrorf("guests must not be negative")
+    }
+    b := &model.Bill{
+        Name:     truncate(strings.TrimSpace(req.Name), 100),
+        Guests:   req.Guests,
+        Note:     truncate(strings.TrimSpace(req.Note), 500),
+        Status:   model.BillOpen,
+        OpenedBy: userID,
+    }
+    if req.TableID != nil {
+        t, err := s.activeTable(*req.TableID)
+        if err != nil {
+            return nil, err
+        }
+        b.TableID = &t.ID
+        if b.Name == "" {
+            b.Name = t.Name
+        }
+    }
+    items, err := s.items(req.Items, userID)
+    if err != nil {
+        return nil, err
+    }
+    var tickets []model.KitchenTicket
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        if err := bills.Create(b); err != nil {
+            return err
+        }
+        var err error
+        if len(items) > 0 {
+            for i := range items {
+                items[i].BillID = b.ID
+            }
+            if err := bills.AddItems(items); err != nil {
+                return err
+            }
+            if tickets, err = s.kitchen.TicketsForBillTx(tx, b, items); err != nil {
+                return err
+            }
+        }
+        b, err = s.refresh(bills, b.ID)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(b)
+    s.kitchen.Announce(tickets)
+    return b, nil
+}
+
+func (s *billService) AddItems(id uint, reqItems []dto.BillItemDTO, userID *uint) (*model.Bill, error) {
+    items, err := s.items(reqItems, userID)
+    if err != nil {
+        return nil, err
+    }
+    if len(items) == 0 {
+        return nil, validationErrorf("no items to add")
+    }
+    var b *model.Bill
+    var tickets []model.KitchenTicket
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        cur, err := lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+        for i := range items {
+            items[i].BillID = id
+        }
+        if err := bills.AddItems(items); err != nil {
+            return err
+        }
+        if tickets, err = s.kitchen.TicketsForBillTx(tx, cur, items); err != nil {
+            return err
+        }
+        b, err = s.refresh(bills, id)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(b)
+    s.kitchen.Announce(tickets)
+    return b, nil
+}
+
+func (s *billService) RemoveItem(id, itemID uint, quantity int) (*model.Bill, error) {
+    if quantity < 0 {
+        return nil, validationErrorf("quantity must not be negative")
+    }
+    var b *model.Bill
+    var tickets []model.KitchenTicket
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        cur, err := lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+        line := findBillItem(cur, itemID)
+        if line == nil {
+            return ErrNotFound
+        }
+        if quantity > line.Quantity {
+            return validationErrorf("the line has only %d", line.Quantity)
+        }
+        if quantity == 0 || quantity == line.Quantity {
+            quantity = line.Quantity
+            err = bills.DeleteItem(itemID)
+        } else {
+            err = bills.UpdateItemQuantity(itemID, line.Quantity-quantity)
+        }
+        if err != nil {
+            return err
+        }
+        if tickets, err = s.kitchen.WithdrawBillItemTx(tx, itemID, quantity); err != nil {
+            return err
+        }
+        b, err = s.refresh(bills, id)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(b)
+    s.kitchen.Announce(tickets)
+    return b, nil
+}
+
+func (s *billService) Transfer(id, tableID uint) (*model.Bill, error) {
+    t, err := s.activeTable(tableID)
+    if err != nil {
+        return nil, err
+    }
+    var b *model.Bill
+    var from *uint
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        cur, err := lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+        if cur.TableID != nil && *cur.TableID == tableID {
+            return validationErrorf("the bill is already on table %s", t.Name)
+        }
+        occupied, err := bills.ListActiveByTable(tableID)
+        if err != nil {
+            return err
+        }
+        if len(occupied) > 0 {
+            return conflictErrorf("table %s is occupied, merge the bills instead", t.Name)
+        }
+        from = cur.TableID
+        updates := map[string]interface{}{"table_id": tableID}
+        // a bill named after its table follows the table
+        if cur.Table != nil && cur.Name == cur.Table.Name {
+            updates["name"] = t.Name
+        }
+        if err := bills.Update(id, updates); err != nil {
+            return err
+        }
+        b, err = bills.GetByID(id)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(b, from)
+    return b, nil
+}
+
+func (s *billService) Merge(id, otherID uint) (*model.Bill, error) {
+    if id == otherID {
+        return nil, validationErrorf("a bill cannot be merged with itself")
+    }
+    var b, other *model.Bill
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        // lock in id order so two opposite merges cannot deadlock
+        first, second := id, otherID
+        if first > second {
+            first, second = second, first
+        }
+        locked := map[uint]*model.Bill{}
+        for _, bid := range []uint{first, second} {
+            cur, err := lockOpenBill(bills, bid)
+            if err != nil {
+                return err
+            }
+            locked[bid] = cur
+        }
+        other = locked[otherID]
+        var ids []uint
+        for _, it := range other.Items {
+            ids = append(ids, it.ID)
+        }
+        if err := bills.MoveItems(ids, id); err != nil {
+            return err
+        }
+        ok, err := bills.Transition(otherID, model.BillOpen, map[string]interface{}{
+            "status":      model.BillMerged,
+            "merged_into": id,
+            "total":       0,
+        })
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("bill %d was changed in the meantime", otherID)
+        }
+        other.Status = model.BillMerged
+        other.MergedInto = &id
+        other.Items = nil
+        if err := bills.Update(id, map[string]interface{}{"guests": locked[id].Guests + other.Guests}); err != nil {
+            return err
+        }
+        b, err = s.refresh(bills, id)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(other)
+    s.notify(b)
+    return b, nil
+}
+
+func (s *billService) Split(id uint, req *dto.BillSplitRequest, userID *uint) (*model.Bill, error) {
+    if len(req.Items) == 0 && len(req.Seats) == 0 {
+        return nil, validationErrorf("choose the items or seats to split off")
+    }
+    var b, nb *model.Bill
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        cur, err := lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+
+        // units to move per line
+        move := map[uint]int{}
+        seats := map[int]bool{}
+        for _, seat := range req.Seats {
+            seats[seat] = true
+        }
+        for _, it := range cur.Items {
+            if seats[it.Seat] {
+                move[it.ID] = it.Quantity
+            }
+        }
+        for _, l := range req.Items {
+            line := findBillItem(cur, l.BillItemID)
+            if line == nil {
+                return validationErrorf("item %d is not on this bill", l.BillItemID)
+            }
+            qty := l.Quantity
+            if qty == 0 {
+                qty = line.Quantity
+            }
+            if qty < 0 || qty > line.Quantity || (move[line.ID] > 0 && move[line.ID] != qty) {
+                return validationErrorf("item %d: quantity must be between 1 and %d", line.ID, line.Quantity)
+            }
+            move[line.ID] = qty
+        }
+        if len(move) == 0 {
+            return validationErrorf("the chosen seats have no items")
+        }
+        left := 0
+        for _, it := range cur.Items {
+            left += it.Quantity - move[it.ID]
+        }
+        if left == 0 {
+            return validationErrorf("nothing would be left on the bill, transfer it instead")
+        }
+
+        name := truncate(strings.TrimSpace(req.Name), 100)
+        if name == "" {
+            name = truncate(cur.Name+" (split)", 100)
+        }
+        nb = &model.Bill{TableID: cur.TableID, Name: name, Status: model.BillOpen, OpenedBy: userID}
+        if err := bills.Create(nb); err != nil {
+            return err
+        }
+        var whole []uint
+        var parts []model.BillItem
+        for _, it := range cur.Items {
+            qty := move[it.ID]
+            switch {
+            case qty == 0:
+                continue
+            case qty == it.Quantity:
+                whole = append(whole, it.ID)
+            default:
+                if err := bills.UpdateItemQuantity(it.ID, it.Quantity-qty); err != nil {
+                    return err
+                }
+                parts = append(parts, model.BillItem{BillID: nb.ID, MenuID: it.MenuID, Quantity: qty, Seat: it.Seat, Note: it.Note, AddedBy: it.AddedBy})
+            }
+        }
+        if err := bills.MoveItems(whole, nb.ID); err != nil {
+            return err
+        }
+        if len(parts) > 0 {
+            if err := bills.AddItems(parts); err != nil {
+                return err
+            }
+        }
+        if b, err = s.refresh(bills, id); err != nil {
+            return err
+        }
+        nb, err = s.refresh(bills, nb.ID)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    s.notify(b)
+    s.notify(nb)
+    return nb, nil
+}
+
+func (s *billService) Settle(id uint, req *dto.BillSettleRequest, cashierID *uint) (*model.Transaction, error) {
+    var b *model.Bill
+    var t *model.Transaction
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        var err error
+        b, err = lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+        if len(b.Items) == 0 {
+            return validationErrorf("the bill has no items")
+        }
+        checkout := dto.TransactionCreateRequest{
+            PaymentMethod: req.PaymentMethod,
+            AmountPaid:    req.AmountPaid,
+            Discount:      req.Discount,
+            VoucherCodes:  req.VoucherCodes,
+            Payments:      req.Payments,
+            Total:         req.Total,
+        }
+        for _, it := range b.Items {
+            if it.MenuID == nil {
+                return validationErrorf("a menu on this bill was deleted, remove the line first")
+            }
+            checkout.Items = append(checkout.Items, dto.TransactionItemDTO{MenuID: *it.MenuID, Quantity: it.Quantity, Note: it.Note})
+        }
+        t, err = s.txSvc.CheckoutTx(tx, &checkout, cashierID)
+        if err != nil {
+            return err
+        }
+        updates := map[string]interface{}{
+            "status":         model.BillSettled,
+            "transaction_id": t.ID,
+            "settled_by":     cashierID,
+            "total":          t.Total,
+        }
+        // the table stays occupied until the QRIS payment is confirmed
+        if t.Status == model.TransactionPendingPayment {
+            updates["status"] = model.BillSettling
+        } else {
+            now := time.Now()
+            updates["settled_at"] = now
+            b.SettledAt = &now
+        }
+        ok, err := bills.Transition(id, model.BillOpen, updates)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("bill %d was settled by someone else", id)
+        }
+        b.Status = updates["status"].(string)
+        b.TransactionID = &t.ID
+        b.SettledBy = cashierID
+        b.Total = t.Total
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := s.txSvc.StartPayment(t); err != nil {
+        return t, err
+    }
+    s.txSvc.Notify(t)
+    s.notify(b)
+    return t, nil
+}
+
+func (s *billService) Cancel(id uint) error {
+    var b *model.Bill
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        bills := s.repo.WithTx(tx)
+        var err error
+        b, err = lockOpenBill(bills, id)
+        if err != nil {
+            return err
+        }
+        if len(b.Items) > 0 {
+            return conflictErrorf("remove the items before cancelling the bill")
+        }
+        ok, err := bills.Transition(id, model.BillOpen, map[string]interface{}{"status": model.BillCancelled})
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("bill %d is no longer open", id)
+        }
+        b.Status = model.BillCancelled
+        return nil
+    })
+    if err != nil {
+        return err
+    }
+    s.notify(b)
+    return nil
+}
+
+func (s *billService) Tables() ([]TableStatus, error) {
+    tables, err := s.tables.List()
+    if err != nil {
+        return nil, err
+    }
+    open, err := s.repo.ListActive()
+    if err != nil {
+        return nil, err
+    }
+    byTable := map[uint][]model.Bill{}
+    for _, b := range open {
+        if b.TableID != nil {
+            b.Table = nil
+            byTable[*b.TableID] = append(byTable[*b.TableID], b)
+        }
+    }
+    out := make([]TableStatus, 0, len(tables))
+    for _, t := range tables {
+        bills := byTable[t.ID]
+        if bills == nil {
+            bills = []model.Bill{}
+        }
+        out = append(out, TableStatus{TableID: t.ID, Name: t.Name, Seats: t.Seats, IsActive: t.IsActive, Occupied: len(bills) > 0, Bills: bills})
+    }
+    return out, nil
+}
+
+// items validates requested lines against the menus
+func (s *billService) items(req []dto.BillItemDTO, userID *uint) ([]model.BillItem, error) {
+    var out []model.BillItem
+    for _, it := range req {
+        if it.Quantity <= 0 || it.Quantity > maxItemQuantity {
+            return nil, validationErrorf("quantity must be between 1 and %d", maxItemQuantity)
+        }
+        if it.Seat < 0 {
+            return nil, validationErrorf("seat must not be negative")
+        }
+        m, err := s.menuRepo.GetByID(it.MenuID)
+        if err != nil {
+            return nil, err
+        }
+        if m == nil {
+            return nil, validationErrorf("menu id %d not found", it.MenuID)
+        }
+        mid := m.ID
+        out = append(out, model.BillItem{MenuID: &mid, Quantity: it.Quantity, Seat: it.Seat, Note: truncate(strings.TrimSpace(it.Note), 255), AddedBy: userID})
+    }
+    return out, nil
+}
+
+func (s *billService) activeTable(id uint) (*model.DiningTable, error) {
+    t, err := s.tables.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil || !t.IsActive {
+        return nil, validationErrorf("table %d not found", id)
+    }
+    return t, nil
+}
+
+// refresh reloads a bill and stores its estimated total at the current prices
+func (s *billService) refresh(bills repository.BillRepository, id uint) (*model.Bill, error) {
+    b, err := bills.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    total := 0.0
+    if len(b.Items) > 0 {
+        req := &dto.TransactionCreateRequest{}
+        for _, it := range b.Items {
+            if it.MenuID != nil {
+                req.Items = append(req.Items, dto.TransactionItemDTO{MenuID: *it.MenuID, Quantity: it.Quantity})
+            }
+        }
+        if q, err := s.txSvc.Quote(req); err == nil {
+            total = q.Total
+        }
+    }
+    if total != b.Total {
+        if err := bills.Update(id, map[string]interface{}{"total": total}); err != nil {
+            return nil, err
+        }
+        b.Total = total
+    }
+    return b, nil
+}
+
+// lockOpenBill locks a bill for the rest of the database transaction and checks it is open
+func lockOpenBill(bills repository.BillRepository, id uint) (*model.Bill, error) {
+    b, err := bills.Lock(id)
+    if err != nil {
+        return nil, err
+    }
+    if b == nil {
+        return nil, ErrNotFound
+    }
+    if b.Status != model.BillOpen {
+        return nil, conflictErrorf("bill %d is %s", id, b.Status)
+    }
+    return b, nil
+}
+
+func findBillItem(b *model.Bill, itemID uint) *model.BillItem {
+    for i := range b.Items {
+        if b.Items[i].ID == itemID {
+            return &b.Items[i]
+        }
+    }
+    return nil
+}
+
+func (s *billService) notify(b *model.Bill, previousTable ...*uint) {
+    notifyBill(s.repo, b, previousTable...)
+}
+
+// notifyBill pushes the changed bill and the occupancy of its table (and of the table it
+// left) to SSE clients
+func notifyBill(bills repository.BillRepository, b *model.Bill, previousTable ...*uint) {
+    notif := map[string]interface{}{
+        "type":     "bill_updated",
+        "id":       b.ID,
+        "table_id": b.TableID,
+        "status":   b.Status,
+        "total":    b.Total,
+    }
+    if data, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(data))
+    }
+    for _, t := range append([]*uint{b.TableID}, previousTable...) {
+        if t == nil {
+            continue
+        }
+        open, err := bills.ListActiveByTable(*t)
+        if err != nil {
+            continue
+        }
+        ids := make([]uint, 0, len(open))
+        for _, o := range open {
+            ids = append(ids, o.ID)
+        }
+        notif := map[string]interface{}{
+            "type":     "table_status",
+            "table_id": *t,
+            "occupied": len(ids) > 0,
+            "bill_ids": ids,
+        }
+        if data, err := json.Marshal(notif); err == nil {
+            utils.NotifierInstance.Notify(string(data))
+        }
+    }
+}
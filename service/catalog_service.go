@@ -0,0 +1,90 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+)
+
+// maximum number of serialized responses kept per catalog version
+const catalogCacheSize = 512
+
+type CatalogService interface {
+    Version() (*model.CatalogState, error)
+    // Bump marks the catalog as changed: it increments the version, drops cached responses
+    // and pushes a catalog_updated event to SSE clients
+    Bump(reason string)
+    // Cached returns the serialized response stored under key for the given version,
+    // building (and storing) it when missing
+    Cached(version uint64, key string, build func() ([]byte, error)) ([]byte, error)
+}
+
+type catalogService struct{
+    repo repository.CatalogRepository
+
+    mu      sync.Mutex
+    version uint64
+    entries map[string][]byte
+}
+
+func NewCatalogService(r repository.CatalogRepository) CatalogService {
+    return &catalogService{repo: r, entries: map[string][]byte{}}
+}
+
+func (s *catalogService) Version() (*model.CatalogState, error) {
+    return s.repo.Current()
+}
+
+func (s *catalogService) Bump(reason string) {
+    st, err := s.repo.Bump()
+
+    s.mu.Lock()
+    s.entries = map[string][]byte{}
+    s.mu.Unlock()
+
+    if err != nil {
+        log.Printf("failed to bump catalog version (%s): %v", reason, err)
+        return
+    }
+    notif := map[string]interface{}{
+        "type":    "catalog_updated",
+        "version": st.Version,
+        "reason":  reason,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+}
+
+func (s *catalogService) Cached(version uint64, key string, build func() ([]byte, error)) ([]byte, error) {
+    s.mu.Lock()
+    if s.version != version {
+        // another instance (or this one) bumped the catalog, nothing cached is valid anymore
+        s.version = version
+        s.entries = map[string][]byte{}
+    }
+    if b, ok := s.entries[key]; ok {
+        s.mu.Unlock()
+        return b, nil
+    }
+    s.mu.Unlock()
+
+    b, err := build()
+    if err != nil {
+        return nil, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.version == version {
+        if len(s.entries) >= catalogCacheSize {
+            s.entries = map[string][]byte{}
+        }
+        s.entries[key] = b
+    }
+    return b, nil
+}
@@ -11,15 +11,20 @@ type CategoryService interface {
 }
 
 type categoryService struct{
-    repo repository.CategoryRepository
+    repo    repository.CategoryRepository
+    catalog CatalogService
 }
 
-func NewCategoryService(r repository.CategoryRepository) CategoryService {
-    return &categoryService{repo: r}
+func NewCategoryService(r repository.CategoryRepository, catalog CatalogService) CategoryService {
+    return &categoryService{repo: r, catalog: catalog}
 }
 
 func (s *categoryService) Create(cat *model.Category) error {
-    return s.repo.Create(cat)
+    if err := s.repo.Create(cat); err != nil {
+        return err
+    }
+    s.catalog.Bump("category_created")
+    return nil
 }
 
 func (s *categoryService) List() ([]model.Category, error) {
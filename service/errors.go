@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidationError reports invalid client input; controllers answer it with 400 instead of 500
+type ValidationError struct {
+    Message string
+}
+
+func (e *ValidationError) Error() string {
+    return e.Message
+}
+
+func validationErrorf(format string, args ...interface{}) error {
+    return &ValidationError{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsValidationError reports whether err (or an error it wraps) is a ValidationError
+func IsValidationError(err error) bool {
+    var v *ValidationError
+    return errors.As(err, &v)
+}
+
+// ErrNotFound is returned when the addressed record does not exist (404)
+var ErrNotFound = errors.New("not found")
+
+// ErrForbidden is returned when the user (or the approving manager) lacks the permission (403)
+var ErrForbidden = errors.New("forbidden")
+
+// ErrTooManyAttempts is returned while approvals by PIN are locked after too many wrong
+// PINs (429)
+var ErrTooManyAttempts = errors.New("too many wrong PINs")
+
+// ErrGateway wraps failures of the payment gateway (502)
+var ErrGateway = errors.New("payment gateway error")
+
+// ErrPaymentNotStarted is returned together with a stored sale whose QRIS payment the
+// gateway could not start; the sale was cancelled (402)
+var ErrPaymentNotStarted = errors.New("payment could not be started")
+
+// ConflictError reports a request that is valid but clashes with the current state of a
+// record (e.g. an order that was already handled); controllers answer it with 409
+type ConflictError struct {
+    Message string
+}
+
+func (e *ConflictError) Error() string {
+    return e.Message
+}
+
+func conflictErrorf(format string, args ...interface{}) error {
+    return &ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsConflictError reports whether err (or an error it wraps) is a ConflictError
+func IsConflictError(err error) bool {
+    var c *ConflictError
+    return errors.As(err, &c)
+}
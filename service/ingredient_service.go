@@ -0,0 +1,165 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"gorm.io/gorm"
+)
+
+type IngredientService interface {
+    List() ([]model.Ingredient, error)
+    Create(req *dto.IngredientRequest) (*model.Ingredient, error)
+    // Update changes an ingredient and recalculates the cost of menus using it in their recipe
+    Update(id uint, req *dto.IngredientRequest) (*model.Ingredient, error)
+    // Delete removes an ingredient that no recipe uses
+    Delete(id uint) error
+    // MenuCost returns the cost breakdown and margin of a menu
+    MenuCost(menuID uint) (*dto.MenuCost, error)
+    // SetRecipe replaces the recipe of a menu and derives its cost from it; an empty
+    // recipe switches the menu back to a manual cost
+    SetRecipe(menuID uint, lines []dto.RecipeLineRequest) (*dto.MenuCost, error)
+}
+
+type ingredientService struct{
+    repo     repository.IngredientRepository
+    menuRepo repository.MenuRepository
+}
+
+func NewIngredientService(r repository.IngredientRepository, menuRepo repository.MenuRepository) IngredientService {
+    return &ingredientService{repo: r, menuRepo: menuRepo}
+}
+
+func (s *ingredientService) List() ([]model.Ingredient, error) {
+    return s.repo.List()
+}
+
+func (s *ingredientService) Create(req *dto.IngredientRequest) (*model.Ingredient, error) {
+    i := &model.Ingredient{}
+    if err := s.apply(i, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Create(i); err != nil {
+        return nil, err
+    }
+    return i, nil
+}
+
+func (s *ingredientService) Update(id uint, req *dto.IngredientRequest) (*model.Ingredient, error) {
+    i, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if i == nil {
+        return nil, ErrNotFound
+    }
+    if err := s.apply(i, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(i); err != nil {
+        return nil, err
+    }
+    ids, err := s.repo.RecipeMenuIDs(i.ID)
+    if err != nil {
+        return nil, err
+    }
+    for _, menuID := range ids {
+        if err := recalculateRecipeCost(s.menuRepo, menuID); err != nil {
+            return nil, err
+        }
+    }
+    return i, nil
+}
+
+func (s *ingredientService) apply(i *model.Ingredient, req *dto.IngredientRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return validationErrorf("name is required")
+    }
+    if req.CostPerUnit < 0 {
+        return validationErrorf("cost_per_unit must not be negative")
+    }
+    other, err := s.repo.FindByName(name)
+    if err != nil {
+        return err
+    }
+    if other != nil && other.ID != i.ID {
+        return validationErrorf("ingredient %q already exists", name)
+    }
+    i.Name = name
+    i.Unit = strings.TrimSpace(req.Unit)
+    i.CostPerUnit = req.CostPerUnit
+    return nil
+}
+
+func (s *ingredientService) Delete(id uint) error {
+    n, err := s.repo.CountUsage(id)
+    if err != nil {
+        return err
+    }
+    if n > 0 {
+        return conflictErrorf("ingredient is used in %d recipes", n)
+    }
+    return s.repo.Delete(id)
+}
+
+func (s *ingredientService) MenuCost(menuID uint) (*dto.MenuCost, error) {
+    m, err := s.menuRepo.GetByID(menuID)
+    if err != nil {
+        return nil, err
+    }
+    if m == nil {
+        return nil, ErrNotFound
+    }
+    lines, err := s.menuRepo.Recipe(menuID)
+    if err != nil {
+        return nil, err
+    }
+    return menuCostOf(m, lines), nil
+}
+
+func (s *ingredientService) SetRecipe(menuID uint, req []dto.RecipeLineRequest) (*dto.MenuCost, error) {
+    m, err := s.menuRepo.GetByID(menuID)
+    if err != nil {
+        return nil, err
+    }
+    if m == nil {
+        return nil, ErrNotFound
+    }
+    lines := make([]model.MenuIngredient, 0, len(req))
+    seen := map[uint]bool{}
+    for _, l := range req {
+        if l.Quantity <= 0 {
+            return nil, validationErrorf("quantity of ingredient %d must be positive", l.IngredientID)
+        }
+        if seen[l.IngredientID] {
+            return nil, validationErrorf("ingredient %d is listed twice", l.IngredientID)
+        }
+        seen[l.IngredientID] = true
+        ing, err := s.repo.GetByID(l.IngredientID)
+        if err != nil {
+            return nil, err
+        }
+        if ing == nil {
+            return nil, validationErrorf("ingredient %d not found", l.IngredientID)
+        }
+        lines = append(lines, model.MenuIngredient{IngredientID: l.IngredientID, Quantity: l.Quantity})
+    }
+
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        menus := s.menuRepo.WithTx(tx)
+        if err := menus.ReplaceRecipe(menuID, lines); err != nil {
+            return err
+        }
+        if len(lines) == 0 {
+            return menus.SetCost(menuID, m.CostPrice, model.CostModeManual)
+        }
+        return recalculateRecipeCost(menus, menuID)
+    })
+    if err != nil {
+        return nil, err
+    }
+    return s.MenuCost(menuID)
+}
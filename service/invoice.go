@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+// invoiceScope is the part of an invoice number before its sequence: the prefix and the
+// period the sequence runs in, e.g. WRG-20261018 for daily numbering
+func invoiceScope(prefix, reset string, at time.Time) string {
+    switch reset {
+    case "never":
+        return prefix
+    case "yearly":
+        return prefix + "-" + at.Format("2006")
+    case "monthly":
+        return prefix + "-" + at.Format("200601")
+    }
+    return prefix + "-" + at.Format("20060102")
+}
+
+// nextInvoiceNumber allocates the invoice number of a sale made at `at`, e.g.
+// WRG-20261018-0042. invoices must be bound to the database transaction storing the sale.
+func nextInvoiceNumber(invoices repository.InvoiceRepository, at time.Time) (string, error) {
+    scope := invoiceScope(config.InvoicePrefix(), config.InvoiceReset(), at.Local())
+    n, err := invoices.Next(scope)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s-%0*d", scope, config.InvoiceDigits(), n), nil
+}
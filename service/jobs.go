@@ -0,0 +1,22 @@
+package service
+
+import (
+	"log"
+	"time"
+)
+
+// RunEvery calls fn every interval until the process exits, logging failures.
+// A non-positive interval disables the job.
+func RunEvery(name string, interval time.Duration, fn func() error) {
+    if interval <= 0 {
+        log.Printf("job %s disabled", name)
+        return
+    }
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+    for range ticker.C {
+        if err := fn(); err != nil {
+            log.Printf("job %s failed: %v", name, err)
+        }
+    }
+}
@@ -0,0 +1,462 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
+)
+
+// KitchenService routes ordered items to kitchen stations as tickets and tracks their states
+type KitchenService interface {
+    ListStations() ([]model.KitchenStation, error)
+    CreateStation(req *dto.KitchenStationRequest) (*model.KitchenStation, error)
+    UpdateStation(id uint, req *dto.KitchenStationRequest) (*model.KitchenStation, error)
+    DeleteStation(id uint) error
+    // TicketsForTransactionTx creates the tickets of a new sale inside the caller's database
+    // transaction; label defaults to the invoice number. Call Announce after commit.
+    TicketsForTransactionTx(tx *gorm.DB, t *model.Transaction, label string) ([]model.KitchenTicket, error)
+    // TicketsForBillTx creates the tickets of a round of items added to an open bill
+    TicketsForBillTx(tx *gorm.DB, b *model.Bill, items []model.BillItem) ([]model.KitchenTicket, error)
+    // CancelTicketsTx cancels the tickets of a sale that were not served yet (its payment
+    // failed or it was voided) inside the caller's database transaction. Call Announce after
+    // commit.
+    CancelTicketsTx(tx *gorm.DB, transactionID uint) ([]model.KitchenTicket, error)
+    // WithdrawTx takes voided units off the tickets of a sale that were not served yet;
+    // quantities maps transaction item ids to units. A ticket left without lines is cancelled.
+    WithdrawTx(tx *gorm.DB, transactionID uint, quantities map[uint]int) ([]model.KitchenTicket, error)
+    // WithdrawBillItemTx takes quantity units of a bill line off its ticket if that was not
+    // served yet
+    WithdrawBillItemTx(tx *gorm.DB, billItemID uint, quantity int) ([]model.KitchenTicket, error)
+    // Announce pushes new or changed tickets to the SSE feeds of their stations
+    Announce(tickets []model.KitchenTicket)
+    // Tickets returns the tickets of a station (all when 0); active leaves out served and
+    // cancelled ones
+    Tickets(stationID uint, active bool) ([]model.KitchenTicket, error)
+    // Advance moves a ticket forward to status (states cannot go back)
+    Advance(id uint, status string) (*model.KitchenTicket, error)
+}
+
+type kitchenService struct{
+    repo     repository.KitchenRepository
+    menuRepo repository.MenuRepository
+    catRepo  repository.CategoryRepository
+}
+
+func NewKitchenService(r repository.KitchenRepository, menuRepo repository.MenuRepository, catRepo repository.CategoryRepository) KitchenService {
+    return &kitchenService{repo: r, menuRepo: menuRepo, catRepo: catRepo}
+}
+
+func (s *kitchenService) ListStations() ([]model.KitchenStation, error) {
+    return s.repo.ListStations()
+}
+
+func (s *kitchenService) CreateStation(req *dto.KitchenStationRequest) (*model.KitchenStation, error) {
+    st := &model.KitchenStation{IsActive: true}
+    if err := s.apply(st, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.CreateStation(st); err != nil {
+        return nil, err
+    }
+    // is_active defaults to true in the database
+    if !st.IsActive {
+        if err := s.repo.UpdateStation(st); err != nil {
+            return nil, err
+        }
+    }
+    return st, nil
+}
+
+func (s *kitchenService) UpdateStation(id uint, req *dto.KitchenStationRequest) (*model.KitchenStation, error) {
+    st, err := s.repo.GetStation(id)
+    if err != nil {
+        return nil, err
+    }
+    if st == nil {
+        return nil, ErrNotFound
+    }
+    if err := s.apply(st, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.UpdateStation(st); err != nil {
+        return nil, err
+    }
+    return st, nil
+}
+
+func (s *kitchenService) apply(st *model.KitchenStation, req *dto.KitchenStationRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" || len([]rune(name)) > 50 {
+        return validationErrorf("station name is required (at most 50 characters)")
+    }
+    cats, err := s.catRepo.List()
+    if err != nil {
+        return err
+    }
+    byID := map[uint]model.Category{}
+    for _, c := range cats {
+        byID[c.ID] = c
+    }
+    st.Categories = []model.Category{}
+    for _, id := range req.CategoryIDs {
+        c, ok := byID[id]
+        if !ok {
+            return validationErrorf("category id %d not found", id)
+        }
+        st.Categories = append(st.Categories, c)
+    }
+    st.Name = name
+    st.IsDefault = req.IsDefault
+    if req.IsActive != nil {
+        st.IsActive = *req.IsActive
+    }
+    return nil
+}
+
+func (s *kitchenService) DeleteStation(id uint) error {
+    return s.repo.DeleteStation(id)
+}
+
+// ticketLine is an ordered item waiting to be routed to a station
+type ticketLine struct {
+    TransactionItemID *uint
+    BillItemID        *uint
+    MenuID            *uint
+    Quantity          int
+    Seat              int
+    Note              string
+}
+
+// activeTicketStates are the states of tickets still to be served
+var activeTicketStates = []string{model.TicketQueued, model.TicketPreparing, model.TicketReady}
+
+func (s *kitchenService) TicketsForTransactionTx(tx *gorm.DB, t *model.Transaction, label string) ([]model.KitchenTicket, error) {
+    if label == "" {
+        label = invoiceLabel(t)
+    }
+    lines := make([]ticketLine, 0, len(t.Items))
+    for i := range t.Items {
+        it := &t.Items[i]
+        lines = append(lines, ticketLine{TransactionItemID: &it.ID, MenuID: it.MenuID, Quantity: it.Quantity, Note: it.Note})
+    }
+    return s.route(tx, lines, func(k *model.KitchenTicket) {
+        k.TransactionID = &t.ID
+        k.Label = truncate(label, 100)
+    })
+}
+
+func (s *kitchenService) TicketsForBillTx(tx *gorm.DB, b *model.Bill, items []model.BillItem) ([]model.KitchenTicket, error) {
+    lines := make([]ticketLine, 0, len(items))
+    for i := range items {
+        it := &items[i]
+        lines = append(lines, ticketLine{BillItemID: &it.ID, MenuID: it.MenuID, Quantity: it.Quantity, Seat: it.Seat, Note: it.Note})
+    }
+    label := b.Name
+    if label == "" {
+        label = fmt.Sprintf("Bill #%d", b.ID)
+    }
+    return s.route(tx, lines, func(k *model.KitchenTicket) {
+        k.BillID = &b.ID
+        k.Label = truncate(label, 100)
+    })
+}
+
+// route groups the lines by the active station of their menu's category, falling back to the
+// default station; lines no station takes (e.g. packaged goods without a default station)
+// get no ticket. One ticket is created per station.
+func (s *kitchenService) route(tx *gorm.DB, lines []ticketLine, fill func(*model.KitchenTicket)) ([]model.KitchenTicket, error) {
+    kitchen := s.repo.WithTx(tx)
+    stations, err := kitchen.ListStations()
+    if err != nil {
+        return nil, err
+    }
+    byCategory := map[uint]*model.KitchenStation{}
+    var fallback *model.KitchenStation
+    for i := range stations {
+        st := &stations[i]
+        if !st.IsActive {
+            continue
+        }
+        if st.IsDefault && fallback == nil {
+            fallback = st
+        }
+        for _, c := range st.Categories {
+            if _, taken := byCategory[c.ID]; !taken {
+                byCategory[c.ID] = st
+            }
+        }
+    }
+    if len(byCategory) == 0 && fallback == nil {
+        return nil, nil
+    }
+
+    var ids []uint
+    for _, l := range lines {
+        if l.MenuID != nil {
+            ids = append(ids, *l.MenuID)
+        }
+    }
+    menus, err := s.menuRepo.WithTx(tx).ListByIDs(ids)
+    if err != nil {
+        return nil, err
+    }
+    menuByID := map[uint]*model.Menu{}
+    for i := range menus {
+        menuByID[menus[i].ID] = &menus[i]
+    }
+
+    tickets := map[uint]*model.KitchenTicket{}
+    var order []uint
+    for _, l := range lines {
+        if l.MenuID == nil {
+            continue
+        }
+        m := menuByID[*l.MenuID]
+        if m == nil {
+            continue
+        }
+        st := fallback
+        if m.CategoryID != nil {
+            if routed, ok := byCategory[*m.CategoryID]; ok {
+                st = routed
+            }
+        }
+        if st == nil {
+            continue
+        }
+        k, ok := tickets[st.ID]
+        if !ok {
+            k = &model.KitchenTicket{StationID: st.ID, StationName: st.Name, Status: model.TicketQueued}
+            fill(k)
+            tickets[st.ID] = k
+            order = append(order, st.ID)
+        }
+        k.Items = append(k.Items, model.KitchenTicketItem{
+            TransactionItemID: l.TransactionItemID,
+            BillItemID:        l.BillItemID,
+            MenuID:            l.MenuID,
+            Name:              m.Name,
+            Quantity:          l.Quantity,
+            Seat:              l.Seat,
+            Note:              l.Note,
+        })
+    }
+
+    out := make([]model.KitchenTicket, 0, len(order))
+    for _, id := range order {
+        if err := kitchen.CreateTicket(tickets[id]); err != nil {
+            return nil, err
+        }
+        out = append(out, *tickets[id])
+    }
+    return out, nil
+}
+
+func (s *kitchenService) CancelTicketsTx(tx *gorm.DB, transactionID uint) ([]model.KitchenTicket, error) {
+    kitchen := s.repo.WithTx(tx)
+    tickets, err := kitchen.LockTicketsOfTransaction(transactionID, activeTicketStates)
+    if err != nil {
+        return nil, err
+    }
+    for i := range tickets {
+        if err := cancelTicket(kitchen, &tickets[i]); err != nil {
+            return nil, err
+        }
+    }
+    return tickets, nil
+}
+
+func (s *kitchenService) WithdrawTx(tx *gorm.DB, transactionID uint, quantities map[uint]int) ([]model.KitchenTicket, error) {
+    kitchen := s.repo.WithTx(tx)
+    tickets, err := kitchen.LockTicketsOfTransaction(transactionID, activeTicketStates)
+    if err != nil {
+        return nil, err
+    }
+    return withdraw(kitchen, tickets, func(it *model.KitchenTicketItem) int {
+        if it.TransactionItemID == nil {
+            return 0
+        }
+        n := quantities[*it.TransactionItemID]
+        if n > it.Quantity {
+            n = it.Quantity
+        }
+        quantities[*it.TransactionItemID] -= n
+        return n
+    })
+}
+
+func (s *kitchenService) WithdrawBillItemTx(tx *gorm.DB, billItemID uint, quantity int) ([]model.KitchenTicket, error) {
+    kitchen := s.repo.WithTx(tx)
+    tickets, err := kitchen.LockTicketsOfBillItem(billItemID, activeTicketStates)
+    if err != nil {
+        return nil, err
+    }
+    return withdraw(kitchen, tickets, func(it *model.KitchenTicketItem) int {
+        if it.BillItemID == nil || *it.BillItemID != billItemID {
+            return 0
+        }
+        n := quantity
+        if n > it.Quantity {
+            n = it.Quantity
+        }
+        quantity -= n
+        return n
+    })
+}
+
+// withdraw takes units(line) units off the lines of the tickets and returns the tickets it
+// changed. A ticket that would be left without lines is cancelled with its lines as they
+// were, so the station sees what it no longer has to prepare.
+func withdraw(kitchen repository.KitchenRepository, tickets []model.KitchenTicket, units func(*model.KitchenTicketItem) int) ([]model.KitchenTicket, error) {
+    var out []model.KitchenTicket
+    for _, k := range tickets {
+        taken := make([]int, len(k.Items))
+        changed, emptied := false, true
+        for i := range k.Items {
+            taken[i] = units(&k.Items[i])
+            if taken[i] > 0 {
+                changed = true
+            }
+            if taken[i] < k.Items[i].Quantity {
+                emptied = false
+            }
+        }
+        if !changed {
+            continue
+        }
+        if emptied {
+            if err := cancelTicket(kitchen, &k); err != nil {
+                return nil, err
+            }
+            out = append(out, k)
+            continue
+        }
+        left := make([]model.KitchenTicketItem, 0, len(k.Items))
+        for i, it := range k.Items {
+            switch {
+            case taken[i] == 0:
+                left = append(left, it)
+            case taken[i] >= it.Quantity:
+                if err := kitchen.DeleteTicketItem(it.ID); err != nil {
+                    return nil, err
+                }
+            default:
+                it.Quantity -= taken[i]
+                if err := kitchen.UpdateTicketItemQuantity(it.ID, it.Quantity); err != nil {
+                    return nil, err
+                }
+                left = append(left, it)
+            }
+        }
+        k.Items = left
+        out = append(out, k)
+    }
+    return out, nil
+}
+
+// cancelTicket cancels a ticket locked by the caller
+func cancelTicket(kitchen repository.KitchenRepository, k *model.KitchenTicket) error {
+    now := time.Now()
+    ok, err := kitchen.Transition(k.ID, k.Status, map[string]interface{}{"status": model.TicketCancelled, "cancelled_at": now})
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return conflictErrorf("ticket %d was changed in the meantime", k.ID)
+    }
+    k.Status = model.TicketCancelled
+    k.CancelledAt = &now
+    return nil
+}
+
+func (s *kitchenService) Announce(tickets []model.KitchenTicket) {
+    for i := range tickets {
+        s.publish(&tickets[i])
+    }
+}
+
+// publish sends a ticket to its station's feed (and the general one)
+func (s *kitchenService) publish(k *model.KitchenTicket) {
+    notif := map[string]interface{}{
+        "type":   "kitchen_ticket",
+        "ticket": k,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.NotifyTopic(KitchenTopic(k.StationID), string(b))
+    }
+}
+
+// KitchenTopic is the notifier topic of a station's SSE feed
+func KitchenTopic(stationID uint) string {
+    return fmt.Sprintf("kitchen:%d", stationID)
+}
+
+func (s *kitchenService) Tickets(stationID uint, active bool) ([]model.KitchenTicket, error) {
+    var statuses []string
+    if active {
+        statuses = activeTicketStates
+    }
+    return s.repo.ListTickets(stationID, statuses)
+}
+
+func (s *kitchenService) Advance(id uint, status string) (*model.KitchenTicket, error) {
+    to := ticketStateIndex(status)
+    if to < 0 {
+        return nil, validationErrorf("status must be one of %s", strings.Join(model.TicketStates, ", "))
+    }
+    k, err := s.repo.GetTicket(id)
+    if err != nil {
+        return nil, err
+    }
+    if k == nil {
+        return nil, ErrNotFound
+    }
+    if k.Status == model.TicketCancelled {
+        return nil, conflictErrorf("ticket %d was cancelled", id)
+    }
+    from := ticketStateIndex(k.Status)
+    if to <= from {
+        return nil, conflictErrorf("ticket %d is already %s", id, k.Status)
+    }
+    // skipped states get the same time, e.g. a drink handed out straight from queued
+    now := time.Now()
+    updates := map[string]interface{}{"status": status}
+    if from < 1 && to >= 1 {
+        updates["started_at"] = now
+        k.StartedAt = &now
+    }
+    if from < 2 && to >= 2 {
+        updates["ready_at"] = now
+        k.ReadyAt = &now
+    }
+    if to >= 3 {
+        updates["served_at"] = now
+        k.ServedAt = &now
+    }
+    ok, err := s.repo.Transition(id, k.Status, updates)
+    if err != nil {
+        return nil, err
+    }
+    if !ok {
+        return nil, conflictErrorf("ticket %d was changed in the meantime", id)
+    }
+    k.Status = status
+    s.publish(k)
+    return k, nil
+}
+
+func ticketStateIndex(status string) int {
+    for i, st := range model.TicketStates {
+        if st == status {
+            return i
+        }
+    }
+    return -1
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"math"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+// roundMoney rounds to whole cents so float noise does not show up in costs and reports
+func roundMoney(v float64) float64 {
+    return math.Round(v*100) / 100
+}
+
+// marginPercent is the gross margin of amount over revenue in percent (0 without revenue)
+func marginPercent(profit, revenue float64) float64 {
+    if revenue == 0 {
+        return 0
+    }
+    return math.Round(profit/revenue*10000) / 100
+}
+
+func recipeCost(lines []model.MenuIngredient) float64 {
+    sum := 0.0
+    for _, l := range lines {
+        sum += l.Quantity * l.Ingredient.CostPerUnit
+    }
+    return roundMoney(sum)
+}
+
+func menuCostOf(m *model.Menu, lines []model.MenuIngredient) *dto.MenuCost {
+    out := &dto.MenuCost{
+        MenuID:      m.ID,
+        Name:        m.Name,
+        Price:       m.Price,
+        CostPrice:   m.CostPrice,
+        CostMode:    m.CostMode,
+        GrossProfit: roundMoney(m.Price - m.CostPrice),
+        Margin:      marginPercent(m.Price-m.CostPrice, m.Price),
+        Recipe:      []dto.RecipeLine{},
+    }
+    for _, l := range lines {
+        out.Recipe = append(out.Recipe, dto.RecipeLine{
+            IngredientID: l.IngredientID,
+            Name:         l.Ingredient.Name,
+            Unit:         l.Ingredient.Unit,
+            Quantity:     l.Quantity,
+            CostPerUnit:  l.Ingredient.CostPerUnit,
+            Cost:         roundMoney(l.Quantity * l.Ingredient.CostPerUnit),
+        })
+    }
+    return out
+}
+
+// recalculateRecipeCost stores the recipe-derived cost of a menu
+func recalculateRecipeCost(menus repository.MenuRepository, menuID uint) error {
+    lines, err := menus.Recipe(menuID)
+    if err != nil {
+        return err
+    }
+    return menus.SetCost(menuID, recipeCost(lines), model.CostModeRecipe)
+}
@@ -0,0 +1,447 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// menuImportColumns is the column layout used by export and expected (by header name) on import
+var menuImportColumns = []string{"sku", "name", "description", "price", "category", "image_url", "is_available", "barcodes"}
+
+// errImportRollback aborts the import transaction without reporting a failure (dry-run or invalid rows)
+var errImportRollback = errors.New("import rolled back")
+
+type MenuImportService interface {
+    // Export returns all menus encoded as csv or xlsx
+    Export(format string) ([]byte, error)
+    // Import upserts menus by SKU, or by name when the row has no SKU, and creates missing
+    // categories by name. All rows are applied in a single
+    // transaction; when any row is invalid or dryRun is set nothing is committed.
+    Import(r io.Reader, format string, dryRun bool) (*dto.MenuImportReport, error)
+}
+
+type menuImportService struct{
+    menuRepo   repository.MenuRepository
+    catRepo    repository.CategoryRepository
+    uploadRepo repository.UploadRepository
+    catalog    CatalogService
+}
+
+func NewMenuImportService(mr repository.MenuRepository, cr repository.CategoryRepository, ur repository.UploadRepository, catalog CatalogService) MenuImportService {
+    return &menuImportService{menuRepo: mr, catRepo: cr, uploadRepo: ur, catalog: catalog}
+}
+
+func (s *menuImportService) Export(format string) ([]byte, error) {
+    list, err := s.menuRepo.List()
+    if err != nil {
+        return nil, err
+    }
+    rows := [][]string{menuImportColumns}
+    for _, m := range list {
+        sku := ""
+        if m.SKU != nil {
+            sku = *m.SKU
+        }
+        codes := make([]string, 0, len(m.Barcodes))
+        for _, b := range m.Barcodes {
+            codes = append(codes, b.Code)
+        }
+        rows = append(rows, []string{
+            sku,
+            m.Name,
+            m.Description,
+            strconv.FormatFloat(m.Price, 'f', -1, 64),
+            m.Category.Name,
+            m.ImageURL,
+            strconv.FormatBool(m.IsAvailable),
+            strings.Join(codes, "|"),
+        })
+    }
+
+    switch format {
+    case "csv":
+        var buf bytes.Buffer
+        w := csv.NewWriter(&buf)
+        if err := w.WriteAll(rows); err != nil {
+            return nil, err
+        }
+        return buf.Bytes(), nil
+    case "xlsx":
+        f := excelize.NewFile()
+        sheet := "Menus"
+        f.SetSheetName(f.GetSheetName(0), sheet)
+        for i, r := range rows {
+            cell, _ := excelize.CoordinatesToCellName(1, i+1)
+            vals := make([]interface{}, len(r))
+            for j, v := range r {
+                vals[j] = v
+            }
+            // keep price numeric in the spreadsheet
+            if i > 0 {
+                vals[3] = list[i-1].Price
+                vals[6] = list[i-1].IsAvailable
+            }
+            if err := f.SetSheetRow(sheet, cell, &vals); err != nil {
+                return nil, err
+            }
+        }
+        var buf bytes.Buffer
+        if err := f.Write(&buf); err != nil {
+            return nil, err
+        }
+        return buf.Bytes(), nil
+    }
+    return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func (s *menuImportService) Import(r io.Reader, format string, dryRun bool) (*dto.MenuImportReport, error) {
+    records, err := readImportRecords(r, format)
+    if err != nil {
+        return nil, err
+    }
+    if len(records) < 2 {
+        return nil, fmt.Errorf("import file has no data rows")
+    }
+
+    header := map[string]int{}
+    for i, h := range records[0] {
+        header[strings.ToLower(strings.TrimSpace(h))] = i
+    }
+    for _, required := range []string{"name", "price"} {
+        if _, ok := header[required]; !ok {
+            return nil, fmt.Errorf("missing required column %q", required)
+        }
+    }
+
+    report := &dto.MenuImportReport{DryRun: dryRun, CategoriesCreated: []string{}}
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        menus := s.menuRepo.WithTx(tx)
+        cats := s.catRepo.WithTx(tx)
+        uploads := s.uploadRepo.WithTx(tx)
+        seen := map[string]int{}
+        catCache := map[string]*model.Category{}
+
+        for i, rec := range records[1:] {
+            if isBlankRecord(rec) {
+                continue
+            }
+            row := dto.MenuImportRow{Row: i + 2}
+            if err := s.importRow(menus, cats, header, rec, &row, seen, catCache, report); err != nil {
+                return err
+            }
+            if row.MenuID != 0 {
+                if err := uploads.Sync(UploadEntityMenu, row.MenuID, nonEmpty(row.ImageURL)); err != nil {
+                    return err
+                }
+            }
+            report.Total++
+            switch row.Action {
+            case "create":
+                report.Created++
+            case "update":
+                report.Updated++
+            default:
+                report.Failed++
+            }
+            report.Rows = append(report.Rows, row)
+        }
+        if dryRun || report.Failed > 0 {
+            return errImportRollback
+        }
+        return nil
+    })
+    if err != nil && !errors.Is(err, errImportRollback) {
+        return nil, err
+    }
+    report.Committed = err == nil
+    if report.Committed {
+        s.catalog.Bump("menus_imported")
+    }
+    return report, nil
+}
+
+// importRow validates and applies one record. Validation problems are recorded on row;
+// only database errors are returned.
+func (s *menuImportService) importRow(menus repository.MenuRepository, cats repository.CategoryRepository, header map[string]int, rec []string, row *dto.MenuImportRow, seen map[string]int, catCache map[string]*model.Category, report *dto.MenuImportReport) error {
+    get := func(col string) (string, bool) {
+        i, ok := header[col]
+        if !ok {
+            return "", false
+        }
+        if i >= len(rec) {
+            return "", true
+        }
+        return strings.TrimSpace(rec[i]), true
+    }
+
+    name, _ := get("name")
+    row.Name = name
+    sku, _ := get("sku")
+    row.SKU = sku
+    if name == "" {
+        row.Errors = append(row.Errors, "name is required")
+    } else if prev, dup := seen["name:"+strings.ToLower(name)]; dup {
+        row.Errors = append(row.Errors, fmt.Sprintf("duplicate name of row %d", prev))
+    } else {
+        seen["name:"+strings.ToLower(name)] = row.Row
+    }
+    if sku != "" {
+        if prev, dup := seen["sku:"+sku]; dup {
+            row.Errors = append(row.Errors, fmt.Sprintf("duplicate sku of row %d", prev))
+        } else {
+            seen["sku:"+sku] = row.Row
+        }
+    }
+
+    var barcodes []model.MenuBarcode
+    barcodeStr, hasBarcodes := get("barcodes")
+    if hasBarcodes {
+        for _, code := range strings.FieldsFunc(barcodeStr, func(r rune) bool { return r == '|' || r == ',' || r == ';' }) {
+            code = strings.TrimSpace(code)
+            sym := utils.DetectSymbology(code)
+            if err := utils.ValidateBarcode(code, sym); err != nil {
+                row.Errors = append(row.Errors, err.Error())
+                continue
+            }
+            if prev, dup := seen["barcode:"+code]; dup {
+                row.Errors = append(row.Errors, fmt.Sprintf("barcode %s duplicates row %d", code, prev))
+                continue
+            }
+            seen["barcode:"+code] = row.Row
+            barcodes = append(barcodes, model.MenuBarcode{Code: code, Symbology: sym})
+        }
+    }
+
+    priceStr, _ := get("price")
+    price, err := parseImportPrice(priceStr)
+    if err != nil {
+        row.Errors = append(row.Errors, err.Error())
+    }
+
+    var available *bool
+    if v, ok := get("is_available"); ok && v != "" {
+        b, err := parseImportBool(v)
+        if err != nil {
+            row.Errors = append(row.Errors, err.Error())
+        } else {
+            available = &b
+        }
+    }
+
+    var cat *model.Category
+    if catName, ok := get("category"); ok && catName != "" {
+        row.Category = catName
+        key := strings.ToLower(catName)
+        cat = catCache[key]
+        if cat == nil {
+            found, err := cats.FindByName(catName)
+            if err != nil {
+                return err
+            }
+            cat = found
+        }
+        if cat == nil && len(row.Errors) == 0 {
+            cat = &model.Category{Name: catName}
+            if err := cats.Create(cat); err != nil {
+                return err
+            }
+            report.CategoriesCreated = append(report.CategoriesCreated, catName)
+        }
+        if cat != nil {
+            catCache[key] = cat
+        }
+    }
+
+    if len(row.Errors) > 0 {
+        row.Action = "error"
+        return nil
+    }
+
+    // match by SKU first so renamed items are updated, then by name
+    var m *model.Menu
+    if sku != "" {
+        if m, err = menus.FindBySKU(sku); err != nil {
+            return err
+        }
+    }
+    if m == nil {
+        if m, err = menus.FindByName(name); err != nil {
+            return err
+        }
+        if m != nil && sku != "" && m.SKU != nil && *m.SKU != sku {
+            row.Errors = append(row.Errors, fmt.Sprintf("menu %q already has sku %s", name, *m.SKU))
+        }
+    }
+    for _, b := range barcodes {
+        other, err := menus.FindByBarcode([]string{b.Code})
+        if err != nil {
+            return err
+        }
+        if other != nil && (m == nil || other.ID != m.ID) {
+            row.Errors = append(row.Errors, fmt.Sprintf("barcode %s is already used by %s", b.Code, other.Name))
+        }
+    }
+    if len(row.Errors) > 0 {
+        row.Action = "error"
+        return nil
+    }
+
+    if m == nil {
+        m = &model.Menu{Name: name, IsAvailable: true}
+        row.Action = "create"
+    } else {
+        row.Action = "update"
+    }
+    m.Name = name
+    if sku != "" {
+        m.SKU = &sku
+    }
+    m.Price = price
+    if v, ok := get("description"); ok {
+        m.Description = v
+    }
+    if v, ok := get("image_url"); ok {
+        m.ImageURL = v
+    }
+    if available != nil {
+        m.IsAvailable = *available
+    }
+    if cat != nil {
+        m.CategoryID = &cat.ID
+        m.Category = *cat
+    }
+
+    row.ImageURL = m.ImageURL
+    if row.Action == "create" {
+        err = menus.Create(m)
+    } else {
+        err = menus.Update(m)
+    }
+    if err != nil {
+        return err
+    }
+    if hasBarcodes {
+        if err := menus.ReplaceBarcodes(m.ID, barcodes); err != nil {
+            return err
+        }
+    }
+    row.MenuID = m.ID
+    return nil
+}
+
+func readImportRecords(r io.Reader, format string) ([][]string, error) {
+    switch format {
+    case "csv":
+        cr := csv.NewReader(r)
+        cr.FieldsPerRecord = -1
+        cr.TrimLeadingSpace = true
+        return cr.ReadAll()
+    case "xlsx":
+        f, err := excelize.OpenReader(r)
+        if err != nil {
+            return nil, err
+        }
+        defer f.Close()
+        return f.GetRows(f.GetSheetName(0))
+    }
+    return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+func nonEmpty(vals ...string) []string {
+    var out []string
+    for _, v := range vals {
+        if v != "" {
+            out = append(out, v)
+        }
+    }
+    return out
+}
+
+func isBlankRecord(rec []string) bool {
+    for _, v := range rec {
+        if strings.TrimSpace(v) != "" {
+            return false
+        }
+    }
+    return true
+}
+
+// parseImportPrice reads a price as exported ("15000", "12500.5") or as typed in Indonesian
+// spreadsheets ("Rp 15.000", "Rp15.000,50"); US grouping ("15,000.50") is understood too.
+// A lone separator followed by exactly three digits per group is a thousands separator,
+// otherwise it is the decimal mark.
+func parseImportPrice(v string) (float64, error) {
+    v = strings.TrimSpace(v)
+    raw := v
+    if len(v) >= 2 && strings.EqualFold(v[:2], "rp") {
+        v = strings.TrimSpace(strings.TrimPrefix(v[2:], "."))
+    }
+    v = strings.ReplaceAll(v, " ", "")
+    if v == "" {
+        return 0, fmt.Errorf("price is required")
+    }
+    dot, comma := strings.LastIndex(v, "."), strings.LastIndex(v, ",")
+    switch {
+    case dot >= 0 && comma >= 0:
+        // both: the last one is the decimal mark
+        thousands, decimal := ",", "."
+        if comma > dot {
+            thousands, decimal = ".", ","
+        }
+        intPart, frac, _ := strings.Cut(v, decimal)
+        if strings.Contains(frac, thousands) || !thousandsGrouped(intPart, thousands) {
+            return 0, fmt.Errorf("invalid price %q", raw)
+        }
+        v = strings.ReplaceAll(intPart, thousands, "") + "." + frac
+    case dot >= 0 && thousandsGrouped(v, "."):
+        v = strings.ReplaceAll(v, ".", "")
+    case comma >= 0 && thousandsGrouped(v, ","):
+        v = strings.ReplaceAll(v, ",", "")
+    case comma >= 0:
+        v = strings.Replace(v, ",", ".", 1)
+    }
+    p, err := strconv.ParseFloat(v, 64)
+    if err != nil {
+        return 0, fmt.Errorf("invalid price %q", raw)
+    }
+    if p < 0 {
+        return 0, fmt.Errorf("price must not be negative")
+    }
+    return p, nil
+}
+
+// thousandsGrouped reports whether s is digits grouped by sep in threes, e.g. "1.500.000"
+func thousandsGrouped(s, sep string) bool {
+    groups := strings.Split(s, sep)
+    if len(groups) < 2 || len(groups[0]) == 0 || len(groups[0]) > 3 {
+        return false
+    }
+    for i, g := range groups {
+        if (i > 0 && len(g) != 3) || strings.Trim(g, "0123456789") != "" {
+            return false
+        }
+    }
+    return true
+}
+
+func parseImportBool(v string) (bool, error) {
+    switch strings.ToLower(v) {
+    case "1", "true", "yes", "y", "ya":
+        return true, nil
+    case "0", "false", "no", "n", "tidak":
+        return false, nil
+    }
+    return false, fmt.Errorf("invalid is_available %q", v)
+}
@@ -0,0 +1,40 @@
+package service
+
+import "testing"
+
+func TestParseImportPrice(t *testing.T) {
+    cases := []struct {
+        in   string
+        want float64
+    }{
+        {"15000", 15000},
+        {"12500.5", 12500.5},
+        {"Rp 15.000", 15000},
+        {"Rp15.000", 15000},
+        {"Rp. 1.500.000", 1500000},
+        {"rp 15.000,50", 15000.5},
+        {"15.000,5", 15000.5},
+        {"15,000", 15000},
+        {"15,000.50", 15000.5},
+        {"12500,5", 12500.5},
+        {"0.5", 0.5},
+    }
+    for _, c := range cases {
+        got, err := parseImportPrice(c.in)
+        if err != nil {
+            t.Errorf("parseImportPrice(%q): %v", c.in, err)
+            continue
+        }
+        if got != c.want {
+            t.Errorf("parseImportPrice(%q) = %v, want %v", c.in, got, c.want)
+        }
+    }
+}
+
+func TestParseImportPriceRejects(t *testing.T) {
+    for _, in := range []string{"", "Rp", "lima ribu", "1.50.000", "15.000,000.5", "-15000", "15,00,0"} {
+        if p, err := parseImportPrice(in); err == nil {
+            t.Errorf("parseImportPrice(%q) = %v, want an error", in, p)
+        }
+    }
+}
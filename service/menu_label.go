@@ -0,0 +1,168 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// label sheet layout (A4, 3 x 8 labels)
+const (
+    labelCols    = 3
+    labelRows    = 8
+    labelWidth   = 65.0
+    labelHeight  = 34.0
+    labelMarginX = 7.5
+    labelMarginY = 12.5
+    labelPadding = 3.0
+    labelBarsH   = 14.0
+)
+
+type labelCode struct {
+    modules []bool
+    text    string
+}
+
+// LabelsPDF renders `copies` labels per menu with name, price and a Code128 or EAN-13 barcode.
+// symbology is "auto" (EAN-13 for GTIN barcodes, Code128 otherwise), "ean13" or "code128".
+// The code printed is the menu's first barcode, or its SKU when it has none.
+func (s *menuService) LabelsPDF(ids []uint, copies int, symbology string) ([]byte, error) {
+    if len(ids) == 0 {
+        return nil, validationErrorf("no menus selected")
+    }
+    if copies <= 0 {
+        copies = 1
+    }
+    menus, err := s.repo.ListByIDs(ids)
+    if err != nil {
+        return nil, err
+    }
+    if len(menus) == 0 {
+        return nil, validationErrorf("menus not found")
+    }
+
+    codes := make([]labelCode, len(menus))
+    var missing []string
+    for i, m := range menus {
+        c, err := menuLabelCode(m, symbology)
+        if err != nil {
+            return nil, validationErrorf("%s: %v", m.Name, err)
+        }
+        if c == nil {
+            missing = append(missing, m.Name)
+            continue
+        }
+        codes[i] = *c
+    }
+    if len(missing) > 0 {
+        return nil, validationErrorf("no barcode or sku for: %s", strings.Join(missing, ", "))
+    }
+
+    pdf := gofpdf.New("P", "mm", "A4", "")
+    pdf.SetAutoPageBreak(false, 0)
+    pos := 0
+    for i, m := range menus {
+        for c := 0; c < copies; c++ {
+            if pos%(labelCols*labelRows) == 0 {
+                pdf.AddPage()
+            }
+            slot := pos % (labelCols * labelRows)
+            x := labelMarginX + float64(slot%labelCols)*labelWidth
+            y := labelMarginY + float64(slot/labelCols)*labelHeight
+            drawLabel(pdf, x, y, m, codes[i])
+            pos++
+        }
+    }
+
+    var buf bytes.Buffer
+    if err := pdf.Output(&buf); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
+
+func menuLabelCode(m model.Menu, symbology string) (*labelCode, error) {
+    code, sym := "", utils.SymbologyCode128
+    if len(m.Barcodes) > 0 {
+        code, sym = m.Barcodes[0].Code, m.Barcodes[0].Symbology
+    } else if m.SKU != nil {
+        code = *m.SKU
+    }
+    if code == "" {
+        return nil, nil
+    }
+    if symbology == "" || symbology == "auto" {
+        symbology = utils.SymbologyCode128
+        if sym == utils.SymbologyEAN13 || sym == utils.SymbologyUPCA {
+            symbology = utils.SymbologyEAN13
+        }
+    }
+    switch symbology {
+    case utils.SymbologyEAN13:
+        if len(code) == 12 && sym == utils.SymbologyUPCA {
+            code = "0" + code
+        }
+        mods, full, err := utils.EncodeEAN13(code)
+        if err != nil {
+            return nil, err
+        }
+        return &labelCode{modules: mods, text: full}, nil
+    case utils.SymbologyCode128:
+        mods, err := utils.EncodeCode128(code)
+        if err != nil {
+            return nil, err
+        }
+        return &labelCode{modules: mods, text: code}, nil
+    }
+    return nil, fmt.Errorf("unsupported label symbology %q", symbology)
+}
+
+func drawLabel(pdf *gofpdf.Fpdf, x, y float64, m model.Menu, code labelCode) {
+    inner := labelWidth - 2*labelPadding
+
+    // light cut guide
+    pdf.SetDrawColor(220, 220, 220)
+    pdf.SetLineWidth(0.1)
+    pdf.Rect(x, y, labelWidth, labelHeight, "D")
+
+    name := m.Name
+    if len(name) > 34 {
+        name = name[:31] + "..."
+    }
+    pdf.SetTextColor(0, 0, 0)
+    pdf.SetFont("Helvetica", "B", 8)
+    pdf.SetXY(x+labelPadding, y+labelPadding)
+    pdf.CellFormat(inner, 4, name, "", 0, "L", false, 0, "")
+    pdf.SetFont("Helvetica", "B", 9)
+    pdf.SetXY(x+labelPadding, y+labelPadding+4)
+    pdf.CellFormat(inner, 4, utils.FormatRupiah(m.Price), "", 0, "L", false, 0, "")
+
+    // bars, centred; 0.33mm is the nominal EAN module width
+    mw := inner / float64(len(code.modules))
+    if mw > 0.33 {
+        mw = 0.33
+    }
+    bx := x + (labelWidth-mw*float64(len(code.modules)))/2
+    by := y + labelPadding + 9
+    pdf.SetFillColor(0, 0, 0)
+    for i := 0; i < len(code.modules); {
+        if !code.modules[i] {
+            i++
+            continue
+        }
+        j := i
+        for j < len(code.modules) && code.modules[j] {
+            j++
+        }
+        pdf.Rect(bx+float64(i)*mw, by, float64(j-i)*mw, labelBarsH, "F")
+        i = j
+    }
+
+    pdf.SetFont("Courier", "", 8)
+    pdf.SetXY(x+labelPadding, by+labelBarsH+0.5)
+    pdf.CellFormat(inner, 3.5, code.text, "", 0, "C", false, 0, "")
+}
@@ -1,42 +1,233 @@
 package service
 
 import (
+	"strings"
+
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
 )
 
 type MenuService interface {
     Create(m *model.Menu) error
     List() ([]model.Menu, error)
+    Search(f repository.MenuFilter, q utils.ListQuery) ([]model.Menu, utils.PageMeta, error)
     GetByID(id uint) (*model.Menu, error)
     Update(m *model.Menu) error
+    // SetAvailability only switches whether the menu can be ordered (e.g. sold out)
+    SetAvailability(id uint, available bool) (*model.Menu, error)
     Delete(id uint) error
+    // Lookup finds a menu by scanned barcode or by SKU
+    Lookup(code string) (*model.Menu, error)
+    LookupSKU(sku string) (*model.Menu, error)
+    // LabelsPDF renders printable barcode labels for the menus
+    LabelsPDF(ids []uint, copies int, symbology string) ([]byte, error)
 }
 
 type menuService struct{
-    repo repository.MenuRepository
+    repo    repository.MenuRepository
+    uploads UploadService
+    catalog CatalogService
+    tags    TagService
 }
 
-func NewMenuService(r repository.MenuRepository) MenuService {
-    return &menuService{repo: r}
+func NewMenuService(r repository.MenuRepository, uploads UploadService, catalog CatalogService, tags TagService) MenuService {
+    return &menuService{repo: r, uploads: uploads, catalog: catalog, tags: tags}
 }
 
 func (s *menuService) Create(m *model.Menu) error {
-    return s.repo.Create(m)
+    if err := validateMenuCodes(s.repo, m); err != nil {
+        return err
+    }
+    // a recipe can only be attached once the menu exists
+    if m.CostMode == model.CostModeRecipe {
+        return validationErrorf("create the menu with a manual cost, then set its recipe")
+    }
+    if err := validateMenuCost(m); err != nil {
+        return err
+    }
+    if err := s.resolveLabels(m); err != nil {
+        return err
+    }
+    if err := s.repo.Create(m); err != nil {
+        return err
+    }
+    s.catalog.Bump("menu_created")
+    return s.uploads.Attach(UploadEntityMenu, m.ID, m.ImageURL)
 }
 
 func (s *menuService) List() ([]model.Menu, error) {
     return s.repo.List()
 }
 
+func (s *menuService) Search(f repository.MenuFilter, q utils.ListQuery) ([]model.Menu, utils.PageMeta, error) {
+    return s.repo.Search(f, q)
+}
+
 func (s *menuService) GetByID(id uint) (*model.Menu, error) {
     return s.repo.GetByID(id)
 }
 
 func (s *menuService) Update(m *model.Menu) error {
-    return s.repo.Update(m)
+    if err := validateMenuCodes(s.repo, m); err != nil {
+        return err
+    }
+    if err := validateMenuCost(m); err != nil {
+        return err
+    }
+    if m.CostMode == model.CostModeRecipe {
+        lines, err := s.repo.Recipe(m.ID)
+        if err != nil {
+            return err
+        }
+        if len(lines) == 0 {
+            return validationErrorf("menu has no recipe, set it first")
+        }
+        m.CostPrice = recipeCost(lines)
+    }
+    if err := s.resolveLabels(m); err != nil {
+        return err
+    }
+    codes := make([]string, 0, len(m.Allergens))
+    for _, a := range m.Allergens {
+        codes = append(codes, a.Code)
+    }
+    // the menu and its barcodes, tags and allergens change together or not at all
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        menus := s.repo.WithTx(tx)
+        if err := menus.Update(m); err != nil {
+            return err
+        }
+        if err := menus.ReplaceBarcodes(m.ID, m.Barcodes); err != nil {
+            return err
+        }
+        if err := menus.ReplaceTags(m.ID, m.Tags); err != nil {
+            return err
+        }
+        return menus.ReplaceAllergens(m.ID, codes)
+    })
+    if err != nil {
+        return err
+    }
+    s.catalog.Bump("menu_updated")
+    // releases the previous image when it was replaced
+    return s.uploads.Attach(UploadEntityMenu, m.ID, m.ImageURL)
+}
+
+func (s *menuService) SetAvailability(id uint, available bool) (*model.Menu, error) {
+    m, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if m == nil {
+        return nil, ErrNotFound
+    }
+    if err := s.repo.SetAvailable(id, available); err != nil {
+        return nil, err
+    }
+    s.catalog.Bump("menu_updated")
+    return s.repo.GetByID(id)
 }
 
 func (s *menuService) Delete(id uint) error {
-    return s.repo.Delete(id)
+    if err := s.repo.Delete(id); err != nil {
+        return err
+    }
+    s.catalog.Bump("menu_deleted")
+    return s.uploads.Attach(UploadEntityMenu, id)
+}
+
+func (s *menuService) Lookup(code string) (*model.Menu, error) {
+    return s.repo.FindByBarcode(utils.BarcodeVariants(code))
+}
+
+func (s *menuService) LookupSKU(sku string) (*model.Menu, error) {
+    return s.repo.FindBySKU(strings.TrimSpace(sku))
+}
+
+// validateMenuCodes normalises the SKU and barcodes of m and makes sure no other menu uses them
+func validateMenuCodes(repo repository.MenuRepository, m *model.Menu) error {
+    if m.SKU != nil {
+        sku := strings.TrimSpace(*m.SKU)
+        if sku == "" {
+            m.SKU = nil
+        } else {
+            m.SKU = &sku
+            other, err := repo.FindBySKU(sku)
+            if err != nil {
+                return err
+            }
+            if other != nil && other.ID != m.ID {
+                return validationErrorf("sku %s is already used by %s", sku, other.Name)
+            }
+        }
+    }
+
+    seen := map[string]bool{}
+    for i := range m.Barcodes {
+        b := &m.Barcodes[i]
+        b.Code = strings.TrimSpace(b.Code)
+        b.Symbology = strings.ToLower(strings.TrimSpace(b.Symbology))
+        if b.Symbology == "" {
+            b.Symbology = utils.DetectSymbology(b.Code)
+        }
+        if err := utils.ValidateBarcode(b.Code, b.Symbology); err != nil {
+            return validationErrorf("%v", err)
+        }
+        if seen[b.Code] {
+            return validationErrorf("duplicate barcode %s", b.Code)
+        }
+        seen[b.Code] = true
+        other, err := repo.FindByBarcode([]string{b.Code})
+        if err != nil {
+            return err
+        }
+        if other != nil && other.ID != m.ID {
+            return validationErrorf("barcode %s is already used by %s", b.Code, other.Name)
+        }
+    }
+    return nil
+}
+
+func validateMenuCost(m *model.Menu) error {
+    if m.CostMode == "" {
+        m.CostMode = model.CostModeManual
+    }
+    if m.CostMode != model.CostModeManual && m.CostMode != model.CostModeRecipe {
+        return validationErrorf("cost_mode must be manual or recipe")
+    }
+    if m.CostPrice < 0 {
+        return validationErrorf("cost_price must not be negative")
+    }
+    return nil
+}
+
+// resolveLabels turns the tag names of m into stored tags (created when new) and checks the
+// allergen codes against the fixed list
+func (s *menuService) resolveLabels(m *model.Menu) error {
+    seen := map[string]bool{}
+    allergens := make([]model.MenuAllergen, 0, len(m.Allergens))
+    for _, a := range m.Allergens {
+        code := strings.ToLower(strings.TrimSpace(a.Code))
+        if !model.IsAllergen(code) {
+            return validationErrorf("unknown allergen %q", a.Code)
+        }
+        if !seen[code] {
+            seen[code] = true
+            allergens = append(allergens, model.MenuAllergen{Code: code})
+        }
+    }
+    m.Allergens = allergens
+
+    names := make([]string, 0, len(m.Tags))
+    for _, t := range m.Tags {
+        names = append(names, t.Name)
+    }
+    tags, err := s.tags.Resolve(names)
+    if err != nil {
+        return err
+    }
+    m.Tags = tags
+    return nil
 }
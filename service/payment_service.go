@@ -0,0 +1,376 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/gateway"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+// PaymentService confirms QRIS payments through the payment gateway. Without a gateway
+// QRIS tenders are recorded as paid right away.
+type PaymentService interface {
+    // Online reports whether QRIS payments wait for gateway confirmation
+    Online() bool
+    // Start requests the QRIS codes of a stored pending_payment transaction and fills them
+    // into t.Payments. When a code cannot be created or stored the codes created so far are
+    // cancelled, the transaction is cancelled and ErrPaymentNotStarted returned.
+    Start(t *model.Transaction) error
+    // HandleWebhook verifies and applies a gateway notification
+    HandleWebhook(header http.Header, body []byte) error
+    // Cancel gives up the pending payments of a transaction (e.g. the customer pays cash)
+    Cancel(transactionID uint) error
+    // ExpireOverdue cancels the transactions whose QRIS code expired without payment
+    ExpireOverdue() (int, error)
+    QRCode(paymentID uint, size int) ([]byte, error)
+    // Simulate sends a signed notification through the mock gateway
+    Simulate(paymentID uint, status string) error
+}
+
+type paymentService struct{
+    gw         gateway.Gateway
+    repo       repository.PaymentRepository
+    txRepo     repository.TransactionRepository
+    billRepo   repository.BillRepository
+    promotions PromotionService
+    kitchen    KitchenService
+}
+
+func NewPaymentService(gw gateway.Gateway, r repository.PaymentRepository, txRepo repository.TransactionRepository, billRepo repository.BillRepository, promotions PromotionService, kitchen KitchenService) PaymentService {
+    return &paymentService{gw: gw, repo: r, txRepo: txRepo, billRepo: billRepo, promotions: promotions, kitchen: kitchen}
+}
+
+// gatewayTimeout bounds a call to the payment provider
+const gatewayTimeout = 20 * time.Second
+
+func (s *paymentService) Online() bool {
+    return s.gw != nil
+}
+
+func (s *paymentService) Start(t *model.Transaction) error {
+    for i := range t.Payments {
+        p := &t.Payments[i]
+        if p.Status != model.PaymentPending {
+            continue
+        }
+        // the order id is stored first so an early webhook finds the payment
+        orderID := fmt.Sprintf("POS-%d-%d", t.ID, p.ID)
+        expires := time.Now().Add(config.QRISExpiry())
+        if err := s.repo.Update(p.ID, map[string]interface{}{"order_id": orderID, "gateway": s.gw.Name(), "expires_at": expires}); err != nil {
+            return s.abort(t, i, err)
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+        res, err := s.gw.CreateQRIS(ctx, gateway.Charge{OrderID: orderID, Amount: p.Amount, ExpiresAt: expires})
+        cancel()
+        if err != nil {
+            return s.abort(t, i, err)
+        }
+        p.OrderID = &orderID
+        p.Gateway = s.gw.Name()
+        p.Reference = res.Reference
+        p.QRString = res.QRString
+        p.ExpiresAt = &res.ExpiresAt
+        updates := map[string]interface{}{"reference": res.Reference, "qr_string": res.QRString, "expires_at": res.ExpiresAt}
+        if err := s.repo.Update(p.ID, updates); err != nil {
+            return s.abort(t, i, err)
+        }
+    }
+    return nil
+}
+
+// abort gives up a transaction whose payment at index failed could not be started: the codes
+// already created for it are cancelled at the gateway and the failed payment cancels the
+// transaction, so its stock and promotions are not held by a sale nobody can pay
+func (s *paymentService) abort(t *model.Transaction, failed int, cause error) error {
+    for j := 0; j <= failed; j++ {
+        q := t.Payments[j]
+        if q.Status != model.PaymentPending || q.OrderID == nil {
+            continue
+        }
+        ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+        if err := s.gw.Cancel(ctx, *q.OrderID); err != nil {
+            log.Printf("payment %d: cancel at gateway: %v", q.ID, err)
+        }
+        cancel()
+    }
+    p := &t.Payments[failed]
+    if err := s.finish(p.ID, model.PaymentFailed, ""); err != nil {
+        log.Printf("payment %d: %v", p.ID, err)
+    }
+    t.Status = model.TransactionCancelled
+    for j := range t.Payments {
+        if t.Payments[j].Status == model.PaymentPending {
+            t.Payments[j].Status = model.PaymentCancelled
+        }
+    }
+    p.Status = model.PaymentFailed
+    return fmt.Errorf("%w: %v", ErrPaymentNotStarted, cause)
+}
+
+func (s *paymentService) HandleWebhook(header http.Header, body []byte) error {
+    if s.gw == nil {
+        return ErrNotFound
+    }
+    n, err := s.gw.ParseWebhook(header, body)
+    if err != nil {
+        return err
+    }
+    p, err := s.repo.GetByOrderID(n.OrderID)
+    if err != nil {
+        return err
+    }
+    if p == nil {
+        return ErrNotFound
+    }
+    switch n.Status {
+    case gateway.StatusSettled:
+        if math.Abs(n.Amount-p.Amount) > totalTolerance {
+            return validationErrorf("settled amount %.2f does not match payment %.2f", n.Amount, p.Amount)
+        }
+        return s.finish(p.ID, model.PaymentPaid, n.Reference)
+    case gateway.StatusExpired:
+        return s.finish(p.ID, model.PaymentExpired, n.Reference)
+    case gateway.StatusCancelled:
+        return s.finish(p.ID, model.PaymentCancelled, n.Reference)
+    case gateway.StatusFailed:
+        return s.finish(p.ID, model.PaymentFailed, n.Reference)
+    }
+    // still pending
+    return nil
+}
+
+func (s *paymentService) Cancel(transactionID uint) error {
+    t, err := s.txRepo.GetByID(transactionID)
+    if err != nil {
+        return err
+    }
+    if t == nil {
+        return ErrNotFound
+    }
+    if t.Status != model.TransactionPendingPayment {
+        return conflictErrorf("transaction %d is not waiting for payment", transactionID)
+    }
+    for _, p := range t.Payments {
+        if p.Status != model.PaymentPending {
+            continue
+        }
+        if p.OrderID != nil {
+            ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+            err := s.gw.Cancel(ctx, *p.OrderID)
+            cancel()
+            if err != nil {
+                return fmt.Errorf("%w: %v", ErrGateway, err)
+            }
+        }
+        if err := s.finish(p.ID, model.PaymentCancelled, ""); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *paymentService) ExpireOverdue() (int, error) {
+    if s.gw == nil {
+        return 0, nil
+    }
+    // a minute of grace for webhooks that are on their way
+    list, err := s.repo.ListExpired(time.Now().Add(-time.Minute))
+    if err != nil {
+        return 0, err
+    }
+    for _, p := range list {
+        if p.OrderID != nil {
+            ctx, cancel := context.WithTimeout(context.Background(), gatewayTimeout)
+            if err := s.gw.Cancel(ctx, *p.OrderID); err != nil {
+                log.Printf("payment %d: cancel at gateway: %v", p.ID, err)
+            }
+            cancel()
+        }
+        if err := s.finish(p.ID, model.PaymentExpired, ""); err != nil {
+            return 0, err
+        }
+    }
+    return len(list), nil
+}
+
+// finish moves a pending payment to status. A paid payment completes its transaction once
+// no other payment is pending; any other outcome cancels the transaction, its remaining
+// pending payments and kitchen tickets and gives back its promotion uses. A bill settling
+// with the transaction is settled or opened again accordingly. Payments already handled are
+// left alone, so repeated notifications are harmless.
+func (s *paymentService) finish(paymentID uint, status, reference string) error {
+    var t *model.Transaction
+    var bill *model.Bill
+    var tickets []model.KitchenTicket
+    changed := false
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        payments := s.repo.WithTx(tx)
+        txs := s.txRepo.WithTx(tx)
+        bills := s.billRepo.WithTx(tx)
+        p, err := payments.GetByID(paymentID)
+        if err != nil {
+            return err
+        }
+        if p == nil {
+            return ErrNotFound
+        }
+        updates := map[string]interface{}{"status": status}
+        if reference != "" {
+            updates["reference"] = reference
+        }
+        if status == model.PaymentPaid {
+            updates["paid_at"] = time.Now()
+        }
+        ok, err := payments.Transition(paymentID, model.PaymentPending, updates)
+        if err != nil || !ok {
+            return err
+        }
+        changed = true
+
+        if status == model.PaymentPaid {
+            list, err := payments.ListByTransaction(p.TransactionID)
+            if err != nil {
+                return err
+            }
+            for _, other := range list {
+                if other.Status == model.PaymentPending {
+                    t, err = txs.GetByID(p.TransactionID)
+                    return err
+                }
+            }
+            ok, err := txs.Transition(p.TransactionID, model.TransactionPendingPayment, map[string]interface{}{"status": model.TransactionCompleted})
+            if err != nil {
+                return err
+            }
+            if ok {
+                if bill, err = closeBill(bills, p.TransactionID, true); err != nil {
+                    return err
+                }
+            }
+        } else {
+            list, err := payments.ListByTransaction(p.TransactionID)
+            if err != nil {
+                return err
+            }
+            for _, other := range list {
+                if other.Status == model.PaymentPending {
+                    if _, err := payments.Transition(other.ID, model.PaymentPending, map[string]interface{}{"status": model.PaymentCancelled}); err != nil {
+                        return err
+                    }
+                }
+            }
+            ok, err := txs.Transition(p.TransactionID, model.TransactionPendingPayment, map[string]interface{}{"status": model.TransactionCancelled})
+            if err != nil {
+                return err
+            }
+            if ok {
+                cur, err := txs.GetByID(p.TransactionID)
+                if err != nil {
+                    return err
+                }
+                if err := s.promotions.ReleaseTx(tx, cur.Promotions); err != nil {
+                    return err
+                }
+                if tickets, err = s.kitchen.CancelTicketsTx(tx, p.TransactionID); err != nil {
+                    return err
+                }
+                if bill, err = closeBill(bills, p.TransactionID, false); err != nil {
+                    return err
+                }
+            }
+        }
+        t, err = txs.GetByID(p.TransactionID)
+        return err
+    })
+    if err != nil || !changed || t == nil {
+        return err
+    }
+    notif := map[string]interface{}{
+        "type":               "payment_status",
+        "transaction_id":     t.ID,
+        "payment_id":         paymentID,
+        "status":             status,
+        "transaction_status": t.Status,
+        "total":              t.Total,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+    s.kitchen.Announce(tickets)
+    if bill != nil {
+        notifyBill(s.billRepo, bill)
+    }
+    return nil
+}
+
+// closeBill settles the bill settling with a transaction once it is paid, or opens it again
+// when the payment did not go through; it returns the changed bill (nil when there is none)
+func closeBill(bills repository.BillRepository, transactionID uint, paid bool) (*model.Bill, error) {
+    b, err := bills.GetByTransaction(transactionID)
+    if err != nil || b == nil || b.Status != model.BillSettling {
+        return nil, err
+    }
+    updates := map[string]interface{}{"status": model.BillOpen, "transaction_id": nil, "settled_by": nil}
+    if paid {
+        updates = map[string]interface{}{"status": model.BillSettled, "settled_at": time.Now()}
+    }
+    ok, err := bills.Transition(b.ID, model.BillSettling, updates)
+    if err != nil || !ok {
+        return nil, err
+    }
+    return bills.GetByID(b.ID)
+}
+
+func (s *paymentService) QRCode(paymentID uint, size int) ([]byte, error) {
+    p, err := s.repo.GetByID(paymentID)
+    if err != nil {
+        return nil, err
+    }
+    if p == nil || p.QRString == "" {
+        return nil, ErrNotFound
+    }
+    if p.Status != model.PaymentPending {
+        return nil, conflictErrorf("payment %d is %s", paymentID, p.Status)
+    }
+    if size < 128 || size > 2048 {
+        size = 512
+    }
+    return qrcode.Encode(p.QRString, qrcode.Medium, size)
+}
+
+func (s *paymentService) Simulate(paymentID uint, status string) error {
+    mock, ok := s.gw.(*gateway.Mock)
+    if !ok {
+        return ErrNotFound
+    }
+    switch status {
+    case gateway.StatusSettled, gateway.StatusExpired, gateway.StatusCancelled, gateway.StatusFailed:
+    default:
+        return validationErrorf("status must be settled, expired, cancelled or failed")
+    }
+    p, err := s.repo.GetByID(paymentID)
+    if err != nil {
+        return err
+    }
+    if p == nil || p.OrderID == nil {
+        return ErrNotFound
+    }
+    body, sig, err := mock.Sign(gateway.Notification{OrderID: *p.OrderID, Reference: p.Reference, Status: status, Amount: p.Amount})
+    if err != nil {
+        return err
+    }
+    header := http.Header{}
+    header.Set("X-Signature", sig)
+    return s.HandleWebhook(header, body)
+}
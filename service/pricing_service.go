@@ -0,0 +1,274 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+// PricingService applies price rules (happy hours, weekday discounts...) to menu prices
+type PricingService interface {
+    // Snapshot loads the rules in effect at the given time so a whole order is priced
+    // against the same set
+    Snapshot(at time.Time) (*PriceSnapshot, error)
+    // Current quotes the menus whose price a rule changes right now
+    Current() ([]dto.PriceQuote, error)
+    ListRules() ([]model.PriceRule, error)
+    CreateRule(req *dto.PriceRuleRequest) (*model.PriceRule, error)
+    UpdateRule(id uint, req *dto.PriceRuleRequest) (*model.PriceRule, error)
+    DeleteRule(id uint) error
+}
+
+type pricingService struct{
+    repo     repository.PriceRuleRepository
+    menuRepo repository.MenuRepository
+}
+
+func NewPricingService(r repository.PriceRuleRepository, menuRepo repository.MenuRepository) PricingService {
+    return &pricingService{repo: r, menuRepo: menuRepo}
+}
+
+// PriceSnapshot holds the rules matching one moment
+type PriceSnapshot struct {
+    at    time.Time
+    rules []model.PriceRule
+}
+
+func (s *pricingService) Snapshot(at time.Time) (*PriceSnapshot, error) {
+    rules, err := s.repo.ListActive()
+    if err != nil {
+        return nil, err
+    }
+    // daily windows and weekdays are in the shop's time, whatever zone a device sent
+    at = at.Local()
+    snap := &PriceSnapshot{at: at}
+    for _, r := range rules {
+        if ruleActiveAt(r, at) {
+            snap.rules = append(snap.rules, r)
+        }
+    }
+    return snap, nil
+}
+
+// Quote returns the price of m: the matching rule with the highest priority wins, ties go
+// to the lower price. Without a matching rule the menu price applies.
+func (p *PriceSnapshot) Quote(m *model.Menu) dto.PriceQuote {
+    q := dto.PriceQuote{MenuID: m.ID, BasePrice: m.Price, Price: m.Price}
+    var best *model.PriceRule
+    bestPrice := 0.0
+    for i := range p.rules {
+        r := &p.rules[i]
+        if !ruleTargets(r, m) {
+            continue
+        }
+        price := rulePrice(r, m.Price)
+        if best == nil || r.Priority > best.Priority || (r.Priority == best.Priority && price < bestPrice) {
+            best, bestPrice = r, price
+        }
+    }
+    if best != nil {
+        id := best.ID
+        q.Price = bestPrice
+        q.PriceRuleID = &id
+        q.PriceRuleName = best.Name
+    }
+    return q
+}
+
+func (s *pricingService) Current() ([]dto.PriceQuote, error) {
+    snap, err := s.Snapshot(time.Now())
+    if err != nil {
+        return nil, err
+    }
+    out := []dto.PriceQuote{}
+    if len(snap.rules) == 0 {
+        return out, nil
+    }
+    menus, err := s.menuRepo.List()
+    if err != nil {
+        return nil, err
+    }
+    for i := range menus {
+        if q := snap.Quote(&menus[i]); q.PriceRuleID != nil {
+            out = append(out, q)
+        }
+    }
+    return out, nil
+}
+
+func ruleTargets(r *model.PriceRule, m *model.Menu) bool {
+    if r.MenuID != nil {
+        return *r.MenuID == m.ID
+    }
+    if r.CategoryID != nil {
+        return m.CategoryID != nil && *m.CategoryID == *r.CategoryID
+    }
+    return true
+}
+
+func rulePrice(r *model.PriceRule, base float64) float64 {
+    if r.Kind == model.PriceRuleFixed {
+        return r.Value
+    }
+    return roundMoney(base * (1 - r.Value/100))
+}
+
+// ruleActiveAt evaluates the date range, weekday and daily window of a rule
+func ruleActiveAt(r model.PriceRule, at time.Time) bool {
+    if r.ValidFrom != nil && at.Before(*r.ValidFrom) {
+        return false
+    }
+    if r.ValidUntil != nil && !at.Before(*r.ValidUntil) {
+        return false
+    }
+    minute := at.Hour()*60 + at.Minute()
+    day := int(at.Weekday())
+    if r.StartTime != "" && r.EndTime != "" {
+        start, _ := parseClock(r.StartTime)
+        end, _ := parseClock(r.EndTime)
+        if start <= end {
+            if minute < start || minute >= end {
+                return false
+            }
+        } else {
+            // the window runs past midnight; after midnight it still belongs to the previous day
+            if minute < start && minute >= end {
+                return false
+            }
+            if minute < end {
+                day = (day + 6) % 7
+            }
+        }
+    }
+    if r.Weekdays != "" {
+        days, _ := parseWeekdays(r.Weekdays)
+        if !days[day] {
+            return false
+        }
+    }
+    return true
+}
+
+// parseClock turns "HH:MM" into minutes after midnight
+func parseClock(v string) (int, error) {
+    t, err := time.Parse("15:04", v)
+    if err != nil {
+        return 0, validationErrorf("invalid time %q, use HH:MM", v)
+    }
+    return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekdays(v string) (map[int]bool, error) {
+    days := map[int]bool{}
+    for _, p := range strings.Split(v, ",") {
+        d, err := strconv.Atoi(strings.TrimSpace(p))
+        if err != nil || d < 0 || d > 6 {
+            return nil, validationErrorf("invalid weekday %q, use 0 (Sunday) .. 6", p)
+        }
+        days[d] = true
+    }
+    return days, nil
+}
+
+func (s *pricingService) ListRules() ([]model.PriceRule, error) {
+    return s.repo.List()
+}
+
+func (s *pricingService) CreateRule(req *dto.PriceRuleRequest) (*model.PriceRule, error) {
+    r := &model.PriceRule{IsActive: true}
+    if err := applyPriceRule(r, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Create(r); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *pricingService) UpdateRule(id uint, req *dto.PriceRuleRequest) (*model.PriceRule, error) {
+    r, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if r == nil {
+        return nil, ErrNotFound
+    }
+    if err := applyPriceRule(r, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(r); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *pricingService) DeleteRule(id uint) error {
+    return s.repo.Delete(id)
+}
+
+func applyPriceRule(r *model.PriceRule, req *dto.PriceRuleRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return validationErrorf("name is required")
+    }
+    if req.MenuID != nil && req.CategoryID != nil {
+        return validationErrorf("a rule targets either a menu or a category, not both")
+    }
+    switch req.Kind {
+    case model.PriceRuleFixed:
+        if req.Value < 0 {
+            return validationErrorf("price must not be negative")
+        }
+    case model.PriceRulePercent:
+        if req.Value <= 0 || req.Value > 100 {
+            return validationErrorf("percentage must be between 0 and 100")
+        }
+    default:
+        return validationErrorf("kind must be fixed or percent")
+    }
+    if (req.StartTime == "") != (req.EndTime == "") {
+        return validationErrorf("start_time and end_time go together")
+    }
+    if req.StartTime != "" {
+        start, err := parseClock(req.StartTime)
+        if err != nil {
+            return err
+        }
+        end, err := parseClock(req.EndTime)
+        if err != nil {
+            return err
+        }
+        if start == end {
+            return validationErrorf("start_time and end_time must differ")
+        }
+    }
+    weekdays := strings.ReplaceAll(req.Weekdays, " ", "")
+    if weekdays != "" {
+        if _, err := parseWeekdays(weekdays); err != nil {
+            return err
+        }
+    }
+    if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+        return validationErrorf("valid_until must be after valid_from")
+    }
+
+    r.Name = name
+    r.MenuID = req.MenuID
+    r.CategoryID = req.CategoryID
+    r.Weekdays = weekdays
+    r.StartTime = req.StartTime
+    r.EndTime = req.EndTime
+    r.ValidFrom = req.ValidFrom
+    r.ValidUntil = req.ValidUntil
+    r.Kind = req.Kind
+    r.Value = req.Value
+    r.Priority = req.Priority
+    if req.IsActive != nil {
+        r.IsActive = *req.IsActive
+    }
+    return nil
+}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
+)
+
+// PromotionService manages promotions and vouchers and works out which apply to an order
+type PromotionService interface {
+    // Evaluate returns the promotions applied to an order: the automatic ones it qualifies
+    // for plus the given voucher codes. An unusable voucher is a validation error naming the
+    // reason; automatic promotions that do not qualify are skipped.
+    Evaluate(lines []PromoLine, codes []string, at time.Time) ([]model.TransactionPromotion, error)
+    // RedeemTx counts the use of each applied promotion inside tx; a voucher whose usage
+    // limit was reached in the meantime is a conflict
+    RedeemTx(tx *gorm.DB, applied []model.TransactionPromotion) error
+    // ReleaseTx gives back the uses counted by RedeemTx when the transaction is cancelled
+    ReleaseTx(tx *gorm.DB, applied []model.TransactionPromotion) error
+    List() ([]model.Promotion, error)
+    GetByID(id uint) (*model.Promotion, error)
+    Create(req *dto.PromotionRequest) (*model.Promotion, error)
+    Update(id uint, req *dto.PromotionRequest) (*model.Promotion, error)
+    Delete(id uint) error
+}
+
+// PromoLine is an order line as the promotion rules see it
+type PromoLine struct {
+    CategoryID *uint
+    Price      float64
+    Quantity   int
+}
+
+type promotionService struct{
+    repo repository.PromotionRepository
+}
+
+func NewPromotionService(r repository.PromotionRepository) PromotionService {
+    return &promotionService{repo: r}
+}
+
+// promotionCandidate is a promotion with the discount it gives on the order
+type promotionCandidate struct {
+    promo    *model.Promotion
+    discount float64
+}
+
+func (s *promotionService) Evaluate(lines []PromoLine, codes []string, at time.Time) ([]model.TransactionPromotion, error) {
+    subtotal := 0.0
+    for _, l := range lines {
+        subtotal += l.Price * float64(l.Quantity)
+    }
+
+    var vouchers []promotionCandidate
+    seen := map[string]bool{}
+    for _, code := range codes {
+        code = normalizeVoucherCode(code)
+        if code == "" || seen[code] {
+            continue
+        }
+        seen[code] = true
+        p, err := s.repo.GetByCode(code)
+        if err != nil {
+            return nil, err
+        }
+        if p == nil {
+            return nil, validationErrorf("voucher %s is not valid", code)
+        }
+        if problem := promotionProblem(p, at, subtotal); problem != "" {
+            return nil, validationErrorf("voucher %s %s", code, problem)
+        }
+        d := promotionDiscount(p, lines)
+        if d <= 0 {
+            return nil, validationErrorf("voucher %s does not apply to any item of this order", code)
+        }
+        vouchers = append(vouchers, promotionCandidate{promo: p, discount: d})
+    }
+    if len(vouchers) > 1 {
+        for _, v := range vouchers {
+            if !v.promo.Stackable {
+                return nil, validationErrorf("voucher %s cannot be combined with other vouchers", *v.promo.Code)
+            }
+        }
+    }
+
+    autos, err := s.repo.ListAutomatic()
+    if err != nil {
+        return nil, err
+    }
+    var stackable, exclusive []promotionCandidate
+    for i := range autos {
+        p := &autos[i]
+        if promotionProblem(p, at, subtotal) != "" {
+            continue
+        }
+        d := promotionDiscount(p, lines)
+        if d <= 0 {
+            continue
+        }
+        if p.Stackable {
+            stackable = append(stackable, promotionCandidate{promo: p, discount: d})
+        } else {
+            exclusive = append(exclusive, promotionCandidate{promo: p, discount: d})
+        }
+    }
+
+    // a non-stackable voucher was chosen by the customer and applies alone; otherwise the
+    // stackable promotions together compete with the best non-stackable automatic one
+    var chosen []promotionCandidate
+    if len(vouchers) == 1 && !vouchers[0].promo.Stackable {
+        chosen = vouchers
+    } else {
+        chosen = stackDiscounts(append(stackable, vouchers...), lines)
+        sum := 0.0
+        for _, c := range chosen {
+            sum += c.discount
+        }
+        for _, c := range exclusive {
+            if c.discount > sum && len(vouchers) == 0 {
+                chosen, sum = []promotionCandidate{c}, c.discount
+            }
+        }
+    }
+
+    // discounts never exceed the order
+    out := []model.TransactionPromotion{}
+    left := roundMoney(subtotal)
+    for _, c := range chosen {
+        amount := c.discount
+        if amount > left {
+            amount = left
+        }
+        if amount <= 0 {
+            if c.promo.Code != nil {
+                return nil, validationErrorf("voucher %s does not apply to what is left of this order after the other promotions", *c.promo.Code)
+            }
+            continue
+        }
+        left = roundMoney(left - amount)
+        id := c.promo.ID
+        tp := model.TransactionPromotion{PromotionID: &id, Name: c.promo.Name, Kind: c.promo.Kind, Amount: amount}
+        if c.promo.Code != nil {
+            tp.Code = *c.promo.Code
+        }
+        out = append(out, tp)
+    }
+    return out, nil
+}
+
+func (s *promotionService) RedeemTx(tx *gorm.DB, applied []model.TransactionPromotion) error {
+    promos := s.repo.WithTx(tx)
+    for _, a := range applied {
+        if a.PromotionID == nil {
+            continue
+        }
+        ok, err := promos.Redeem(*a.PromotionID)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("promotion %s has reached its usage limit", a.Name)
+        }
+    }
+    return nil
+}
+
+func (s *promotionService) ReleaseTx(tx *gorm.DB, applied []model.TransactionPromotion) error {
+    promos := s.repo.WithTx(tx)
+    for _, a := range applied {
+        if a.PromotionID == nil {
+            continue
+        }
+        if err := promos.Release(*a.PromotionID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// promotionProblem explains why p cannot be used on an order of subtotal at the given time
+func promotionProblem(p *model.Promotion, at time.Time, subtotal float64) string {
+    switch {
+    case !p.IsActive:
+        return "is not active"
+    case p.ValidFrom != nil && at.Before(*p.ValidFrom):
+        return "is not valid yet"
+    case p.ValidUntil != nil && !at.Before(*p.ValidUntil):
+        return "has expired"
+    case p.UsageLimit > 0 && p.UsageCount >= p.UsageLimit:
+        return "has been used up"
+    case subtotal < p.MinSpend:
+        return "needs a minimum spend of " + utils.FormatRupiah(p.MinSpend)
+    }
+    return ""
+}
+
+// stackDiscounts works out the discounts of promotions applied together in their order: each
+// is computed on what the ones before it left of its eligible items, so percentages do not
+// count money that was already taken off
+func stackDiscounts(chosen []promotionCandidate, lines []PromoLine) []promotionCandidate {
+    left := append([]PromoLine(nil), lines...)
+    out := make([]promotionCandidate, len(chosen))
+    for i, c := range chosen {
+        d := promotionDiscount(c.promo, left)
+        out[i] = promotionCandidate{promo: c.promo, discount: d}
+        eligible := 0.0
+        for _, l := range left {
+            if promotionApplies(c.promo, l) {
+                eligible += l.Price * float64(l.Quantity)
+            }
+        }
+        if d <= 0 || eligible <= 0 {
+            continue
+        }
+        // the discount is spread over the eligible items in proportion to their value
+        share := math.Min(d/eligible, 1)
+        for j := range left {
+            if promotionApplies(c.promo, left[j]) {
+                left[j].Price -= left[j].Price * share
+            }
+        }
+    }
+    return out
+}
+
+func promotionApplies(p *model.Promotion, l PromoLine) bool {
+    return p.CategoryID == nil || (l.CategoryID != nil && *l.CategoryID == *p.CategoryID)
+}
+
+// promotionDiscount computes the discount of p on the order lines it covers
+func promotionDiscount(p *model.Promotion, lines []PromoLine) float64 {
+    eligible := 0.0
+    var units []float64
+    for _, l := range lines {
+        if !promotionApplies(p, l) {
+            continue
+        }
+        eligible += l.Price * float64(l.Quantity)
+        if p.Kind == model.PromotionBuyGet {
+            for i := 0; i < l.Quantity; i++ {
+                units = append(units, l.Price)
+            }
+        }
+    }
+    d := 0.0
+    switch p.Kind {
+    case model.PromotionPercent:
+        d = eligible * p.Value / 100
+        if p.MaxDiscount > 0 && d > p.MaxDiscount {
+            d = p.MaxDiscount
+        }
+    case model.PromotionFixed:
+        d = p.Value
+        if d > eligible {
+            d = eligible
+        }
+    case model.PromotionBuyGet:
+        // most expensive units first: in every group of buy+get units the last (cheapest)
+        // get units are free
+        sort.Sort(sort.Reverse(sort.Float64Slice(units)))
+        group := p.BuyQuantity + p.GetQuantity
+        full := len(units) / group * group
+        for i := 0; i < full; i++ {
+            if i%group >= p.BuyQuantity {
+                d += units[i]
+            }
+        }
+    }
+    return roundMoney(d)
+}
+
+func normalizeVoucherCode(code string) string {
+    return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (s *promotionService) List() ([]model.Promotion, error) {
+    return s.repo.List()
+}
+
+func (s *promotionService) GetByID(id uint) (*model.Promotion, error) {
+    p, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if p == nil {
+        return nil, ErrNotFound
+    }
+    return p, nil
+}
+
+func (s *promotionService) Create(req *dto.PromotionRequest) (*model.Promotion, error) {
+    p := &model.Promotion{IsActive: true}
+    if err := s.apply(p, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Create(p); err != nil {
+        return nil, err
+    }
+    return p, nil
+}
+
+func (s *promotionService) Update(id uint, req *dto.PromotionRequest) (*model.Promotion, error) {
+    p, err := s.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if err := s.apply(p, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(p); err != nil {
+        return nil, err
+    }
+    return p, nil
+}
+
+func (s *promotionService) Delete(id uint) error {
+    return s.repo.Delete(id)
+}
+
+// apply validates req and copies it onto p; voucher codes must be unique
+func (s *promotionService) apply(p *model.Promotion, req *dto.PromotionRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return validationErrorf("name is required")
+    }
+    switch req.Kind {
+    case model.PromotionPercent:
+        if req.Value <= 0 || req.Value > 100 {
+            return validationErrorf("percentage must be between 0 and 100")
+        }
+    case model.PromotionFixed:
+        if req.Value <= 0 {
+            return validationErrorf("value must be positive")
+        }
+    case model.PromotionBuyGet:
+        if req.BuyQuantity <= 0 || req.GetQuantity <= 0 {
+            return validationErrorf("buy_quantity and get_quantity must be positive")
+        }
+    default:
+        return validationErrorf("kind must be percent, fixed or bxgy")
+    }
+    if req.MinSpend < 0 || req.MaxDiscount < 0 || req.UsageLimit < 0 {
+        return validationErrorf("min_spend, max_discount and usage_limit must not be negative")
+    }
+    if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+        return validationErrorf("valid_until must be after valid_from")
+    }
+    var code *string
+    if c := normalizeVoucherCode(req.Code); c != "" {
+        other, err := s.repo.GetByCode(c)
+        if err != nil {
+            return err
+        }
+        if other != nil && other.ID != p.ID {
+            return conflictErrorf("voucher code %s is already used", c)
+        }
+        code = &c
+    }
+
+    p.Name = name
+    p.Code = code
+    p.Kind = req.Kind
+    p.Value = req.Value
+    p.MaxDiscount = req.MaxDiscount
+    p.BuyQuantity = req.BuyQuantity
+    p.GetQuantity = req.GetQuantity
+    p.MinSpend = req.MinSpend
+    p.CategoryID = req.CategoryID
+    p.Stackable = req.Stackable
+    p.UsageLimit = req.UsageLimit
+    p.ValidFrom = req.ValidFrom
+    p.ValidUntil = req.ValidUntil
+    if req.IsActive != nil {
+        p.IsActive = *req.IsActive
+    }
+    return nil
+}
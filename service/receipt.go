@@ -0,0 +1,456 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"github.com/jung-kurt/gofpdf"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Receipt formats; HTML is only used for the public receipt page
+const (
+    ReceiptESCPOS = "escpos"
+    ReceiptText   = "text"
+    ReceiptPDF    = "pdf"
+    ReceiptHTML   = "html"
+)
+
+// receiptLine is one line of a receipt; each renderer shows the alignment and style its own way
+type receiptLine struct {
+    Text   string
+    Center bool
+    Bold   bool
+    // Big lines (store name, total) are printed double height
+    Big    bool
+}
+
+// receipt is the layout of a receipt in lines of Width characters, shared by the renderers
+type receipt struct {
+    Width int
+    Lines []receiptLine
+    // QR is encoded as a QR code below the lines (none when empty)
+    QR    string
+}
+
+// receiptChars is how many characters of the printer's standard font fit a paper width
+// (32 on 58 mm, 48 on 80 mm)
+func receiptChars(widthMM int) int {
+    if widthMM >= 80 {
+        return 48
+    }
+    return 32
+}
+
+func (r *receipt) add(text string) {
+    r.Lines = append(r.Lines, receiptLine{Text: text})
+}
+
+func (r *receipt) center(text string, bold, big bool) {
+    for _, l := range wrapText(text, r.Width) {
+        r.Lines = append(r.Lines, receiptLine{Text: l, Center: true, Bold: bold, Big: big})
+    }
+}
+
+// wrapped adds text broken at word boundaries, every line indented by indent
+func (r *receipt) wrapped(text string, indent int) {
+    pad := strings.Repeat(" ", indent)
+    for _, l := range wrapText(text, r.Width-indent) {
+        r.add(pad + l)
+    }
+}
+
+// amount adds a line with label on the left and the amount right aligned
+func (r *receipt) amount(label string, v float64, bold bool) {
+    r.Lines = append(r.Lines, receiptLine{Text: columns(label, receiptAmount(v), r.Width), Bold: bold})
+}
+
+func (r *receipt) separator() {
+    r.add(strings.Repeat("-", r.Width))
+}
+
+// receiptAmount is an amount without the currency, e.g. 18.000 or -2.500
+func receiptAmount(v float64) string {
+    return strings.Replace(utils.FormatRupiah(v), "Rp ", "", 1)
+}
+
+// columns puts left and right on one line of width characters, shortening left if needed
+func columns(left, right string, width int) string {
+    l, rr := []rune(left), []rune(right)
+    space := width - len(rr) - 1
+    if space < 0 {
+        space = 0
+    }
+    if len(l) > space {
+        l = l[:space]
+    }
+    return string(l) + strings.Repeat(" ", width-len(l)-len(rr)) + string(rr)
+}
+
+// wrapText breaks s into lines of at most width characters, splitting long words
+func wrapText(s string, width int) []string {
+    var lines []string
+    var cur []rune
+    for _, word := range strings.Fields(s) {
+        w := []rune(word)
+        if len(cur) > 0 && len(cur)+1+len(w) > width {
+            lines = append(lines, string(cur))
+            cur = nil
+        }
+        if len(cur) > 0 {
+            cur = append(cur, ' ')
+        }
+        cur = append(cur, w...)
+        for len(cur) > width {
+            lines = append(lines, string(cur[:width]))
+            cur = cur[width:]
+        }
+    }
+    if len(cur) > 0 {
+        lines = append(lines, string(cur))
+    }
+    return lines
+}
+
+// invoiceLabel is the invoice number of t, or its id for sales stored before numbering
+func invoiceLabel(t *model.Transaction) string {
+    if t.InvoiceNumber != nil {
+        return *t.InvoiceNumber
+    }
+    return fmt.Sprintf("#%d", t.ID)
+}
+
+// paymentLabel names a tender method on receipts
+func paymentLabel(method string) string {
+    switch method {
+    case model.PaymentCash:
+        return "Tunai"
+    case model.PaymentQRIS:
+        return "QRIS"
+    case "":
+        return "Bayar"
+    }
+    return strings.ToUpper(method[:1]) + method[1:]
+}
+
+// layoutReceipt lays out the receipt of t: store header, items with their allergens, discounts,
+// taxes, rounding, total, tenders and change, then the footer and a QR code of qr (none when
+// empty). allergens holds the allergen names per menu id, cashier the cashier's name (may be
+// empty).
+func layoutReceipt(t *model.Transaction, allergens map[uint][]string, cashier, qr string, widthMM int) *receipt {
+    r := &receipt{Width: receiptChars(widthMM)}
+
+    r.center(config.StoreName(), true, true)
+    if a := config.StoreAddress(); a != "" {
+        r.center(a, false, false)
+    }
+    if p := config.StorePhone(); p != "" {
+        r.center("Telp. "+p, false, false)
+    }
+    r.separator()
+    r.add(columns("No", invoiceLabel(t), r.Width))
+    r.add(columns("Tanggal", t.CreatedAt.Format("02/01/2006 15:04"), r.Width))
+    if cashier != "" {
+        r.add(columns("Kasir", cashier, r.Width))
+    }
+    switch t.Status {
+    case model.TransactionPendingPayment:
+        r.center("*** BELUM LUNAS ***", true, false)
+    case model.TransactionCancelled:
+        r.center("*** DIBATALKAN ***", true, false)
+    case model.TransactionVoided:
+        r.center("*** VOID ***", true, false)
+    }
+    r.separator()
+
+    for _, it := range t.Items {
+        name := it.Menu.Name
+        if name == "" {
+            name = "Item"
+        }
+        r.wrapped(name, 0)
+        r.add(columns(fmt.Sprintf("  %d x %s", it.Quantity, receiptAmount(it.Price)), receiptAmount(it.Price*float64(it.Quantity)), r.Width))
+        if it.PriceRuleName != "" {
+            r.wrapped("("+it.PriceRuleName+")", 2)
+        }
+        if it.RefundedQuantity > 0 {
+            r.wrapped(fmt.Sprintf("%d dikembalikan", it.RefundedQuantity), 2)
+        }
+        if it.MenuID != nil && len(allergens[*it.MenuID]) > 0 {
+            r.wrapped("Alergen: "+strings.Join(allergens[*it.MenuID], ", "), 2)
+        }
+    }
+    r.separator()
+
+    r.amount("Subtotal", t.Subtotal, false)
+    var promoTotal float64
+    for _, p := range t.Promotions {
+        label := "Diskon " + p.Name
+        if p.Code != "" {
+            label = "Diskon " + p.Code
+        }
+        r.amount(label, -p.Amount, false)
+        promoTotal += p.Amount
+    }
+    if manual := roundMoney(t.Discount - promoTotal); manual > 0 {
+        r.amount("Diskon", -manual, false)
+    }
+    for _, tax := range t.Taxes {
+        label := fmt.Sprintf("%s %s%%", tax.Name, strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", tax.Rate), "0"), "."))
+        if tax.Inclusive {
+            label += " (termasuk)"
+        }
+        r.amount(label, tax.Amount, false)
+    }
+    if t.RoundingAdjustment != 0 {
+        r.amount("Pembulatan", t.RoundingAdjustment, false)
+    }
+    r.Lines = append(r.Lines, receiptLine{Text: columns("TOTAL", receiptAmount(t.Total), r.Width), Bold: true, Big: true})
+
+    if len(t.Payments) == 0 && t.AmountPaid > 0 {
+        // sales stored before split payments
+        r.amount(paymentLabel(t.PaymentMethod), t.AmountPaid, false)
+    }
+    for _, p := range t.Payments {
+        label := paymentLabel(p.Method)
+        switch p.Status {
+        case model.PaymentPending:
+            label += " (menunggu)"
+        case model.PaymentExpired, model.PaymentCancelled, model.PaymentFailed:
+            continue
+        }
+        tendered := p.Tendered
+        if tendered == 0 {
+            tendered = p.Amount
+        }
+        r.amount(label, tendered, false)
+        if p.Reference != "" && p.Method != model.PaymentCash {
+            r.wrapped("Ref: "+p.Reference, 2)
+        }
+    }
+    if t.Change > 0 {
+        r.amount("Kembali", t.Change, true)
+    }
+    if t.RefundedAmount > 0 {
+        r.amount("Dikembalikan", -t.RefundedAmount, false)
+        r.amount("Total bersih", t.Total-t.RefundedAmount, true)
+    }
+    r.separator()
+
+    if f := config.ReceiptFooter(); f != "" {
+        r.center(f, false, false)
+    }
+    r.QR = qr
+    return r
+}
+
+// renderText returns the receipt as plain UTF-8 text
+func renderText(r *receipt) []byte {
+    var b bytes.Buffer
+    for _, l := range r.Lines {
+        if l.Center {
+            if pad := (r.Width - len([]rune(l.Text))) / 2; pad > 0 {
+                b.WriteString(strings.Repeat(" ", pad))
+            }
+        }
+        b.WriteString(l.Text)
+        b.WriteByte('\n')
+    }
+    if r.QR != "" {
+        for _, l := range wrapText(r.QR, r.Width) {
+            b.WriteString(l)
+            b.WriteByte('\n')
+        }
+    }
+    return b.Bytes()
+}
+
+// receiptPage is the public receipt page, readable on a phone
+var receiptPage = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html lang="id">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; padding: 16px; background: #f2f2f2; font-family: sans-serif; }
+.receipt { max-width: {{.Width}}ch; margin: 0 auto; padding: 16px; background: #fff; font-family: monospace; font-size: 14px; line-height: 1.4; box-shadow: 0 1px 4px rgba(0,0,0,.15); }
+.line { white-space: pre; min-height: 1.4em; }
+.center { text-align: center; white-space: pre-wrap; }
+.bold { font-weight: bold; }
+.center.big { font-size: 1.3em; }
+.download { display: block; max-width: {{.Width}}ch; margin: 16px auto; text-align: center; }
+</style>
+</head>
+<body>
+<div class="receipt">
+{{range .Lines}}<div class="line{{if .Center}} center{{end}}{{if .Bold}} bold{{end}}{{if .Big}} big{{end}}">{{.Text}}</div>
+{{end}}</div>
+<a class="download" href="{{.PDFURL}}">Unduh PDF</a>
+</body>
+</html>
+`))
+
+// renderHTML returns the receipt as the public receipt page; pdfURL links its PDF version
+func renderHTML(r *receipt, title, pdfURL string) ([]byte, error) {
+    var b bytes.Buffer
+    err := receiptPage.Execute(&b, map[string]interface{}{
+        "Title":  title,
+        "Width":  r.Width + 4,
+        "Lines":  r.Lines,
+        "PDFURL": pdfURL,
+    })
+    if err != nil {
+        return nil, err
+    }
+    return b.Bytes(), nil
+}
+
+// ESC/POS commands
+var (
+    escInit        = []byte{0x1b, '@'}
+    escAlignLeft   = []byte{0x1b, 'a', 0}
+    escAlignCenter = []byte{0x1b, 'a', 1}
+    escBoldOn      = []byte{0x1b, 'E', 1}
+    escBoldOff     = []byte{0x1b, 'E', 0}
+    escDoubleH     = []byte{0x1d, '!', 0x01}
+    escNormalSize  = []byte{0x1d, '!', 0x00}
+    // feed 4 lines, then a partial cut
+    escFeedCut     = []byte{0x1b, 'd', 4, 0x1d, 'V', 66, 0}
+)
+
+// renderESCPOS returns the receipt as an ESC/POS byte stream for thermal printers. Text is sent
+// as ASCII (other characters print as ?), the QR code with the printer's native QR command.
+func renderESCPOS(r *receipt) []byte {
+    var b bytes.Buffer
+    b.Write(escInit)
+    for _, l := range r.Lines {
+        if l.Center {
+            b.Write(escAlignCenter)
+        } else {
+            b.Write(escAlignLeft)
+        }
+        if l.Bold {
+            b.Write(escBoldOn)
+        }
+        if l.Big {
+            b.Write(escDoubleH)
+        }
+        b.WriteString(asciiOnly(l.Text))
+        b.WriteByte('\n')
+        if l.Big {
+            b.Write(escNormalSize)
+        }
+        if l.Bold {
+            b.Write(escBoldOff)
+        }
+    }
+    if r.QR != "" {
+        b.Write(escAlignCenter)
+        b.WriteByte('\n')
+        writeESCPOSQR(&b, r.QR, r.Width)
+        b.Write(escAlignLeft)
+    }
+    b.Write(escFeedCut)
+    return b.Bytes()
+}
+
+// writeESCPOSQR stores and prints a QR code (GS ( k, model 2, error correction M)
+func writeESCPOSQR(b *bytes.Buffer, data string, width int) {
+    module := byte(4)
+    if width >= 48 {
+        module = 6
+    }
+    n := len(data) + 3
+    b.Write([]byte{0x1d, '(', 'k', 4, 0, 49, 65, 50, 0})
+    b.Write([]byte{0x1d, '(', 'k', 3, 0, 49, 67, module})
+    b.Write([]byte{0x1d, '(', 'k', 3, 0, 49, 69, 49})
+    b.Write([]byte{0x1d, '(', 'k', byte(n % 256), byte(n / 256), 49, 80, 48})
+    b.WriteString(data)
+    b.Write([]byte{0x1d, '(', 'k', 3, 0, 49, 81, 48})
+    b.WriteByte('\n')
+}
+
+func asciiOnly(s string) string {
+    var b strings.Builder
+    for _, c := range s {
+        if c < 32 || c > 126 {
+            c = '?'
+        }
+        b.WriteRune(c)
+    }
+    return b.String()
+}
+
+// PDF receipt layout: Courier at 1.5 mm per character keeps the columns of the text layout
+const (
+    receiptCharMM = 1.5
+    receiptLineMM = 3.6
+    receiptBigMM  = 5.0
+    receiptQRMM   = 28.0
+    receiptMargin = 4.0
+)
+
+// renderPDF returns the receipt as a PDF page of the paper width, as long as its content
+func renderPDF(r *receipt, widthMM int) ([]byte, error) {
+    w := float64(widthMM)
+    h := 2 * receiptMargin
+    for _, l := range r.Lines {
+        if l.Big {
+            h += receiptBigMM
+        } else {
+            h += receiptLineMM
+        }
+    }
+    if r.QR != "" {
+        h += receiptQRMM + receiptLineMM
+    }
+
+    pdf := gofpdf.NewCustom(&gofpdf.InitType{UnitStr: "mm", Size: gofpdf.SizeType{Wd: w, Ht: h}})
+    pdf.SetMargins(0, receiptMargin, 0)
+    pdf.SetAutoPageBreak(false, 0)
+    pdf.AddPage()
+    tr := pdf.UnicodeTranslatorFromDescriptor("")
+    // Courier glyphs are 0.6 em wide
+    size := receiptCharMM / 0.6 * 72 / 25.4
+    x := (w - float64(r.Width)*receiptCharMM) / 2
+    y := receiptMargin
+    for _, l := range r.Lines {
+        style := ""
+        if l.Bold {
+            style = "B"
+        }
+        pdf.SetFont("Courier", style, size)
+        lh := receiptLineMM
+        if l.Big {
+            lh = receiptBigMM
+        }
+        align := "L"
+        if l.Center {
+            align = "C"
+        }
+        pdf.SetXY(x, y)
+        pdf.CellFormat(float64(r.Width)*receiptCharMM, lh, tr(l.Text), "", 0, align+"M", false, 0, "")
+        y += lh
+    }
+    if r.QR != "" {
+        png, err := qrcode.Encode(r.QR, qrcode.Medium, 256)
+        if err != nil {
+            return nil, err
+        }
+        pdf.RegisterImageOptionsReader("qr", gofpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(png))
+        pdf.ImageOptions("qr", (w-receiptQRMM)/2, y+receiptLineMM/2, receiptQRMM, receiptQRMM, false, gofpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+    }
+
+    var buf bytes.Buffer
+    if err := pdf.Output(&buf); err != nil {
+        return nil, err
+    }
+    return buf.Bytes(), nil
+}
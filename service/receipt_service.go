@@ -0,0 +1,193 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+)
+
+// ReceiptService renders the customer receipts of transactions
+type ReceiptService interface {
+    // Render returns the receipt of a transaction in format (escpos, text or pdf) for a paper
+    // width of 58 or 80 mm (0 for RECEIPT_WIDTH_MM), with its content type
+    Render(id uint, format string, widthMM int) ([]byte, string, error)
+    // Link returns the public receipt link of a transaction, creating the token of sales
+    // stored before links existed. Voided and cancelled sales have none.
+    Link(id uint) (*ReceiptLink, error)
+    // Public renders the receipt of a public link as html or pdf, with its content type
+    Public(token, format string) ([]byte, string, error)
+}
+
+// ReceiptLink is the public receipt page of a transaction
+type ReceiptLink struct {
+    Token string `json:"token"`
+    URL   string `json:"url"`
+}
+
+type receiptService struct{
+    txRepo   repository.TransactionRepository
+    menuRepo repository.MenuRepository
+    userRepo repository.UserRepository
+}
+
+func NewReceiptService(txRepo repository.TransactionRepository, menuRepo repository.MenuRepository, userRepo repository.UserRepository) ReceiptService {
+    return &receiptService{txRepo: txRepo, menuRepo: menuRepo, userRepo: userRepo}
+}
+
+func (s *receiptService) Render(id uint, format string, widthMM int) ([]byte, string, error) {
+    if widthMM == 0 {
+        widthMM = config.ReceiptWidth()
+    }
+    if widthMM != 58 && widthMM != 80 {
+        return nil, "", validationErrorf("width must be 58 or 80")
+    }
+    switch format {
+    case ReceiptESCPOS, ReceiptText, ReceiptPDF:
+    default:
+        return nil, "", validationErrorf("format must be escpos, text or pdf")
+    }
+    t, err := s.txRepo.GetByID(id)
+    if err != nil {
+        return nil, "", err
+    }
+    if t == nil {
+        return nil, "", ErrNotFound
+    }
+    qr := config.ReceiptQRURL()
+    if config.ReceiptLinkQR() && shareable(t) {
+        link, err := s.link(t)
+        if err != nil {
+            return nil, "", err
+        }
+        qr = link.URL
+    }
+    r, err := s.layout(t, qr, widthMM)
+    if err != nil {
+        return nil, "", err
+    }
+
+    switch format {
+    case ReceiptESCPOS:
+        return renderESCPOS(r), "application/octet-stream", nil
+    case ReceiptPDF:
+        b, err := renderPDF(r, widthMM)
+        return b, "application/pdf", err
+    }
+    return renderText(r), "text/plain; charset=utf-8", nil
+}
+
+func (s *receiptService) Link(id uint) (*ReceiptLink, error) {
+    t, err := s.txRepo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil {
+        return nil, ErrNotFound
+    }
+    if !shareable(t) {
+        return nil, conflictErrorf("transaction %d is %s, its receipt cannot be shared", id, t.Status)
+    }
+    return s.link(t)
+}
+
+// link returns the receipt link of t, giving it a token first if it has none
+func (s *receiptService) link(t *model.Transaction) (*ReceiptLink, error) {
+    if t.ReceiptToken == nil {
+        token, err := utils.NewReceiptToken()
+        if err != nil {
+            return nil, err
+        }
+        ok, err := s.txRepo.SetReceiptToken(t.ID, token)
+        if err != nil {
+            return nil, err
+        }
+        if !ok {
+            // created by a concurrent request (or the sale was voided meanwhile)
+            cur, err := s.txRepo.GetByID(t.ID)
+            if err != nil {
+                return nil, err
+            }
+            if cur == nil || cur.ReceiptToken == nil {
+                return nil, conflictErrorf("transaction %d was changed in the meantime", t.ID)
+            }
+            token = *cur.ReceiptToken
+        }
+        t.ReceiptToken = &token
+    }
+    return &ReceiptLink{Token: *t.ReceiptToken, URL: receiptURL(*t.ReceiptToken)}, nil
+}
+
+func receiptURL(token string) string {
+    return config.ReceiptBaseURL() + "/r/" + token
+}
+
+// shareable reports whether a sale may have a public receipt
+func shareable(t *model.Transaction) bool {
+    return t.Status != model.TransactionVoided && t.Status != model.TransactionCancelled
+}
+
+func (s *receiptService) Public(token, format string) ([]byte, string, error) {
+    if format != ReceiptHTML && format != ReceiptPDF {
+        return nil, "", validationErrorf("format must be html or pdf")
+    }
+    if token == "" || len(token) > 32 {
+        return nil, "", ErrNotFound
+    }
+    t, err := s.txRepo.GetByReceiptToken(token)
+    if err != nil {
+        return nil, "", err
+    }
+    if t == nil || !shareable(t) {
+        return nil, "", ErrNotFound
+    }
+    widthMM := config.ReceiptWidth()
+    // no QR code: the customer is already looking at the link
+    r, err := s.layout(t, "", widthMM)
+    if err != nil {
+        return nil, "", err
+    }
+    if format == ReceiptPDF {
+        b, err := renderPDF(r, widthMM)
+        return b, "application/pdf", err
+    }
+    title := fmt.Sprintf("Struk %s - %s", invoiceLabel(t), config.StoreName())
+    b, err := renderHTML(r, title, "?format=pdf")
+    return b, "text/html; charset=utf-8", err
+}
+
+// layout loads what the receipt shows besides the transaction (allergens, cashier name)
+func (s *receiptService) layout(t *model.Transaction, qr string, widthMM int) (*receipt, error) {
+    var ids []uint
+    for _, it := range t.Items {
+        if it.MenuID != nil {
+            ids = append(ids, *it.MenuID)
+        }
+    }
+    allergens := map[uint][]string{}
+    if len(ids) > 0 {
+        menus, err := s.menuRepo.ListByIDs(ids)
+        if err != nil {
+            return nil, err
+        }
+        locale := config.DefaultLocale()
+        for _, m := range menus {
+            for _, a := range m.Allergens {
+                allergens[m.ID] = append(allergens[m.ID], model.AllergenName(a.Code, locale, "en"))
+            }
+        }
+    }
+    var cashier string
+    if t.CashierID != nil {
+        u, err := s.userRepo.FindByID(*t.CashierID)
+        if err != nil {
+            return nil, err
+        }
+        if u != nil {
+            cashier = u.Name
+        }
+    }
+    return layoutReceipt(t, allergens, cashier, qr, widthMM), nil
+}
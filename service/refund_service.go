@@ -0,0 +1,261 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
+)
+
+// RefundService voids and refunds transactions with a manager's approval
+type RefundService interface {
+    // Void reverses a mistaken sale (all or some items) on the day it was made
+    Void(transactionID uint, req *dto.RefundRequest, requestedBy *uint) (*model.Refund, error)
+    // Refund gives money back for returned items (all or some) at any later time
+    Refund(transactionID uint, req *dto.RefundRequest, requestedBy *uint) (*model.Refund, error)
+    List(transactionID uint) ([]model.Refund, error)
+}
+
+type refundService struct{
+    repo       repository.RefundRepository
+    txRepo     repository.TransactionRepository
+    auth       AuthService
+    promotions PromotionService
+    kitchen    KitchenService
+}
+
+func NewRefundService(r repository.RefundRepository, txRepo repository.TransactionRepository, auth AuthService, promotions PromotionService, kitchen KitchenService) RefundService {
+    return &refundService{repo: r, txRepo: txRepo, auth: auth, promotions: promotions, kitchen: kitchen}
+}
+
+func (s *refundService) Void(transactionID uint, req *dto.RefundRequest, requestedBy *uint) (*model.Refund, error) {
+    return s.reverse(model.RefundVoid, transactionID, req, requestedBy)
+}
+
+func (s *refundService) Refund(transactionID uint, req *dto.RefundRequest, requestedBy *uint) (*model.Refund, error) {
+    return s.reverse(model.RefundReturn, transactionID, req, requestedBy)
+}
+
+func (s *refundService) List(transactionID uint) ([]model.Refund, error) {
+    return s.repo.ListByTransaction(transactionID)
+}
+
+// reversible lists the statuses a transaction can be voided or refunded in
+var reversible = map[string]bool{
+    "":                                 true,
+    model.TransactionCompleted:         true,
+    model.TransactionPartiallyVoided:   true,
+    model.TransactionPartiallyRefunded: true,
+}
+
+func (s *refundService) reverse(kind string, transactionID uint, req *dto.RefundRequest, requestedBy *uint) (*model.Refund, error) {
+    reason := strings.TrimSpace(req.Reason)
+    if reason == "" {
+        return nil, validationErrorf("reason is required")
+    }
+    approver, err := s.auth.Approve(req.ApprovalToken, req.ManagerID, req.ManagerPIN, requestedBy)
+    if err != nil {
+        return nil, err
+    }
+
+    var rf *model.Refund
+    var t *model.Transaction
+    var tickets []model.KitchenTicket
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        txs := s.txRepo.WithTx(tx)
+        refunds := s.repo.WithTx(tx)
+        var err error
+        t, err = txs.GetByID(transactionID)
+        if err != nil {
+            return err
+        }
+        if t == nil {
+            return ErrNotFound
+        }
+        if !reversible[t.Status] {
+            return conflictErrorf("transaction %d is %s", transactionID, t.Status)
+        }
+        if kind == model.RefundVoid && !sameDay(t.CreatedAt, time.Now()) {
+            return validationErrorf("only sales of today can be voided, use a refund")
+        }
+        method, err := refundMethod(req.Method, t)
+        if err != nil {
+            return err
+        }
+
+        lines, err := refundLines(t, req.Items)
+        if err != nil {
+            return err
+        }
+        rf = &model.Refund{
+            TransactionID: t.ID,
+            Kind:          kind,
+            Reason:        truncate(reason, 255),
+            Method:        method,
+            RequestedBy:   requestedBy,
+            ApprovedBy:    &approver.ID,
+            Items:         lines,
+        }
+        full := true
+        for i := range t.Items {
+            it := &t.Items[i]
+            for _, l := range lines {
+                if l.TransactionItemID == it.ID {
+                    it.RefundedQuantity += l.Quantity
+                }
+            }
+            if it.RefundedQuantity < it.Quantity {
+                full = false
+            }
+        }
+        // the items' share of what was paid (after discounts, taxes and rounding); a full
+        // reversal gives back exactly what is left
+        ratio := 0.0
+        if t.Subtotal > 0 {
+            ratio = t.Total / t.Subtotal
+        }
+        for i := range rf.Items {
+            rf.Items[i].Amount = roundMoney(rf.Items[i].Amount * ratio)
+            rf.Amount += rf.Items[i].Amount
+        }
+        remaining := roundMoney(t.Total - t.RefundedAmount)
+        rf.Amount = roundMoney(rf.Amount)
+        if full || rf.Amount > remaining {
+            rf.Amount = remaining
+        }
+
+        for _, l := range lines {
+            ok, err := refunds.ReverseItem(l.TransactionItemID, l.Quantity)
+            if err != nil {
+                return err
+            }
+            if !ok {
+                return conflictErrorf("item %d was reversed in the meantime", l.TransactionItemID)
+            }
+        }
+        status := model.TransactionPartiallyRefunded
+        switch {
+        case full && kind == model.RefundVoid:
+            status = model.TransactionVoided
+        case full:
+            status = model.TransactionRefunded
+        case kind == model.RefundVoid:
+            status = model.TransactionPartiallyVoided
+        }
+        updates := map[string]interface{}{
+            "status":          status,
+            "refunded_amount": roundMoney(t.RefundedAmount + rf.Amount),
+        }
+        // a voided sale was never meant to happen, so its public receipt link stops working
+        if kind == model.RefundVoid {
+            updates["receipt_token"] = nil
+        }
+        ok, err := txs.Transition(t.ID, t.Status, updates)
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("transaction %d was changed in the meantime", t.ID)
+        }
+        t.Status = status
+        t.RefundedAmount = roundMoney(t.RefundedAmount + rf.Amount)
+        // a fully reversed sale does not count as a promotion use
+        if full {
+            if err := s.promotions.ReleaseTx(tx, t.Promotions); err != nil {
+                return err
+            }
+        }
+        // voided items are no longer to be prepared; returned ones were already served
+        if kind == model.RefundVoid {
+            if full {
+                tickets, err = s.kitchen.CancelTicketsTx(tx, t.ID)
+            } else {
+                voided := map[uint]int{}
+                for _, l := range lines {
+                    voided[l.TransactionItemID] += l.Quantity
+                }
+                tickets, err = s.kitchen.WithdrawTx(tx, t.ID, voided)
+            }
+            if err != nil {
+                return err
+            }
+        }
+        return refunds.Create(rf)
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    notif := map[string]interface{}{
+        "type":            "transaction_refunded",
+        "id":              t.ID,
+        "kind":            kind,
+        "amount":          rf.Amount,
+        "status":          t.Status,
+        "refunded_amount": t.RefundedAmount,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+    s.kitchen.Announce(tickets)
+    return rf, nil
+}
+
+// refundLines validates the requested items against what is left of each line; without
+// items every unit not yet reversed is taken. Amount holds the lines' gross value for now.
+func refundLines(t *model.Transaction, items []dto.RefundItemDTO) ([]model.RefundItem, error) {
+    byID := map[uint]*model.TransactionItem{}
+    for i := range t.Items {
+        byID[t.Items[i].ID] = &t.Items[i]
+    }
+    var out []model.RefundItem
+    if len(items) == 0 {
+        for _, it := range t.Items {
+            if left := it.Quantity - it.RefundedQuantity; left > 0 {
+                out = append(out, model.RefundItem{TransactionItemID: it.ID, MenuID: it.MenuID, Quantity: left, Amount: float64(left) * it.Price})
+            }
+        }
+    }
+    requested := map[uint]int{}
+    for _, r := range items {
+        it, ok := byID[r.TransactionItemID]
+        if !ok {
+            return nil, validationErrorf("item %d does not belong to this transaction", r.TransactionItemID)
+        }
+        requested[it.ID] += r.Quantity
+        if r.Quantity <= 0 || requested[it.ID] > it.Quantity-it.RefundedQuantity {
+            return nil, validationErrorf("item %d: quantity must be between 1 and %d", it.ID, it.Quantity-it.RefundedQuantity)
+        }
+        out = append(out, model.RefundItem{TransactionItemID: it.ID, MenuID: it.MenuID, Quantity: r.Quantity, Amount: float64(r.Quantity) * it.Price})
+    }
+    if len(out) == 0 {
+        return nil, conflictErrorf("nothing left to reverse")
+    }
+    return out, nil
+}
+
+// refundMethod picks how money goes back: as requested, else the method of the sale
+// (cash for split payments)
+func refundMethod(method string, t *model.Transaction) (string, error) {
+    if method == "" {
+        method = t.PaymentMethod
+        if method == model.PaymentSplit || method == "" {
+            method = model.PaymentCash
+        }
+    }
+    if method != model.PaymentCash && method != model.PaymentQRIS {
+        return "", validationErrorf("method must be %s or %s", model.PaymentCash, model.PaymentQRIS)
+    }
+    return method, nil
+}
+
+func sameDay(a, b time.Time) bool {
+    ay, am, ad := a.Date()
+    by, bm, bd := b.In(a.Location()).Date()
+    return ay == by && am == bm && ad == bd
+}
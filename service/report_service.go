@@ -3,8 +3,10 @@ package service
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"time"
 
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"github.com/IndalAwalaikal/warung-pos/backend/repository"
 	"github.com/jung-kurt/gofpdf"
 	"github.com/xuri/excelize/v2"
@@ -18,11 +20,12 @@ type ReportService interface {
 }
 
 type reportService struct{
-    txRepo repository.TransactionRepository
+    txRepo      repository.TransactionRepository
+    kitchenRepo repository.KitchenRepository
 }
 
-func NewReportService(tx repository.TransactionRepository) ReportService {
-    return &reportService{txRepo: tx}
+func NewReportService(tx repository.TransactionRepository, kitchen repository.KitchenRepository) ReportService {
+    return &reportService{txRepo: tx, kitchenRepo: kitchen}
 }
 
 // Daily returns aggregated report data for the given date
@@ -39,42 +42,276 @@ func (s *reportService) Daily(date time.Time) (map[string]interface{}, error) {
     var totalRevenue float64
     var totalTransactions int
     var totalItems int
-    menuCount := map[uint]*struct{ Name string; Count int; Revenue float64 }{}
+    // net sales and cost drive the gross profit; items sold before costs were recorded
+    // (cost 0) are counted so the report can say the profit is overstated
+    var netSales, totalCost float64
+    var totalTax, totalService float64
+    // revenue per tender; transactions stored before split payments count under their method
+    byMethod := map[string]*struct{ Count int; Amount float64 }{}
+    addTender := func(method string, amount float64) {
+        if _, ok := byMethod[method]; !ok {
+            byMethod[method] = &struct{ Count int; Amount float64 }{}
+        }
+        byMethod[method].Count++
+        byMethod[method].Amount += amount
+    }
+    // discounts per promotion; manual discounts are collected under id 0
+    type promoStat struct{ Name string; Code string; Uses int; Discount float64 }
+    promoStats := map[uint]*promoStat{}
+    var promoOrder []uint
+    addPromo := func(id uint, name, code string, amount float64) {
+        if _, ok := promoStats[id]; !ok {
+            promoStats[id] = &promoStat{Name: name, Code: code}
+            promoOrder = append(promoOrder, id)
+        }
+        promoStats[id].Uses++
+        promoStats[id].Discount += amount
+    }
+    var itemsWithoutCost int
+    menuCount := map[uint]*struct{ Name string; Count int; Revenue float64; Cost float64 }{}
+
+    // voids and refunds of the day's sales; the sales themselves are reported net of them
+    var voidedAmount, refundedAmount float64
+    var voidCount, refundCount int
+    // every numbered sale of the day, whatever became of it, so the numbers can be checked
+    // for gaps
+    invoices := []map[string]interface{}{}
 
     for _, t := range list {
         if t.CreatedAt.Before(start) || !t.CreatedAt.Before(end) {
             continue
         }
+        if t.InvoiceNumber != nil {
+            invoices = append(invoices, map[string]interface{}{
+                "id": t.ID,
+                "invoice_number": *t.InvoiceNumber,
+                "created_at": t.CreatedAt,
+                "status": t.Status,
+                "payment_method": t.PaymentMethod,
+                "total": t.Total,
+                "refunded_amount": t.RefundedAmount,
+            })
+        }
+        for _, r := range t.Refunds {
+            if r.Kind == model.RefundVoid {
+                voidCount++
+                voidedAmount += r.Amount
+            } else {
+                refundCount++
+                refundedAmount += r.Amount
+            }
+        }
+        if !countsAsSale(&t) {
+            continue
+        }
+        // share of the sale that was not given back
+        kept := 1.0
+        if t.Total > 0 {
+            kept = (t.Total - t.RefundedAmount) / t.Total
+        }
         totalTransactions++
-        totalRevenue += t.Total
+        totalRevenue += t.Total - t.RefundedAmount
+        // taxes included in the prices are not sales
+        netSales += (t.Subtotal - t.Discount - t.IncludedTax) * kept
+        totalTax += t.Tax * kept
+        totalService += t.ServiceCharge * kept
+        if len(t.Payments) == 0 {
+            addTender(t.PaymentMethod, t.Total)
+        }
+        for _, p := range t.Payments {
+            addTender(p.Method, p.Amount)
+        }
+        for _, r := range t.Refunds {
+            if _, ok := byMethod[r.Method]; ok {
+                byMethod[r.Method].Amount -= r.Amount
+            }
+        }
+        manual := t.Discount
+        for _, p := range t.Promotions {
+            id := uint(0)
+            if p.PromotionID != nil {
+                id = *p.PromotionID
+            }
+            addPromo(id, p.Name, p.Code, p.Amount)
+            manual -= p.Amount
+        }
+        if manual > 0.005 {
+            addPromo(0, "Manual discount", "", manual)
+        }
         for _, it := range t.Items {
+            it.Quantity -= it.RefundedQuantity
+            if it.Quantity <= 0 {
+                continue
+            }
             totalItems += it.Quantity
             mid := uint(0)
             if it.MenuID != nil {
                 mid = *it.MenuID
             }
             if _, ok := menuCount[mid]; !ok {
-                menuCount[mid] = &struct{ Name string; Count int; Revenue float64 }{Name: it.Menu.Name}
+                menuCount[mid] = &struct{ Name string; Count int; Revenue float64; Cost float64 }{Name: it.Menu.Name}
             }
             menuCount[mid].Count += it.Quantity
             menuCount[mid].Revenue += float64(it.Quantity) * it.Price
+            menuCount[mid].Cost += float64(it.Quantity) * it.CostPrice
+            totalCost += float64(it.Quantity) * it.CostPrice
+            if it.CostPrice == 0 {
+                itemsWithoutCost += it.Quantity
+            }
         }
     }
 
     best := []map[string]interface{}{}
     for k, v := range menuCount {
-        best = append(best, map[string]interface{}{"id": k, "name": v.Name, "count": v.Count, "revenue": v.Revenue})
+        profit := roundMoney(v.Revenue - v.Cost)
+        best = append(best, map[string]interface{}{
+            "id": k,
+            "name": v.Name,
+            "count": v.Count,
+            "revenue": v.Revenue,
+            "cost": roundMoney(v.Cost),
+            "gross_profit": profit,
+            "margin": marginPercent(profit, v.Revenue),
+        })
     }
+    sort.Slice(best, func(i, j int) bool {
+        return best[i]["count"].(int) > best[j]["count"].(int)
+    })
 
+    promotions := []map[string]interface{}{}
+    for _, id := range promoOrder {
+        p := promoStats[id]
+        promotions = append(promotions, map[string]interface{}{
+            "id": id,
+            "name": p.Name,
+            "code": p.Code,
+            "uses": p.Uses,
+            "discount": roundMoney(p.Discount),
+        })
+    }
+
+    payments := []map[string]interface{}{}
+    for method, v := range byMethod {
+        payments = append(payments, map[string]interface{}{
+            "method": method,
+            "count": v.Count,
+            "amount": roundMoney(v.Amount),
+        })
+    }
+    sort.Slice(payments, func(i, j int) bool {
+        return payments[i]["amount"].(float64) > payments[j]["amount"].(float64)
+    })
+
+    kitchen, err := s.kitchenStats(start, end)
+    if err != nil {
+        return nil, err
+    }
+    sort.Slice(invoices, func(i, j int) bool {
+        return invoices[i]["id"].(uint) < invoices[j]["id"].(uint)
+    })
+    var firstInvoice, lastInvoice string
+    if len(invoices) > 0 {
+        firstInvoice = invoices[0]["invoice_number"].(string)
+        lastInvoice = invoices[len(invoices)-1]["invoice_number"].(string)
+    }
+
+    grossProfit := roundMoney(netSales - totalCost)
     return map[string]interface{}{
         "date": start.Format("2006-01-02"),
         "total_revenue": totalRevenue,
         "total_transactions": totalTransactions,
         "total_items": totalItems,
+        "net_sales": roundMoney(netSales),
+        "total_tax": roundMoney(totalTax),
+        "total_service_charge": roundMoney(totalService),
+        "voids": voidCount,
+        "voided_amount": roundMoney(voidedAmount),
+        "refunds": refundCount,
+        "refunded_amount": roundMoney(refundedAmount),
+        "total_cost": roundMoney(totalCost),
+        "gross_profit": grossProfit,
+        "margin": marginPercent(grossProfit, netSales),
+        "items_without_cost": itemsWithoutCost,
         "best_sellers": best,
+        "promotions": promotions,
+        "payments": payments,
+        "kitchen": kitchen,
+        "first_invoice": firstInvoice,
+        "last_invoice": lastInvoice,
+        "invoices": invoices,
     }, nil
 }
 
+// kitchenStats returns the prep times per station of the tickets created in [start, end):
+// wait is queued until preparing, prep is preparing until ready and total is queued until
+// ready. Averages only count tickets that reached the state (skipped states count as 0).
+func (s *reportService) kitchenStats(start, end time.Time) ([]map[string]interface{}, error) {
+    tickets, err := s.kitchenRepo.ListTicketsBetween(start, end)
+    if err != nil {
+        return nil, err
+    }
+    type stationStat struct{
+        Name                    string
+        Tickets, Started, Ready int
+        Wait, Prep, Total       time.Duration
+    }
+    stats := map[uint]*stationStat{}
+    var order []uint
+    for _, k := range tickets {
+        if k.Status == model.TicketCancelled {
+            continue
+        }
+        st, ok := stats[k.StationID]
+        if !ok {
+            st = &stationStat{Name: k.StationName}
+            stats[k.StationID] = st
+            order = append(order, k.StationID)
+        }
+        st.Tickets++
+        if k.StartedAt != nil {
+            st.Started++
+            st.Wait += k.StartedAt.Sub(k.CreatedAt)
+        }
+        if k.ReadyAt != nil {
+            st.Ready++
+            st.Total += k.ReadyAt.Sub(k.CreatedAt)
+            if k.StartedAt != nil {
+                st.Prep += k.ReadyAt.Sub(*k.StartedAt)
+            }
+        }
+    }
+    avgMinutes := func(d time.Duration, n int) float64 {
+        if n == 0 {
+            return 0
+        }
+        return roundMoney(d.Minutes() / float64(n))
+    }
+    res := make([]map[string]interface{}, 0, len(order))
+    for _, id := range order {
+        st := stats[id]
+        res = append(res, map[string]interface{}{
+            "station_id": id,
+            "station": st.Name,
+            "tickets": st.Tickets,
+            "ready": st.Ready,
+            "avg_wait_minutes": avgMinutes(st.Wait, st.Started),
+            "avg_prep_minutes": avgMinutes(st.Prep, st.Ready),
+            "avg_total_minutes": avgMinutes(st.Total, st.Ready),
+        })
+    }
+    return res, nil
+}
+
+// countsAsSale leaves out transactions still waiting for payment, cancelled or fully voided
+// or refunded (rows stored before statuses existed have none and are sales)
+func countsAsSale(t *model.Transaction) bool {
+    switch t.Status {
+    case "", model.TransactionCompleted, model.TransactionPartiallyVoided, model.TransactionPartiallyRefunded:
+        return true
+    }
+    return false
+}
+
 // Aggregate returns revenue per day for the last `days` days (including today)
 func (s *reportService) Aggregate(days int) ([]map[string]interface{}, error) {
     list, err := s.txRepo.List()
@@ -92,10 +329,10 @@ func (s *reportService) Aggregate(days int) ([]map[string]interface{}, error) {
         var revenue float64
         var txCount int
         for _, t := range list {
-            if t.CreatedAt.Before(start) || !t.CreatedAt.Before(end) {
+            if t.CreatedAt.Before(start) || !t.CreatedAt.Before(end) || !countsAsSale(&t) {
                 continue
             }
-            revenue += t.Total
+            revenue += t.Total - t.RefundedAmount
             txCount++
         }
         res = append(res, map[string]interface{}{
@@ -127,6 +364,20 @@ func (s *reportService) ExportExcel(date time.Time) ([]byte, error) {
     f.SetCellValue(sheet, "B3", daily["total_transactions"])
     f.SetCellValue(sheet, "A4", "Total Items")
     f.SetCellValue(sheet, "B4", daily["total_items"])
+    f.SetCellValue(sheet, "A5", "Net Sales")
+    f.SetCellValue(sheet, "B5", daily["net_sales"])
+    f.SetCellValue(sheet, "A6", "Cost of Goods Sold")
+    f.SetCellValue(sheet, "B6", daily["total_cost"])
+    f.SetCellValue(sheet, "A7", "Gross Profit")
+    f.SetCellValue(sheet, "B7", daily["gross_profit"])
+    f.SetCellValue(sheet, "A8", "Gross Margin (%)")
+    f.SetCellValue(sheet, "B8", daily["margin"])
+    f.SetCellValue(sheet, "A9", "Items Without Cost")
+    f.SetCellValue(sheet, "B9", daily["items_without_cost"])
+    f.SetCellValue(sheet, "A10", "Voided Amount")
+    f.SetCellValue(sheet, "B10", daily["voided_amount"])
+    f.SetCellValue(sheet, "A11", "Refunded Amount")
+    f.SetCellValue(sheet, "B11", daily["refunded_amount"])
 
     // Best sellers sheet
     bsSheet := "Best Sellers"
@@ -135,6 +386,9 @@ func (s *reportService) ExportExcel(date time.Time) ([]byte, error) {
     f.SetCellValue(bsSheet, "B1", "Name")
     f.SetCellValue(bsSheet, "C1", "Count")
     f.SetCellValue(bsSheet, "D1", "Revenue")
+    f.SetCellValue(bsSheet, "E1", "Cost")
+    f.SetCellValue(bsSheet, "F1", "Gross Profit")
+    f.SetCellValue(bsSheet, "G1", "Margin (%)")
     best, _ := daily["best_sellers"].([]map[string]interface{})
     // fallback: try casting from []interface{}
     if best == nil {
@@ -153,6 +407,68 @@ func (s *reportService) ExportExcel(date time.Time) ([]byte, error) {
         f.SetCellValue(bsSheet, fmt.Sprintf("B%d", row), b["name"])
         f.SetCellValue(bsSheet, fmt.Sprintf("C%d", row), b["count"])
         f.SetCellValue(bsSheet, fmt.Sprintf("D%d", row), b["revenue"])
+        f.SetCellValue(bsSheet, fmt.Sprintf("E%d", row), b["cost"])
+        f.SetCellValue(bsSheet, fmt.Sprintf("F%d", row), b["gross_profit"])
+        f.SetCellValue(bsSheet, fmt.Sprintf("G%d", row), b["margin"])
+        row++
+    }
+
+    // Payments sheet: revenue per tender
+    paySheet := "Payments"
+    f.NewSheet(paySheet)
+    f.SetCellValue(paySheet, "A1", "Method")
+    f.SetCellValue(paySheet, "B1", "Count")
+    f.SetCellValue(paySheet, "C1", "Amount")
+    payments, _ := daily["payments"].([]map[string]interface{})
+    row = 2
+    for _, p := range payments {
+        f.SetCellValue(paySheet, fmt.Sprintf("A%d", row), p["method"])
+        f.SetCellValue(paySheet, fmt.Sprintf("B%d", row), p["count"])
+        f.SetCellValue(paySheet, fmt.Sprintf("C%d", row), p["amount"])
+        row++
+    }
+
+    // Invoices sheet: every numbered sale of the day
+    invSheet := "Invoices"
+    f.NewSheet(invSheet)
+    f.SetCellValue(invSheet, "A1", "Invoice Number")
+    f.SetCellValue(invSheet, "B1", "Time")
+    f.SetCellValue(invSheet, "C1", "Status")
+    f.SetCellValue(invSheet, "D1", "Payment Method")
+    f.SetCellValue(invSheet, "E1", "Total")
+    f.SetCellValue(invSheet, "F1", "Refunded")
+    invoices, _ := daily["invoices"].([]map[string]interface{})
+    row = 2
+    for _, inv := range invoices {
+        f.SetCellValue(invSheet, fmt.Sprintf("A%d", row), inv["invoice_number"])
+        if at, ok := inv["created_at"].(time.Time); ok {
+            f.SetCellValue(invSheet, fmt.Sprintf("B%d", row), at.Format("15:04:05"))
+        }
+        f.SetCellValue(invSheet, fmt.Sprintf("C%d", row), inv["status"])
+        f.SetCellValue(invSheet, fmt.Sprintf("D%d", row), inv["payment_method"])
+        f.SetCellValue(invSheet, fmt.Sprintf("E%d", row), inv["total"])
+        f.SetCellValue(invSheet, fmt.Sprintf("F%d", row), inv["refunded_amount"])
+        row++
+    }
+
+    // Kitchen sheet: prep times per station
+    kitchenSheet := "Kitchen"
+    f.NewSheet(kitchenSheet)
+    f.SetCellValue(kitchenSheet, "A1", "Station")
+    f.SetCellValue(kitchenSheet, "B1", "Tickets")
+    f.SetCellValue(kitchenSheet, "C1", "Ready")
+    f.SetCellValue(kitchenSheet, "D1", "Avg Wait (min)")
+    f.SetCellValue(kitchenSheet, "E1", "Avg Prep (min)")
+    f.SetCellValue(kitchenSheet, "F1", "Avg Total (min)")
+    kitchen, _ := daily["kitchen"].([]map[string]interface{})
+    row = 2
+    for _, k := range kitchen {
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("A%d", row), k["station"])
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("B%d", row), k["tickets"])
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("C%d", row), k["ready"])
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("D%d", row), k["avg_wait_minutes"])
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("E%d", row), k["avg_prep_minutes"])
+        f.SetCellValue(kitchenSheet, fmt.Sprintf("F%d", row), k["avg_total_minutes"])
         row++
     }
 
@@ -230,6 +546,11 @@ func (s *reportService) ExportPDF(date time.Time) ([]byte, error) {
     pdf.Ln(7)
     pdf.CellFormat(60, 7, "Periode", "1", 0, "L", true, 0, "")
     pdf.CellFormat(130, 7, ": Harian (Daily Report)", "1", 0, "L", false, 0, "")
+    if first, _ := daily["first_invoice"].(string); first != "" {
+        pdf.Ln(7)
+        pdf.CellFormat(60, 7, "Nomor Faktur", "1", 0, "L", true, 0, "")
+        pdf.CellFormat(130, 7, fmt.Sprintf(": %s s/d %s (%d faktur)", first, daily["last_invoice"], len(daily["invoices"].([]map[string]interface{}))), "1", 0, "L", false, 0, "")
+    }
     pdf.Ln(12)
     
     // Summary section
@@ -275,7 +596,49 @@ func (s *reportService) ExportPDF(date time.Time) ([]byte, error) {
     pdf.CellFormat(95, 8, "Rata-rata per Transaksi (Rp)", "1", 0, "L", true, 0, "")
     pdf.SetFont("Helvetica", "B", 11)
     pdf.CellFormat(95, 8, fmt.Sprintf("Rp %.2f", avgPerTx), "1", 0, "R", false, 0, "")
-    pdf.Ln(14)
+    pdf.Ln(8)
+
+    // Gross profit rows
+    for _, r := range []struct{ Label, Value string }{
+        {"Penjualan Bersih (Rp)", fmt.Sprintf("Rp %.2f", daily["net_sales"])},
+        {"Harga Pokok Penjualan (Rp)", fmt.Sprintf("Rp %.2f", daily["total_cost"])},
+        {"Laba Kotor (Rp)", fmt.Sprintf("Rp %.2f", daily["gross_profit"])},
+        {"Margin Laba Kotor", fmt.Sprintf("%.2f%%", daily["margin"])},
+        {"Void (Rp)", fmt.Sprintf("Rp %.2f", daily["voided_amount"])},
+        {"Refund (Rp)", fmt.Sprintf("Rp %.2f", daily["refunded_amount"])},
+    } {
+        pdf.SetFont("Helvetica", "", 11)
+        pdf.CellFormat(95, 8, r.Label, "1", 0, "L", true, 0, "")
+        pdf.SetFont("Helvetica", "B", 11)
+        pdf.CellFormat(95, 8, r.Value, "1", 0, "R", false, 0, "")
+        pdf.Ln(8)
+    }
+    if n, ok := daily["items_without_cost"].(int); ok && n > 0 {
+        pdf.SetFont("Helvetica", "I", 9)
+        pdf.CellFormat(0, 6, fmt.Sprintf("* %d item terjual tanpa harga pokok, laba kotor bisa lebih kecil", n), "", 0, "L", false, 0, "")
+        pdf.Ln(6)
+    }
+    // revenue per payment method
+    if payments, ok := daily["payments"].([]map[string]interface{}); ok {
+        for _, p := range payments {
+            pdf.SetFont("Helvetica", "", 11)
+            pdf.CellFormat(95, 8, fmt.Sprintf("Pembayaran %v (%v)", p["method"], p["count"]), "1", 0, "L", true, 0, "")
+            pdf.SetFont("Helvetica", "B", 11)
+            pdf.CellFormat(95, 8, fmt.Sprintf("Rp %.2f", p["amount"]), "1", 0, "R", false, 0, "")
+            pdf.Ln(8)
+        }
+    }
+    // prep time per kitchen station
+    if kitchen, ok := daily["kitchen"].([]map[string]interface{}); ok {
+        for _, k := range kitchen {
+            pdf.SetFont("Helvetica", "", 11)
+            pdf.CellFormat(95, 8, fmt.Sprintf("Dapur %v (%v tiket)", k["station"], k["tickets"]), "1", 0, "L", true, 0, "")
+            pdf.SetFont("Helvetica", "B", 11)
+            pdf.CellFormat(95, 8, fmt.Sprintf("rata-rata %.1f menit", k["avg_total_minutes"]), "1", 0, "R", false, 0, "")
+            pdf.Ln(8)
+        }
+    }
+    pdf.Ln(6)
     
     // Best sellers section
     pdf.SetFont("Helvetica", "B", 12)
@@ -335,6 +698,46 @@ func (s *reportService) ExportPDF(date time.Time) ([]byte, error) {
         pdf.Ln(7)
     }
     
+    // Gross profit per menu
+    if len(best) > 0 {
+        pdf.Ln(8)
+        pdf.SetFont("Helvetica", "B", 12)
+        pdf.SetFillColor(70, 130, 180)
+        pdf.SetTextColor(255, 255, 255)
+        pdf.CellFormat(0, 9, "LABA KOTOR PER MENU", "1", 0, "C", true, 0, "")
+        pdf.Ln(9)
+        pdf.SetTextColor(0, 0, 0)
+
+        pdf.SetFont("Helvetica", "B", 10)
+        pdf.SetFillColor(220, 220, 220)
+        pdf.CellFormat(70, 8, "Nama Menu", "1", 0, "L", true, 0, "")
+        pdf.CellFormat(35, 8, "Pendapatan (Rp)", "1", 0, "R", true, 0, "")
+        pdf.CellFormat(30, 8, "HPP (Rp)", "1", 0, "R", true, 0, "")
+        pdf.CellFormat(35, 8, "Laba Kotor (Rp)", "1", 0, "R", true, 0, "")
+        pdf.CellFormat(20, 8, "Margin", "1", 0, "R", true, 0, "")
+        pdf.Ln(8)
+
+        pdf.SetFont("Helvetica", "", 10)
+        for i, b := range best {
+            name, _ := b["name"].(string)
+            revenue, _ := b["revenue"].(float64)
+            cost, _ := b["cost"].(float64)
+            profit, _ := b["gross_profit"].(float64)
+            margin, _ := b["margin"].(float64)
+            if i%2 == 0 {
+                pdf.SetFillColor(255, 255, 255)
+            } else {
+                pdf.SetFillColor(245, 245, 245)
+            }
+            pdf.CellFormat(70, 7, name, "1", 0, "L", true, 0, "")
+            pdf.CellFormat(35, 7, fmt.Sprintf("%.2f", revenue), "1", 0, "R", true, 0, "")
+            pdf.CellFormat(30, 7, fmt.Sprintf("%.2f", cost), "1", 0, "R", true, 0, "")
+            pdf.CellFormat(35, 7, fmt.Sprintf("%.2f", profit), "1", 0, "R", true, 0, "")
+            pdf.CellFormat(20, 7, fmt.Sprintf("%.1f%%", margin), "1", 0, "R", true, 0, "")
+            pdf.Ln(7)
+        }
+    }
+
     // Visual chart section
     if len(best) > 0 && len(best) <= 10 {
         pdf.Ln(8)
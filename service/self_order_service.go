@@ -0,0 +1,247 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
+)
+
+// upper bounds for a single customer order, guarding the unauthenticated endpoint
+const (
+    selfOrderMaxLines    = 50
+    selfOrderMaxQuantity = 99
+)
+
+type SelfOrderService interface {
+    // Submit places a pending order for the table the token belongs to
+    Submit(token string, req *dto.SelfOrderRequest) (*model.SelfOrder, error)
+    // Status returns an order of the token's table as shown to the customer
+    Status(token string, id uint) (*dto.SelfOrderStatus, error)
+    List(status string) ([]model.SelfOrder, error)
+    GetByID(id uint) (*model.SelfOrder, error)
+    // Accept turns a pending order into a transaction handled by cashierID (returned with
+    // ErrPaymentNotStarted, like Checkout, when its QRIS payment cannot be started)
+    Accept(id uint, cashierID *uint, req *dto.SelfOrderAcceptRequest) (*model.Transaction, error)
+    Reject(id uint, cashierID *uint, reason string) error
+}
+
+type selfOrderService struct{
+    repo     repository.SelfOrderRepository
+    menuRepo repository.MenuRepository
+    tables   TableService
+    txSvc    TransactionService
+    kitchen  KitchenService
+}
+
+func NewSelfOrderService(r repository.SelfOrderRepository, menuRepo repository.MenuRepository, tables TableService, txSvc TransactionService, kitchen KitchenService) SelfOrderService {
+    return &selfOrderService{repo: r, menuRepo: menuRepo, tables: tables, txSvc: txSvc, kitchen: kitchen}
+}
+
+func (s *selfOrderService) Submit(token string, req *dto.SelfOrderRequest) (*model.SelfOrder, error) {
+    table, err := s.tables.Resolve(token)
+    if err != nil {
+        return nil, err
+    }
+    if len(req.Items) == 0 {
+        return nil, validationErrorf("order has no items")
+    }
+    if len(req.Items) > selfOrderMaxLines {
+        return nil, validationErrorf("an order can have at most %d lines", selfOrderMaxLines)
+    }
+    pending, err := s.repo.CountPending(table.ID)
+    if err != nil {
+        return nil, err
+    }
+    if pending >= int64(config.SelfOrderMaxPending()) {
+        return nil, conflictErrorf("this table already has %d orders waiting for the cashier", pending)
+    }
+
+    o := &model.SelfOrder{
+        TableID:      table.ID,
+        CustomerName: truncate(strings.TrimSpace(req.CustomerName), 100),
+        Note:         truncate(strings.TrimSpace(req.Note), 500),
+        Status:       model.SelfOrderPending,
+    }
+    priceReq := &dto.TransactionCreateRequest{}
+    for _, it := range req.Items {
+        if it.Quantity <= 0 || it.Quantity > selfOrderMaxQuantity {
+            return nil, validationErrorf("quantity must be between 1 and %d", selfOrderMaxQuantity)
+        }
+        m, err := s.menuRepo.GetByID(it.MenuID)
+        if err != nil {
+            return nil, err
+        }
+        if m == nil {
+            return nil, validationErrorf("menu id %d not found", it.MenuID)
+        }
+        if !m.IsAvailable {
+            return nil, validationErrorf("%s is not available", m.Name)
+        }
+        mid := m.ID
+        o.Items = append(o.Items, model.SelfOrderItem{
+            MenuID:   &mid,
+            Quantity: it.Quantity,
+            Note:     truncate(strings.TrimSpace(it.Note), 255),
+        })
+        priceReq.Items = append(priceReq.Items, dto.TransactionItemDTO{MenuID: m.ID, Quantity: it.Quantity})
+    }
+    // the estimate uses the checkout pricing: price rules, taxes and service charges
+    quote, err := s.txSvc.Quote(priceReq)
+    if err != nil {
+        return nil, err
+    }
+    for i := range o.Items {
+        o.Items[i].Price = quote.Items[i].Price
+    }
+    o.Total = quote.Total
+    if err := s.repo.Create(o); err != nil {
+        return nil, err
+    }
+    o.Table = *table
+
+    notif := map[string]interface{}{
+        "type":          "self_order_created",
+        "id":            o.ID,
+        "table_id":      table.ID,
+        "table_name":    table.Name,
+        "customer_name": o.CustomerName,
+        "items":         len(o.Items),
+        "total":         o.Total,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+    return o, nil
+}
+
+func (s *selfOrderService) Status(token string, id uint) (*dto.SelfOrderStatus, error) {
+    table, err := s.tables.Resolve(token)
+    if err != nil {
+        return nil, err
+    }
+    o, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    // orders of other tables are not visible through this token
+    if o == nil || o.TableID != table.ID {
+        return nil, ErrNotFound
+    }
+    return &dto.SelfOrderStatus{ID: o.ID, Status: o.Status, Total: o.Total, RejectReason: o.RejectReason}, nil
+}
+
+func (s *selfOrderService) List(status string) ([]model.SelfOrder, error) {
+    return s.repo.List(status)
+}
+
+func (s *selfOrderService) GetByID(id uint) (*model.SelfOrder, error) {
+    return s.repo.GetByID(id)
+}
+
+func (s *selfOrderService) Accept(id uint, cashierID *uint, req *dto.SelfOrderAcceptRequest) (*model.Transaction, error) {
+    var t *model.Transaction
+    var tickets []model.KitchenTicket
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        orders := s.repo.WithTx(tx)
+        o, err := orders.GetByID(id)
+        if err != nil {
+            return err
+        }
+        if o == nil {
+            return ErrNotFound
+        }
+        if o.Status != model.SelfOrderPending {
+            return conflictErrorf("self order %d is already %s", id, o.Status)
+        }
+
+        checkout := dto.TransactionCreateRequest{
+            PaymentMethod: req.PaymentMethod,
+            AmountPaid:    req.AmountPaid,
+            Discount:      req.Discount,
+            VoucherCodes:  req.VoucherCodes,
+            Payments:      req.Payments,
+        }
+        for _, it := range o.Items {
+            if it.MenuID == nil {
+                return validationErrorf("a menu of this order was deleted, reject it and ask the customer to order again")
+            }
+            checkout.Items = append(checkout.Items, dto.TransactionItemDTO{MenuID: *it.MenuID, Quantity: it.Quantity, Note: it.Note})
+        }
+        t, err = s.txSvc.CheckoutTx(tx, &checkout, cashierID)
+        if err != nil {
+            return err
+        }
+        ok, err := orders.Transition(id, model.SelfOrderPending, map[string]interface{}{
+            "status":         model.SelfOrderAccepted,
+            "transaction_id": t.ID,
+            "handled_by":     cashierID,
+        })
+        if err != nil {
+            return err
+        }
+        if !ok {
+            return conflictErrorf("self order %d was handled by someone else", id)
+        }
+        tickets, err = s.kitchen.TicketsForTransactionTx(tx, t, o.Table.Name)
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := s.txSvc.StartPayment(t); err != nil {
+        return t, err
+    }
+    s.txSvc.Notify(t)
+    s.kitchen.Announce(tickets)
+    s.notifyStatus(id, model.SelfOrderAccepted)
+    return t, nil
+}
+
+func (s *selfOrderService) Reject(id uint, cashierID *uint, reason string) error {
+    o, err := s.repo.GetByID(id)
+    if err != nil {
+        return err
+    }
+    if o == nil {
+        return ErrNotFound
+    }
+    ok, err := s.repo.Transition(id, model.SelfOrderPending, map[string]interface{}{
+        "status":        model.SelfOrderRejected,
+        "reject_reason": truncate(strings.TrimSpace(reason), 255),
+        "handled_by":    cashierID,
+    })
+    if err != nil {
+        return err
+    }
+    if !ok {
+        return conflictErrorf("self order %d was already handled", id)
+    }
+    s.notifyStatus(id, model.SelfOrderRejected)
+    return nil
+}
+
+// notifyStatus tells other cashier screens to drop a handled order from their queue
+func (s *selfOrderService) notifyStatus(id uint, status string) {
+    notif := map[string]interface{}{
+        "type":   "self_order_updated",
+        "id":     id,
+        "status": status,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+}
+
+func truncate(s string, n int) string {
+    r := []rune(s)
+    if len(r) <= n {
+        return s
+    }
+    return string(r[:n])
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+// SyncService tells offline devices what changed in the catalog since their last sync
+type SyncService interface {
+    // Catalog returns the changes since the given time, or the full catalog when since is
+    // nil or older than the remembered deletions
+    Catalog(since *time.Time) (*CatalogChanges, error)
+    // PurgeTombstones forgets deletions older than the retention
+    PurgeTombstones() (int64, error)
+}
+
+// CatalogChanges is what a device needs to price sales offline. Menus and categories are
+// sent when they changed, the rules, promotions and tags (short lists) always in full.
+type CatalogChanges struct {
+    // ServerTime is the since to send on the next sync
+    ServerTime   time.Time             `json:"server_time"`
+    // Full means the device has to replace its catalog instead of merging the changes
+    Full         bool                  `json:"full"`
+    Version      uint64                `json:"version"`
+    Menus        []model.Menu          `json:"menus"`
+    Categories   []model.Category      `json:"categories"`
+    DeletedMenus []uint                `json:"deleted_menus"`
+    Tags         []repository.TagUsage `json:"tags"`
+    PriceRules   []model.PriceRule     `json:"price_rules"`
+    TaxRules     []model.TaxRule       `json:"tax_rules"`
+    Promotions   []model.Promotion     `json:"promotions"`
+}
+
+type syncService struct{
+    repo       repository.SyncRepository
+    catalog    CatalogService
+    tags       TagService
+    pricing    PricingService
+    taxes      TaxService
+    promotions PromotionService
+}
+
+func NewSyncService(r repository.SyncRepository, catalog CatalogService, tags TagService, pricing PricingService, taxes TaxService, promotions PromotionService) SyncService {
+    return &syncService{repo: r, catalog: catalog, tags: tags, pricing: pricing, taxes: taxes, promotions: promotions}
+}
+
+func (s *syncService) Catalog(since *time.Time) (*CatalogChanges, error) {
+    // taken before reading so nothing changed meanwhile is missed next time
+    now := time.Now()
+    if since != nil && since.Before(now.Add(-config.SyncTombstoneRetention())) {
+        since = nil
+    }
+    ch := &CatalogChanges{ServerTime: now, Full: since == nil, DeletedMenus: []uint{}}
+    st, err := s.catalog.Version()
+    if err != nil {
+        return nil, err
+    }
+    ch.Version = st.Version
+    if ch.Menus, err = s.repo.MenusChangedSince(since); err != nil {
+        return nil, err
+    }
+    if ch.Categories, err = s.repo.CategoriesChangedSince(since); err != nil {
+        return nil, err
+    }
+    if since != nil {
+        if ch.DeletedMenus, err = s.repo.DeletedSince(model.TombstoneMenu, *since); err != nil {
+            return nil, err
+        }
+    }
+    if ch.Tags, err = s.tags.List(); err != nil {
+        return nil, err
+    }
+    if ch.PriceRules, err = s.pricing.ListRules(); err != nil {
+        return nil, err
+    }
+    if ch.TaxRules, err = s.taxes.ListRules(); err != nil {
+        return nil, err
+    }
+    if ch.Promotions, err = s.promotions.List(); err != nil {
+        return nil, err
+    }
+    return ch, nil
+}
+
+func (s *syncService) PurgeTombstones() (int64, error) {
+    return s.repo.PurgeTombstones(time.Now().Add(-config.SyncTombstoneRetention()))
+}
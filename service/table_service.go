@@ -0,0 +1,150 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+type TableService interface {
+    List() ([]model.DiningTable, error)
+    GetByID(id uint) (*model.DiningTable, error)
+    Create(req *dto.TableRequest) (*model.DiningTable, error)
+    Update(id uint, req *dto.TableRequest) (*model.DiningTable, error)
+    Delete(id uint) error
+    // Link returns the signed token and ordering URL printed on the table
+    Link(t *model.DiningTable) dto.TableLink
+    // RotateToken invalidates the printed QR code of a table and returns the new link
+    RotateToken(id uint) (*dto.TableLink, error)
+    // QRCode renders the ordering URL of a table as a PNG
+    QRCode(id uint, size int) ([]byte, error)
+    // Resolve returns the active table a customer token belongs to
+    Resolve(token string) (*model.DiningTable, error)
+}
+
+type tableService struct{
+    repo repository.TableRepository
+}
+
+func NewTableService(r repository.TableRepository) TableService {
+    return &tableService{repo: r}
+}
+
+func (s *tableService) List() ([]model.DiningTable, error) {
+    return s.repo.List()
+}
+
+func (s *tableService) GetByID(id uint) (*model.DiningTable, error) {
+    return s.repo.GetByID(id)
+}
+
+func (s *tableService) Create(req *dto.TableRequest) (*model.DiningTable, error) {
+    t := &model.DiningTable{TokenVersion: 1, IsActive: true}
+    if err := s.apply(t, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Create(t); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+func (s *tableService) Update(id uint, req *dto.TableRequest) (*model.DiningTable, error) {
+    t, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil {
+        return nil, ErrNotFound
+    }
+    if err := s.apply(t, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(t); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+func (s *tableService) apply(t *model.DiningTable, req *dto.TableRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return validationErrorf("name is required")
+    }
+    if req.Seats < 0 {
+        return validationErrorf("seats must not be negative")
+    }
+    other, err := s.repo.FindByName(name)
+    if err != nil {
+        return err
+    }
+    if other != nil && other.ID != t.ID {
+        return validationErrorf("table %q already exists", name)
+    }
+    t.Name = name
+    t.Seats = req.Seats
+    if req.IsActive != nil {
+        t.IsActive = *req.IsActive
+    }
+    return nil
+}
+
+func (s *tableService) Delete(id uint) error {
+    return s.repo.Delete(id)
+}
+
+func (s *tableService) Link(t *model.DiningTable) dto.TableLink {
+    token := utils.SignTableToken(config.TableTokenSecret(), t.ID, t.TokenVersion)
+    return dto.TableLink{TableID: t.ID, Name: t.Name, Token: token, URL: config.SelfOrderBaseURL() + "/" + token}
+}
+
+func (s *tableService) RotateToken(id uint) (*dto.TableLink, error) {
+    t, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil {
+        return nil, ErrNotFound
+    }
+    t.TokenVersion++
+    if err := s.repo.Update(t); err != nil {
+        return nil, err
+    }
+    link := s.Link(t)
+    return &link, nil
+}
+
+func (s *tableService) QRCode(id uint, size int) ([]byte, error) {
+    t, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil {
+        return nil, ErrNotFound
+    }
+    if size < 128 || size > 2048 {
+        size = 512
+    }
+    return qrcode.Encode(s.Link(t).URL, qrcode.Medium, size)
+}
+
+func (s *tableService) Resolve(token string) (*model.DiningTable, error) {
+    id, version, err := utils.ParseTableToken(config.TableTokenSecret(), token)
+    if err != nil {
+        return nil, ErrNotFound
+    }
+    t, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    // codes of deleted, disabled or rotated tables are treated as unknown
+    if t == nil || !t.IsActive || t.TokenVersion != version {
+        return nil, ErrNotFound
+    }
+    return t, nil
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+)
+
+type TagService interface {
+    List() ([]repository.TagUsage, error)
+    Create(name string) (*model.Tag, error)
+    // Update renames a tag (its slug follows the name)
+    Update(id uint, name string) (*model.Tag, error)
+    // Delete removes the tag from all menus
+    Delete(id uint) error
+    // Resolve returns the tags with the given names, creating the missing ones
+    Resolve(names []string) ([]model.Tag, error)
+}
+
+type tagService struct{
+    repo    repository.TagRepository
+    catalog CatalogService
+}
+
+func NewTagService(r repository.TagRepository, catalog CatalogService) TagService {
+    return &tagService{repo: r, catalog: catalog}
+}
+
+func (s *tagService) List() ([]repository.TagUsage, error) {
+    return s.repo.List()
+}
+
+func (s *tagService) Create(name string) (*model.Tag, error) {
+    t, slug, err := s.validate(0, name)
+    if err != nil {
+        return nil, err
+    }
+    if t != nil {
+        return nil, validationErrorf("tag %q already exists", t.Name)
+    }
+    t = &model.Tag{Name: strings.TrimSpace(name), Slug: slug}
+    if err := s.repo.Create(t); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+func (s *tagService) Update(id uint, name string) (*model.Tag, error) {
+    t, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if t == nil {
+        return nil, ErrNotFound
+    }
+    other, slug, err := s.validate(id, name)
+    if err != nil {
+        return nil, err
+    }
+    if other != nil {
+        return nil, validationErrorf("tag %q already exists", other.Name)
+    }
+    t.Name = strings.TrimSpace(name)
+    t.Slug = slug
+    if err := s.repo.Update(t); err != nil {
+        return nil, err
+    }
+    s.catalog.Bump("tag_updated")
+    return t, nil
+}
+
+// validate checks a tag name and returns another tag already using its slug, if any
+func (s *tagService) validate(id uint, name string) (*model.Tag, string, error) {
+    name = strings.TrimSpace(name)
+    slug := utils.Slugify(name)
+    if slug == "" {
+        return nil, "", validationErrorf("tag name is required")
+    }
+    if len([]rune(name)) > 50 {
+        return nil, "", validationErrorf("tag name must be at most 50 characters")
+    }
+    other, err := s.repo.FindBySlug(slug)
+    if err != nil {
+        return nil, "", err
+    }
+    if other != nil && other.ID == id {
+        other = nil
+    }
+    return other, slug, nil
+}
+
+func (s *tagService) Delete(id uint) error {
+    if err := s.repo.Delete(id); err != nil {
+        return err
+    }
+    s.catalog.Bump("tag_deleted")
+    return nil
+}
+
+func (s *tagService) Resolve(names []string) ([]model.Tag, error) {
+    out := []model.Tag{}
+    seen := map[string]bool{}
+    for _, name := range names {
+        slug := utils.Slugify(name)
+        if slug == "" || seen[slug] {
+            continue
+        }
+        seen[slug] = true
+        t, err := s.repo.FindBySlug(slug)
+        if err != nil {
+            return nil, err
+        }
+        if t == nil {
+            if t, err = s.Create(name); err != nil {
+                return nil, err
+            }
+        }
+        out = append(out, *t)
+    }
+    return out, nil
+}
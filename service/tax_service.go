@@ -0,0 +1,164 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+// TaxService holds the configured taxes and service charges and computes them for an order
+type TaxService interface {
+    // Calculate applies the active rules to base (subtotal after discounts)
+    Calculate(base float64) (*TaxBreakdown, error)
+    ListRules() ([]model.TaxRule, error)
+    CreateRule(req *dto.TaxRuleRequest) (*model.TaxRule, error)
+    UpdateRule(id uint, req *dto.TaxRuleRequest) (*model.TaxRule, error)
+    DeleteRule(id uint) error
+}
+
+// TaxBreakdown is the result of applying the tax rules to an order
+type TaxBreakdown struct {
+    Lines         []model.TransactionTax
+    Tax           float64
+    ServiceCharge float64
+    // Included is the part of Tax and ServiceCharge already contained in the base
+    Included      float64
+    // Total is the amount due: the base plus the exclusive rules
+    Total         float64
+}
+
+type taxService struct{
+    repo repository.TaxRuleRepository
+}
+
+func NewTaxService(r repository.TaxRuleRepository) TaxService {
+    return &taxService{repo: r}
+}
+
+func (s *taxService) Calculate(base float64) (*TaxBreakdown, error) {
+    rules, err := s.repo.ListActive()
+    if err != nil {
+        return nil, err
+    }
+    return applyTaxRules(rules, base), nil
+}
+
+// applyTaxRules computes the rules in sequence. Inclusive rules share the base: it is first
+// split into the net amount and their portions. Exclusive rules are charged on the net amount,
+// compound ones also on the exclusive amounts before them.
+func applyTaxRules(rules []model.TaxRule, base float64) *TaxBreakdown {
+    out := &TaxBreakdown{Lines: []model.TransactionTax{}, Total: roundMoney(base)}
+    inclusiveRate := 0.0
+    for _, r := range rules {
+        if r.Inclusive {
+            inclusiveRate += r.Rate
+        }
+    }
+    net := base / (1 + inclusiveRate/100)
+    exclusive := 0.0
+    for _, r := range rules {
+        id := r.ID
+        line := model.TransactionTax{
+            TaxRuleID: &id,
+            Name:      r.Name,
+            Kind:      r.Kind,
+            Rate:      r.Rate,
+            Inclusive: r.Inclusive,
+        }
+        switch {
+        case r.Inclusive:
+            line.Base = roundMoney(base)
+            line.Amount = roundMoney(net * r.Rate / 100)
+            out.Included += line.Amount
+        case r.Compound:
+            line.Base = roundMoney(net + exclusive)
+            line.Amount = roundMoney((net + exclusive) * r.Rate / 100)
+        default:
+            line.Base = roundMoney(net)
+            line.Amount = roundMoney(net * r.Rate / 100)
+        }
+        if !r.Inclusive {
+            exclusive += line.Amount
+        }
+        if r.Kind == model.TaxKindService {
+            out.ServiceCharge += line.Amount
+        } else {
+            out.Tax += line.Amount
+        }
+        out.Lines = append(out.Lines, line)
+    }
+    out.Tax = roundMoney(out.Tax)
+    out.ServiceCharge = roundMoney(out.ServiceCharge)
+    out.Included = roundMoney(out.Included)
+    out.Total = roundMoney(base + exclusive)
+    return out
+}
+
+func (s *taxService) ListRules() ([]model.TaxRule, error) {
+    return s.repo.List()
+}
+
+func (s *taxService) CreateRule(req *dto.TaxRuleRequest) (*model.TaxRule, error) {
+    r := &model.TaxRule{IsActive: true}
+    if err := applyTaxRule(r, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Create(r); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *taxService) UpdateRule(id uint, req *dto.TaxRuleRequest) (*model.TaxRule, error) {
+    r, err := s.repo.GetByID(id)
+    if err != nil {
+        return nil, err
+    }
+    if r == nil {
+        return nil, ErrNotFound
+    }
+    if err := applyTaxRule(r, req); err != nil {
+        return nil, err
+    }
+    if err := s.repo.Update(r); err != nil {
+        return nil, err
+    }
+    return r, nil
+}
+
+func (s *taxService) DeleteRule(id uint) error {
+    return s.repo.Delete(id)
+}
+
+func applyTaxRule(r *model.TaxRule, req *dto.TaxRuleRequest) error {
+    name := strings.TrimSpace(req.Name)
+    if name == "" {
+        return validationErrorf("name is required")
+    }
+    kind := req.Kind
+    if kind == "" {
+        kind = model.TaxKindTax
+    }
+    if kind != model.TaxKindTax && kind != model.TaxKindService {
+        return validationErrorf("kind must be tax or service")
+    }
+    if req.Rate <= 0 || req.Rate > 100 {
+        return validationErrorf("rate must be between 0 and 100")
+    }
+    if req.Inclusive && req.Compound {
+        return validationErrorf("inclusive rules cannot be compound")
+    }
+
+    r.Name = name
+    r.Kind = kind
+    r.Rate = req.Rate
+    r.Inclusive = req.Inclusive
+    r.Compound = req.Compound
+    r.Sequence = req.Sequence
+    if req.IsActive != nil {
+        r.IsActive = *req.IsActive
+    }
+    return nil
+}
@@ -1,32 +1,565 @@
 package service
 
 import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
 	"github.com/IndalAwalaikal/warung-pos/backend/model"
 	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/utils"
+	"gorm.io/gorm"
 )
 
 type TransactionService interface {
     Create(tx *model.Transaction) error
+    // Checkout prices the requested items with the current menu prices, price rules,
+    // promotions and tax rules (client prices and totals are ignored), stores the
+    // transaction with its kitchen tickets and notifies connected clients. When its QRIS
+    // payment cannot be started the cancelled sale is returned with ErrPaymentNotStarted.
+    Checkout(req *dto.TransactionCreateRequest, cashierID *uint) (*model.Transaction, error)
+    // CheckoutTx is Checkout inside the caller's database transaction. Nothing is announced;
+    // call Notify once the surrounding transaction committed.
+    CheckoutTx(tx *gorm.DB, req *dto.TransactionCreateRequest, cashierID *uint) (*model.Transaction, error)
+    // Quote prices an order like Checkout without storing it, so clients can show the
+    // server's subtotal, taxes and total before paying
+    Quote(req *dto.TransactionCreateRequest) (*model.Transaction, error)
+    // StartPayment requests the QRIS codes of a pending_payment transaction from the
+    // payment gateway; call it once the transaction committed (Checkout does)
+    StartPayment(t *model.Transaction) error
+    // Sync stores sales recorded by a device while offline. Each one is priced like Checkout
+    // (with the price rules and promotions of its time of sale) and stored on its own; where
+    // the device charged other prices the sale keeps them and is flagged for review. Sales
+    // already uploaded under the same ClientUUID are reported as duplicates.
+    Sync(batch []dto.SyncTransaction, cashierID *uint) ([]SyncOutcome, error)
+    // Notify pushes a transaction_created event to SSE clients
+    Notify(t *model.Transaction)
     List() ([]model.Transaction, error)
+    // SearchInvoice finds transactions by invoice number or its start (e.g. the day's prefix)
+    SearchInvoice(q string) ([]model.Transaction, error)
+    // ListPriceReview returns the offline sales flagged for a price review
+    ListPriceReview() ([]model.Transaction, error)
     GetByID(id uint) (*model.Transaction, error)
 }
 
 type transactionService struct{
-    repo repository.TransactionRepository
+    repo       repository.TransactionRepository
+    menuRepo   repository.MenuRepository
+    pricing    PricingService
+    taxes      TaxService
+    promotions PromotionService
+    payments   PaymentService
+    kitchen    KitchenService
+    invoices   repository.InvoiceRepository
 }
 
-func NewTransactionService(r repository.TransactionRepository) TransactionService {
-    return &transactionService{repo: r}
+func NewTransactionService(r repository.TransactionRepository, menuRepo repository.MenuRepository, pricing PricingService, taxes TaxService, promotions PromotionService, payments PaymentService, kitchen KitchenService, invoices repository.InvoiceRepository) TransactionService {
+    return &transactionService{repo: r, menuRepo: menuRepo, pricing: pricing, taxes: taxes, promotions: promotions, payments: payments, kitchen: kitchen, invoices: invoices}
 }
 
 func (s *transactionService) Create(tx *model.Transaction) error {
     return s.repo.Create(tx)
 }
 
+func (s *transactionService) Checkout(req *dto.TransactionCreateRequest, cashierID *uint) (*model.Transaction, error) {
+    var t *model.Transaction
+    var tickets []model.KitchenTicket
+    err := repository.Transaction(func(tx *gorm.DB) error {
+        var err error
+        if t, err = s.CheckoutTx(tx, req, cashierID); err != nil {
+            return err
+        }
+        tickets, err = s.kitchen.TicketsForTransactionTx(tx, t, "")
+        return err
+    })
+    if err != nil {
+        return nil, err
+    }
+    if err := s.StartPayment(t); err != nil {
+        return t, err
+    }
+    s.Notify(t)
+    s.kitchen.Announce(tickets)
+    return t, nil
+}
+
+func (s *transactionService) StartPayment(t *model.Transaction) error {
+    if t.Status != model.TransactionPendingPayment {
+        return nil
+    }
+    return s.payments.Start(t)
+}
+
+func (s *transactionService) CheckoutTx(tx *gorm.DB, req *dto.TransactionCreateRequest, cashierID *uint) (*model.Transaction, error) {
+    return s.checkout(tx, req, cashierID, time.Now(), nil)
+}
+
+// checkout prices and stores a sale made at the given time. Offline sales (with a client
+// UUID) keep their time of sale and their QRIS tenders are recorded as paid, since no
+// gateway code could be shown without a connection.
+func (s *transactionService) checkout(tx *gorm.DB, req *dto.TransactionCreateRequest, cashierID *uint, at time.Time, clientUUID *string) (*model.Transaction, error) {
+    t, err := s.build(s.menuRepo.WithTx(tx), req, at, clientUUID != nil)
+    if err != nil {
+        return nil, err
+    }
+    if clientUUID == nil {
+        if err := checkClientTotals(req, t); err != nil {
+            return nil, err
+        }
+    }
+    if err := tender(t); err != nil {
+        return nil, err
+    }
+    t.CashierID = cashierID
+    token, err := utils.NewReceiptToken()
+    if err != nil {
+        return nil, err
+    }
+    t.ReceiptToken = &token
+    // with a payment gateway QRIS tenders wait for its confirmation
+    t.Status = model.TransactionCompleted
+    if clientUUID != nil {
+        now := time.Now()
+        t.ClientUUID = clientUUID
+        t.CreatedAt = at
+        t.SyncedAt = &now
+    } else if s.payments.Online() {
+        for i := range t.Payments {
+            if t.Payments[i].Method == model.PaymentQRIS {
+                t.Payments[i].Status = model.PaymentPending
+                t.Status = model.TransactionPendingPayment
+            }
+        }
+    }
+
+    if err := s.promotions.RedeemTx(tx, t.Promotions); err != nil {
+        return nil, err
+    }
+    // numbered last: the sequence stays locked until the surrounding transaction commits
+    number, err := nextInvoiceNumber(s.invoices.WithTx(tx), at)
+    if err != nil {
+        return nil, err
+    }
+    t.InvoiceNumber = &number
+    if err := s.repo.WithTx(tx).Create(t); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+func (s *transactionService) Quote(req *dto.TransactionCreateRequest) (*model.Transaction, error) {
+    t, err := s.build(s.menuRepo, req, time.Now(), false)
+    if err != nil {
+        return nil, err
+    }
+    // with amounts the quote also shows the change
+    if req.AmountPaid > 0 || len(req.Payments) > 0 {
+        if err := tender(t); err != nil {
+            return nil, err
+        }
+    }
+    return t, nil
+}
+
+// maxItemQuantity bounds the quantity of one order line at the till and on bills, well above
+// any real order; stock and buy-get promotions work unit by unit
+const maxItemQuantity = 999
+
+// build prices an order entirely on the server: menu prices (client prices are ignored) with
+// the price rules in effect at the given time, promotions and the manual discount, then
+// taxes and service charges. With device (offline sales) the device's prices are kept, see
+// keepDevicePrices.
+func (s *transactionService) build(menus repository.MenuRepository, req *dto.TransactionCreateRequest, now time.Time, device bool) (*model.Transaction, error) {
+    prices, err := s.pricing.Snapshot(now)
+    if err != nil {
+        return nil, err
+    }
+    t := &model.Transaction{}
+    var lines []PromoLine
+    sum := 0.0
+    for _, it := range req.Items {
+        if it.Quantity <= 0 || it.Quantity > maxItemQuantity {
+            return nil, validationErrorf("quantity must be between 1 and %d", maxItemQuantity)
+        }
+        m, err := menus.GetByID(it.MenuID)
+        if err != nil {
+            return nil, err
+        }
+        if m == nil {
+            return nil, validationErrorf("menu id %d not found", it.MenuID)
+        }
+        // use server price, and snapshot the cost for margin reports
+        q := prices.Quote(m)
+        mid := it.MenuID
+        t.Items = append(t.Items, model.TransactionItem{
+            MenuID:        &mid,
+            Quantity:      it.Quantity,
+            Price:         q.Price,
+            BasePrice:     q.BasePrice,
+            PriceRuleID:   q.PriceRuleID,
+            PriceRuleName: q.PriceRuleName,
+            CostPrice:     m.CostPrice,
+            Note:          truncate(strings.TrimSpace(it.Note), 255),
+        })
+        lines = append(lines, PromoLine{CategoryID: m.CategoryID, Price: q.Price, Quantity: it.Quantity})
+        sum += float64(it.Quantity) * q.Price
+    }
+    if len(t.Items) == 0 {
+        return nil, validationErrorf("transaction has no items")
+    }
+    t.Subtotal = roundMoney(sum)
+
+    // promotions first, then the manual cashier discount on what is left
+    promos, err := s.promotions.Evaluate(lines, req.VoucherCodes, now)
+    if err != nil {
+        return nil, err
+    }
+    t.Promotions = promos
+    for _, p := range promos {
+        t.Discount += p.Amount
+    }
+    if req.Discount < 0 || req.Discount > t.Subtotal-t.Discount {
+        return nil, validationErrorf("discount must be between 0 and the subtotal after promotions")
+    }
+    t.Discount = roundMoney(t.Discount + req.Discount)
+
+    taxes, err := s.taxes.Calculate(t.Subtotal - t.Discount)
+    if err != nil {
+        return nil, err
+    }
+    t.Taxes = taxes.Lines
+    t.Tax = taxes.Tax
+    t.ServiceCharge = taxes.ServiceCharge
+    t.IncludedTax = taxes.Included
+    t.Total = taxes.Total
+
+    if device {
+        keepDevicePrices(req, t)
+    }
+    if err := preparePayments(t, req); err != nil {
+        return nil, err
+    }
+    return t, nil
+}
+
+// keepDevicePrices applies the item prices and totals an offline device charged where they
+// differ from the server's (e.g. a menu price changed before the device synced its catalog):
+// the sale happened at those prices, so it is stored with them and flagged for review with
+// what the server would have charged. Figures the device left out (0) stay the server's.
+func keepDevicePrices(req *dto.TransactionCreateRequest, t *model.Transaction) {
+    var notes []string
+    subtotal := 0.0
+    for i, it := range req.Items {
+        line := &t.Items[i]
+        if it.Price > 0 && math.Abs(it.Price-line.Price) > totalTolerance {
+            notes = append(notes, fmt.Sprintf("menu %d price: device %.2f, server %.2f", it.MenuID, it.Price, line.Price))
+            line.Price = it.Price
+        }
+        subtotal += float64(line.Quantity) * line.Price
+    }
+    if len(notes) > 0 && req.Subtotal == 0 {
+        t.Subtotal = roundMoney(subtotal)
+    }
+    figures := []struct {
+        name   string
+        device float64
+        server *float64
+    }{
+        {"subtotal", req.Subtotal, &t.Subtotal},
+        {"tax", req.Tax, &t.Tax},
+        {"service_charge", req.ServiceCharge, &t.ServiceCharge},
+    }
+    for _, f := range figures {
+        if f.device != 0 && math.Abs(f.device-*f.server) > totalTolerance {
+            notes = append(notes, fmt.Sprintf("%s: device %.2f, server %.2f", f.name, f.device, *f.server))
+            *f.server = f.device
+        }
+    }
+    // the server's total as charged, with the cash rounding the device may have included
+    serverTotal := t.Total
+    probe := *t
+    probe.Payments, probe.PaymentMethod = nil, ""
+    if preparePayments(&probe, req) == nil {
+        serverTotal = probe.Total
+    }
+    if req.Total != 0 && math.Abs(req.Total-t.Total) > totalTolerance && math.Abs(req.Total-serverTotal) > totalTolerance {
+        notes = append(notes, fmt.Sprintf("total: device %.2f, server %.2f", req.Total, serverTotal))
+        t.Total = req.Total
+    }
+    if len(notes) == 0 {
+        return
+    }
+    t.PriceReview = true
+    t.ServerTotal = &serverTotal
+    t.PriceReviewNote = truncate(strings.Join(notes, "; "), 500)
+}
+
+// roundCash rounds a cash total to unit (no rounding when unit is 0)
+func roundCash(total float64, unit int, mode string) float64 {
+    if unit <= 0 {
+        return total
+    }
+    u := float64(unit)
+    switch mode {
+    case "up":
+        return math.Ceil(total/u) * u
+    case "down":
+        return math.Floor(total/u) * u
+    }
+    return math.Round(total/u) * u
+}
+
+// preparePayments turns the requested tenders (Payments, or PaymentMethod/AmountPaid as a
+// single tender) into t.Payments with their Tendered amounts, sets PaymentMethod and applies
+// cash rounding to the part of the total left for cash
+func preparePayments(t *model.Transaction, req *dto.TransactionCreateRequest) error {
+    tenders := req.Payments
+    if len(tenders) == 0 {
+        method := req.PaymentMethod
+        if method == "" {
+            // default payment method
+            method = model.PaymentCash
+        }
+        tenders = []dto.PaymentDTO{{Method: method, Amount: req.AmountPaid}}
+    }
+    nonCash := 0.0
+    hasCash := false
+    for _, p := range tenders {
+        if p.Method != model.PaymentCash && p.Method != model.PaymentQRIS {
+            return validationErrorf("payment method must be %s or %s", model.PaymentCash, model.PaymentQRIS)
+        }
+        if p.Amount < 0 || (len(tenders) > 1 && p.Amount == 0) {
+            return validationErrorf("each payment needs a positive amount")
+        }
+        if p.Method == model.PaymentCash {
+            hasCash = true
+        } else {
+            nonCash += p.Amount
+        }
+        t.Payments = append(t.Payments, model.Payment{
+            Method:    p.Method,
+            Tendered:  roundMoney(p.Amount),
+            Reference: truncate(strings.TrimSpace(p.Reference), 100),
+            Status:    model.PaymentPaid,
+        })
+        if t.PaymentMethod == "" {
+            t.PaymentMethod = p.Method
+        } else if t.PaymentMethod != p.Method {
+            t.PaymentMethod = model.PaymentSplit
+        }
+    }
+    if nonCash > t.Total+totalTolerance {
+        return validationErrorf("non-cash payments %s exceed the total %s", utils.FormatRupiah(nonCash), utils.FormatRupiah(t.Total))
+    }
+    if hasCash {
+        due := roundMoney(t.Total - nonCash)
+        rounded := roundCash(due, config.CashRounding(), config.CashRoundingMode())
+        t.RoundingAdjustment = roundMoney(rounded - due)
+        t.Total = roundMoney(t.Total + t.RoundingAdjustment)
+    }
+    return nil
+}
+
+// tender checks that the payments cover the total and works out what each settled and the
+// change. Cash must cover what the other tenders leave; non-cash tenders are charged exactly
+// (a single non-cash tender without amount is charged the total).
+func tender(t *model.Transaction) error {
+    if len(t.Payments) == 1 && t.Payments[0].Method != model.PaymentCash && t.Payments[0].Tendered == 0 {
+        t.Payments[0].Tendered = t.Total
+    }
+    nonCash, cash := 0.0, 0.0
+    hasCash := false
+    for _, p := range t.Payments {
+        if p.Method == model.PaymentCash {
+            hasCash = true
+            cash += p.Tendered
+        } else {
+            nonCash += p.Tendered
+        }
+    }
+    due := roundMoney(t.Total - nonCash)
+    if !hasCash && math.Abs(due) > totalTolerance {
+        if len(t.Payments) == 1 {
+            return validationErrorf("%s payments must be exactly the total %s", t.PaymentMethod, utils.FormatRupiah(t.Total))
+        }
+        return validationErrorf("payments %s do not match the total %s", utils.FormatRupiah(nonCash), utils.FormatRupiah(t.Total))
+    }
+    if hasCash && cash < due {
+        return validationErrorf("amount paid %s is less than the total %s", utils.FormatRupiah(cash+nonCash), utils.FormatRupiah(t.Total))
+    }
+    t.Change = 0
+    if hasCash {
+        t.Change = roundMoney(cash - due)
+    }
+    // the change comes out of the cash tenders, the last one first
+    change := t.Change
+    for i := len(t.Payments) - 1; i >= 0; i-- {
+        p := &t.Payments[i]
+        p.Amount = p.Tendered
+        if p.Method == model.PaymentCash && change > 0 {
+            back := math.Min(change, p.Tendered)
+            p.Amount = roundMoney(p.Tendered - back)
+            change = roundMoney(change - back)
+        }
+    }
+    t.AmountPaid = roundMoney(cash + nonCash)
+    return nil
+}
+
+// totalTolerance absorbs float rounding differences between client and server totals
+const totalTolerance = 0.5
+
+// checkClientTotals compares the totals the client showed with the server's. Client values
+// are never used; a difference means the client's prices or rules are stale and the cashier
+// must confirm the new amount, so it is answered with 409. Fields sent as 0 are not checked.
+func checkClientTotals(req *dto.TransactionCreateRequest, t *model.Transaction) error {
+    // a client unaware of cash rounding may send the total before rounding
+    clientTotal := req.Total
+    if clientTotal != 0 && math.Abs(clientTotal-(t.Total-t.RoundingAdjustment)) <= totalTolerance {
+        clientTotal = t.Total
+    }
+    checks := []struct {
+        name           string
+        client, server float64
+    }{
+        {"subtotal", req.Subtotal, t.Subtotal},
+        {"tax", req.Tax, t.Tax},
+        {"service_charge", req.ServiceCharge, t.ServiceCharge},
+        {"total", clientTotal, t.Total},
+    }
+    for _, c := range checks {
+        if c.client != 0 && math.Abs(c.client-c.server) > totalTolerance {
+            return conflictErrorf("%s mismatch: client %.2f, server %.2f", c.name, c.client, c.server)
+        }
+    }
+    return nil
+}
+
+// SyncOutcome is the result of one offline sale: the stored transaction (Duplicate when an
+// earlier upload stored it) or the reason it was rejected
+type SyncOutcome struct {
+    ClientUUID  string
+    Transaction *model.Transaction
+    Duplicate   bool
+    Err         error
+}
+
+const (
+    // maxSyncBatch bounds the sales accepted by one sync request
+    maxSyncBatch = 200
+    // device clocks may run a little ahead of the server's
+    syncClockSkew = 5 * time.Minute
+)
+
+func (s *transactionService) Sync(batch []dto.SyncTransaction, cashierID *uint) ([]SyncOutcome, error) {
+    if len(batch) == 0 || len(batch) > maxSyncBatch {
+        return nil, validationErrorf("a sync request takes 1 to %d transactions", maxSyncBatch)
+    }
+    out := make([]SyncOutcome, len(batch))
+    seen := map[string]bool{}
+    for i := range batch {
+        st := &batch[i]
+        o := &out[i]
+        o.ClientUUID = st.ClientUUID
+        uuid := strings.ToLower(strings.TrimSpace(st.ClientUUID))
+        if !validUUID(uuid) {
+            o.Err = validationErrorf("client_uuid must be a UUID")
+            continue
+        }
+        if seen[uuid] {
+            o.Err = validationErrorf("client_uuid %s appears twice in the batch", uuid)
+            continue
+        }
+        seen[uuid] = true
+        o.Transaction, o.Duplicate, o.Err = s.syncOne(uuid, st, cashierID)
+        if o.Err == nil && !o.Duplicate {
+            s.Notify(o.Transaction)
+        }
+    }
+    return out, nil
+}
+
+func (s *transactionService) syncOne(uuid string, st *dto.SyncTransaction, cashierID *uint) (*model.Transaction, bool, error) {
+    existing, err := s.repo.GetByClientUUID(uuid)
+    if err != nil {
+        return nil, false, err
+    }
+    if existing != nil {
+        return existing, true, nil
+    }
+    if st.CreatedAt.IsZero() || st.CreatedAt.After(time.Now().Add(syncClockSkew)) {
+        return nil, false, validationErrorf("created_at must be the time of sale")
+    }
+    var t *model.Transaction
+    err = repository.Transaction(func(tx *gorm.DB) error {
+        var err error
+        t, err = s.checkout(tx, &st.TransactionCreateRequest, cashierID, st.CreatedAt.Local(), &uuid)
+        return err
+    })
+    if err != nil {
+        // the same sale uploaded concurrently wins the unique index
+        if existing, _ := s.repo.GetByClientUUID(uuid); existing != nil {
+            return existing, true, nil
+        }
+        return nil, false, err
+    }
+    return t, false, nil
+}
+
+// validUUID accepts the canonical lower-case form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func validUUID(s string) bool {
+    if len(s) != 36 {
+        return false
+    }
+    for i, c := range s {
+        switch i {
+        case 8, 13, 18, 23:
+            if c != '-' {
+                return false
+            }
+        default:
+            if !strings.ContainsRune("0123456789abcdef", c) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+func (s *transactionService) Notify(t *model.Transaction) {
+    notif := map[string]interface{}{
+        "type":           "transaction_created",
+        "id":             t.ID,
+        "invoice_number": t.InvoiceNumber,
+        "total":          t.Total,
+        "status":         t.Status,
+        "cashier_id":     t.CashierID,
+    }
+    if b, err := json.Marshal(notif); err == nil {
+        utils.NotifierInstance.Notify(string(b))
+    }
+}
+
 func (s *transactionService) List() ([]model.Transaction, error) {
     return s.repo.List()
 }
 
+func (s *transactionService) SearchInvoice(q string) ([]model.Transaction, error) {
+    q = strings.TrimSpace(q)
+    if q == "" {
+        return nil, validationErrorf("invoice number is required")
+    }
+    return s.repo.ListByInvoice(q)
+}
+
+func (s *transactionService) ListPriceReview() ([]model.Transaction, error) {
+    return s.repo.ListPriceReview()
+}
+
 func (s *transactionService) GetByID(id uint) (*model.Transaction, error) {
     return s.repo.GetByID(id)
 }
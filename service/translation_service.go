@@ -0,0 +1,180 @@
+package service
+
+import (
+	"strings"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+)
+
+type TranslationService interface {
+    // LocalizeMenus replaces menu (and category) names and descriptions in place with their
+    // translation for locale; untranslated fields keep the default-language text
+    LocalizeMenus(list []model.Menu, locale string) error
+    LocalizeCategories(list []model.Category, locale string) error
+    ListMenu(menuID uint) ([]model.MenuTranslation, error)
+    SetMenu(t *model.MenuTranslation) error
+    DeleteMenu(menuID uint, locale string) error
+    ListCategory(categoryID uint) ([]model.CategoryTranslation, error)
+    SetCategory(t *model.CategoryTranslation) error
+    DeleteCategory(categoryID uint, locale string) error
+}
+
+type translationService struct{
+    repo    repository.TranslationRepository
+    catalog CatalogService
+}
+
+func NewTranslationService(r repository.TranslationRepository, catalog CatalogService) TranslationService {
+    return &translationService{repo: r, catalog: catalog}
+}
+
+func (s *translationService) LocalizeMenus(list []model.Menu, locale string) error {
+    // allergens come from a fixed list, their labels are always filled in
+    for i := range list {
+        for j := range list[i].Allergens {
+            a := &list[i].Allergens[j]
+            a.Name = model.AllergenName(a.Code, locale, config.DefaultLocale())
+        }
+    }
+    if locale == "" || locale == config.DefaultLocale() || len(list) == 0 {
+        return nil
+    }
+    ids := make([]uint, 0, len(list))
+    var catIDs []uint
+    for _, m := range list {
+        ids = append(ids, m.ID)
+        if m.CategoryID != nil {
+            catIDs = append(catIDs, *m.CategoryID)
+        }
+    }
+    trs, err := s.repo.MenuTranslations(ids, locale)
+    if err != nil {
+        return err
+    }
+    byMenu := map[uint]model.MenuTranslation{}
+    for _, t := range trs {
+        byMenu[t.MenuID] = t
+    }
+    catTrs, err := s.repo.CategoryTranslations(catIDs, locale)
+    if err != nil {
+        return err
+    }
+    byCat := map[uint]string{}
+    for _, t := range catTrs {
+        byCat[t.CategoryID] = t.Name
+    }
+
+    for i := range list {
+        if t, ok := byMenu[list[i].ID]; ok {
+            if t.Name != "" {
+                list[i].Name = t.Name
+            }
+            if t.Description != "" {
+                list[i].Description = t.Description
+            }
+        }
+        if name, ok := byCat[list[i].Category.ID]; ok && name != "" {
+            list[i].Category.Name = name
+        }
+    }
+    return nil
+}
+
+func (s *translationService) LocalizeCategories(list []model.Category, locale string) error {
+    if locale == "" || locale == config.DefaultLocale() || len(list) == 0 {
+        return nil
+    }
+    ids := make([]uint, 0, len(list))
+    for _, c := range list {
+        ids = append(ids, c.ID)
+    }
+    trs, err := s.repo.CategoryTranslations(ids, locale)
+    if err != nil {
+        return err
+    }
+    byCat := map[uint]string{}
+    for _, t := range trs {
+        byCat[t.CategoryID] = t.Name
+    }
+    for i := range list {
+        if name, ok := byCat[list[i].ID]; ok && name != "" {
+            list[i].Name = name
+        }
+    }
+    return nil
+}
+
+func (s *translationService) ListMenu(menuID uint) ([]model.MenuTranslation, error) {
+    return s.repo.ListMenu(menuID)
+}
+
+func (s *translationService) SetMenu(t *model.MenuTranslation) error {
+    locale, err := translationLocale(t.Locale)
+    if err != nil {
+        return err
+    }
+    t.Locale = locale
+    t.Name = strings.TrimSpace(t.Name)
+    if t.Name == "" && strings.TrimSpace(t.Description) == "" {
+        return validationErrorf("name or description is required")
+    }
+    if err := s.repo.UpsertMenu(t); err != nil {
+        return err
+    }
+    s.catalog.Bump("translation_updated")
+    return nil
+}
+
+func (s *translationService) DeleteMenu(menuID uint, locale string) error {
+    if err := s.repo.DeleteMenu(menuID, strings.ToLower(locale)); err != nil {
+        return err
+    }
+    s.catalog.Bump("translation_deleted")
+    return nil
+}
+
+func (s *translationService) ListCategory(categoryID uint) ([]model.CategoryTranslation, error) {
+    return s.repo.ListCategory(categoryID)
+}
+
+func (s *translationService) SetCategory(t *model.CategoryTranslation) error {
+    locale, err := translationLocale(t.Locale)
+    if err != nil {
+        return err
+    }
+    t.Locale = locale
+    t.Name = strings.TrimSpace(t.Name)
+    if t.Name == "" {
+        return validationErrorf("name is required")
+    }
+    if err := s.repo.UpsertCategory(t); err != nil {
+        return err
+    }
+    s.catalog.Bump("translation_updated")
+    return nil
+}
+
+func (s *translationService) DeleteCategory(categoryID uint, locale string) error {
+    if err := s.repo.DeleteCategory(categoryID, strings.ToLower(locale)); err != nil {
+        return err
+    }
+    s.catalog.Bump("translation_deleted")
+    return nil
+}
+
+// translationLocale validates a locale translations can be stored for; the default locale
+// lives on the menu/category itself
+func translationLocale(locale string) (string, error) {
+    locale = strings.ToLower(strings.TrimSpace(locale))
+    if locale == config.DefaultLocale() {
+        return "", validationErrorf("%s is the default locale, edit the menu itself", locale)
+    }
+    for _, l := range config.SupportedLocales() {
+        if l == locale {
+            return locale, nil
+        }
+    }
+    return "", validationErrorf("unsupported locale %q", locale)
+}
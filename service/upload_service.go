@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/dto"
+	"github.com/IndalAwalaikal/warung-pos/backend/model"
+	"github.com/IndalAwalaikal/warung-pos/backend/repository"
+	"github.com/IndalAwalaikal/warung-pos/backend/storage"
+)
+
+// entity types an upload can be attached to
+const UploadEntityMenu = "menu"
+
+type UploadService interface {
+    Create(u *model.Upload) error
+    // Attach marks the uploads behind urls as used by the entity and releases the ones it
+    // referenced before
+    Attach(entityType string, entityID uint, urls ...string) error
+    // Cleanup deletes unattached uploads older than the grace period; with dryRun it only reports them
+    Cleanup(dryRun bool) (*dto.UploadCleanupReport, error)
+}
+
+type uploadService struct{
+    repo     repository.UploadRepository
+    menuRepo repository.MenuRepository
+    store    storage.Storage
+    grace    time.Duration
+}
+
+func NewUploadService(r repository.UploadRepository, mr repository.MenuRepository, store storage.Storage, grace time.Duration) UploadService {
+    return &uploadService{repo: r, menuRepo: mr, store: store, grace: grace}
+}
+
+func (s *uploadService) Create(u *model.Upload) error {
+    return s.repo.Create(u)
+}
+
+func (s *uploadService) Attach(entityType string, entityID uint, urls ...string) error {
+    return s.repo.Sync(entityType, entityID, nonEmpty(urls...))
+}
+
+func (s *uploadService) Cleanup(dryRun bool) (*dto.UploadCleanupReport, error) {
+    before := time.Now().Add(-s.grace)
+    list, err := s.repo.ListUnattached(before)
+    if err != nil {
+        return nil, err
+    }
+    report := &dto.UploadCleanupReport{
+        DryRun:     dryRun,
+        GraceHours: int(s.grace.Hours()),
+        Before:     before,
+        Candidates: []dto.UploadCleanupItem{},
+    }
+    ctx := context.Background()
+    for _, u := range list {
+        item := dto.UploadCleanupItem{ID: u.ID, OwnerID: u.OwnerID, CreatedAt: u.CreatedAt, URLs: []string{}}
+        for _, f := range u.Files {
+            item.URLs = append(item.URLs, f.URL)
+            item.Size += f.Size
+        }
+
+        // never delete a file that is still referenced, e.g. when a menu was saved before
+        // attachment tracking existed; attach it instead
+        menus, err := s.menuRepo.FindByImageURLs(item.URLs)
+        if err != nil {
+            return nil, err
+        }
+        if len(menus) > 0 {
+            if !dryRun {
+                if err := s.repo.Sync(UploadEntityMenu, menus[0].ID, []string{menus[0].ImageURL}); err != nil {
+                    return nil, err
+                }
+            }
+            report.Reattached++
+            continue
+        }
+
+        report.Candidates = append(report.Candidates, item)
+        if dryRun {
+            continue
+        }
+        failed := false
+        for _, f := range u.Files {
+            if err := s.store.Delete(ctx, f.Key); err != nil {
+                report.Errors = append(report.Errors, fmt.Sprintf("upload %d: %s: %v", u.ID, f.Key, err))
+                failed = true
+            }
+        }
+        if failed {
+            continue
+        }
+        if err := s.repo.Delete(u.ID); err != nil {
+            return nil, err
+        }
+        report.Deleted++
+        report.FreedBytes += item.Size
+    }
+    return report, nil
+}
@@ -0,0 +1,62 @@
+// Package storage abstracts where uploaded files live so the API can run on several
+// instances (S3-compatible object storage) or a single box (local filesystem).
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/IndalAwalaikal/warung-pos/backend/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores objects by key. Keys are relative slash-separated paths, e.g. "menus/123.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+	// URL returns the stable URL to persist for key (e.g. in model.Menu.ImageURL)
+	URL(key string) string
+	// SignedURL returns a URL the client can fetch directly that is valid for ttl. Backends
+	// serving public URLs return URL(key).
+	SignedURL(key string, ttl time.Duration) (string, error)
+	// Key reverses URL; ok is false when url does not belong to this backend
+	Key(url string) (key string, ok bool)
+}
+
+// FromEnv builds the backend selected by STORAGE_DRIVER (local or s3)
+func FromEnv() (Storage, error) {
+	switch driver := config.GetEnv("STORAGE_DRIVER", "local"); driver {
+	case "local":
+		return NewLocal(config.GetEnv("STORAGE_LOCAL_DIR", "./uploads"), "/uploads/"), nil
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:     config.GetEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+			Region:       config.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:       config.GetEnv("S3_BUCKET", ""),
+			AccessKey:    config.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey:    config.GetEnv("S3_SECRET_KEY", ""),
+			PathStyle:    config.GetEnv("S3_PATH_STYLE", "true") == "true",
+			PublicURL:    config.GetEnv("S3_PUBLIC_URL", ""),
+			ProxyPrefix:  "/files/",
+			SignedURLTTL: time.Duration(config.GetEnvInt("S3_SIGNED_URL_TTL_MINUTES", 15)) * time.Minute,
+		})
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
+
+// cleanKey rejects keys that could escape the storage root
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "..") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return key, nil
+}
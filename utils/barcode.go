@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Barcode symbologies accepted for menu barcodes
+const (
+	SymbologyEAN13   = "ean13"
+	SymbologyEAN8    = "ean8"
+	SymbologyUPCA    = "upca"
+	SymbologyCode128 = "code128"
+)
+
+// DetectSymbology guesses the symbology of a scanned code from its shape
+func DetectSymbology(code string) string {
+	if isDigits(code) {
+		switch len(code) {
+		case 13:
+			if ValidGTIN(code) {
+				return SymbologyEAN13
+			}
+		case 12:
+			if ValidGTIN(code) {
+				return SymbologyUPCA
+			}
+		case 8:
+			if ValidGTIN(code) {
+				return SymbologyEAN8
+			}
+		}
+	}
+	return SymbologyCode128
+}
+
+// ValidateBarcode checks a code against its symbology (including the GTIN check digit)
+func ValidateBarcode(code, symbology string) error {
+	if code == "" {
+		return fmt.Errorf("barcode is empty")
+	}
+	switch symbology {
+	case SymbologyEAN13, SymbologyEAN8, SymbologyUPCA:
+		want := map[string]int{SymbologyEAN13: 13, SymbologyEAN8: 8, SymbologyUPCA: 12}[symbology]
+		if len(code) != want || !isDigits(code) {
+			return fmt.Errorf("%s barcode must be %d digits", symbology, want)
+		}
+		if !ValidGTIN(code) {
+			return fmt.Errorf("invalid check digit in %s", code)
+		}
+	case SymbologyCode128:
+		for _, r := range code {
+			if r < 32 || r > 126 {
+				return fmt.Errorf("code128 barcode must be printable ASCII")
+			}
+		}
+	default:
+		return fmt.Errorf("unknown symbology %q", symbology)
+	}
+	return nil
+}
+
+// BarcodeVariants returns the equivalent spellings of a scanned code; scanners may report a
+// UPC-A code as the 13-digit EAN with a leading zero or the other way round
+func BarcodeVariants(code string) []string {
+	code = strings.TrimSpace(code)
+	out := []string{code}
+	if isDigits(code) {
+		if len(code) == 12 {
+			out = append(out, "0"+code)
+		} else if len(code) == 13 && code[0] == '0' {
+			out = append(out, code[1:])
+		}
+	}
+	return out
+}
+
+// GTINCheckDigit computes the check digit for the given digits (without check digit)
+func GTINCheckDigit(digits string) int {
+	sum := 0
+	// weights alternate 3,1,3,... starting from the rightmost digit
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			sum += d * 3
+		} else {
+			sum += d
+		}
+	}
+	return (10 - sum%10) % 10
+}
+
+func ValidGTIN(code string) bool {
+	if len(code) < 2 || !isDigits(code) {
+		return false
+	}
+	return GTINCheckDigit(code[:len(code)-1]) == int(code[len(code)-1]-'0')
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+var eanLCodes = []string{"0001101", "0011001", "0010011", "0111101", "0100011", "0110001", "0101111", "0111011", "0110111", "0001011"}
+
+// parity pattern of the left half, selected by the first (implicit) digit of an EAN-13
+var eanParity = []string{"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL"}
+
+func eanRCode(d int) string {
+	l := eanLCodes[d]
+	b := make([]byte, len(l))
+	for i := range l {
+		if l[i] == '0' {
+			b[i] = '1'
+		} else {
+			b[i] = '0'
+		}
+	}
+	return string(b)
+}
+
+func eanGCode(d int) string {
+	r := eanRCode(d)
+	b := make([]byte, len(r))
+	for i := range r {
+		b[len(r)-1-i] = r[i]
+	}
+	return string(b)
+}
+
+// EncodeEAN13 returns the 95 modules (true = bar) of an EAN-13 code. 12 digits get their
+// check digit appended, 12-digit UPC-A codes can be passed with a leading zero.
+func EncodeEAN13(code string) ([]bool, string, error) {
+	if len(code) == 12 && isDigits(code) {
+		code += fmt.Sprint(GTINCheckDigit(code))
+	}
+	if err := ValidateBarcode(code, SymbologyEAN13); err != nil {
+		return nil, "", err
+	}
+	var sb strings.Builder
+	sb.WriteString("101")
+	parity := eanParity[code[0]-'0']
+	for i := 1; i <= 6; i++ {
+		d := int(code[i] - '0')
+		if parity[i-1] == 'L' {
+			sb.WriteString(eanLCodes[d])
+		} else {
+			sb.WriteString(eanGCode(d))
+		}
+	}
+	sb.WriteString("01010")
+	for i := 7; i <= 12; i++ {
+		sb.WriteString(eanRCode(int(code[i] - '0')))
+	}
+	sb.WriteString("101")
+	return modules(sb.String()), code, nil
+}
+
+// code128Patterns are the bar/space widths of symbol values 0..106 (106 is the stop symbol)
+var code128Patterns = []string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// EncodeCode128 returns the modules (true = bar) of a Code 128 symbol. Even-length numeric
+// codes use subset C (two digits per symbol), everything else subset B.
+func EncodeCode128(code string) ([]bool, error) {
+	if err := ValidateBarcode(code, SymbologyCode128); err != nil {
+		return nil, err
+	}
+	var values []int
+	if isDigits(code) && len(code)%2 == 0 {
+		values = append(values, code128StartC)
+		for i := 0; i < len(code); i += 2 {
+			values = append(values, int(code[i]-'0')*10+int(code[i+1]-'0'))
+		}
+	} else {
+		values = append(values, code128StartB)
+		for i := 0; i < len(code); i++ {
+			values = append(values, int(code[i])-32)
+		}
+	}
+	sum := values[0]
+	for i := 1; i < len(values); i++ {
+		sum += i * values[i]
+	}
+	values = append(values, sum%103, code128Stop)
+
+	var out []bool
+	for _, v := range values {
+		bar := true
+		for _, w := range code128Patterns[v] {
+			for k := 0; k < int(w-'0'); k++ {
+				out = append(out, bar)
+			}
+			bar = !bar
+		}
+	}
+	return out, nil
+}
+
+func modules(s string) []bool {
+	out := make([]bool, len(s))
+	for i := range s {
+		out[i] = s[i] == '1'
+	}
+	return out
+}
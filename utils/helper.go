@@ -1,3 +1,44 @@
 package utils
 
-// helper.go - small utilities placeholder
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FormatRupiah formats an amount the Indonesian way, e.g. 18000 -> "Rp 18.000"
+func FormatRupiah(v float64) string {
+	neg := v < 0
+	n := int64(math.Round(math.Abs(v)))
+	s := strconv.FormatInt(n, 10)
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	if neg {
+		return "-Rp " + b.String()
+	}
+	return "Rp " + b.String()
+}
+
+// Slugify lower-cases s and joins its letters/digits runs with dashes, e.g. "Pedas Level 3" -> "pedas-level-3"
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(s)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
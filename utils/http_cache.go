@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CatalogETag derives a weak ETag from the catalog version and the request variant
+// (query string, language...) so different views of the same version differ
+func CatalogETag(version uint64, variant string) string {
+	h := sha1.Sum([]byte(variant))
+	return `W/"` + hex.EncodeToString(h[:8]) + "-" + strconv.FormatUint(version, 10) + `"`
+}
+
+// NotModified sets the validators on the response and, when the request's If-None-Match
+// (or, without it, If-Modified-Since) shows the client copy is current, writes 304 and
+// returns true
+func NotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-cache")
+
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		for _, t := range strings.Split(inm, ",") {
+			t = strings.TrimSpace(t)
+			if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+				c.Status(http.StatusNotModified)
+				c.Abort()
+				return true
+			}
+		}
+		return false
+	}
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			c.Status(http.StatusNotModified)
+			c.Abort()
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrImageTooLarge    = errors.New("image exceeds the maximum upload size")
+	ErrImageTooManyPx   = errors.New("image exceeds the maximum number of pixels")
+	ErrUnsupportedImage = errors.New("file is not a supported image (jpeg, png, gif or webp)")
+)
+
+// DefaultMaxPixels bounds width x height when ImageOptions.MaxPixels is not set. A small,
+// highly compressed file can declare huge dimensions, and decoding allocates them all.
+const DefaultMaxPixels = 40_000_000
+
+// allowed content types, detected from the file bytes rather than the client's extension
+var imageContentTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Rendition is one output size of an uploaded image. MaxSize bounds the longest side.
+type Rendition struct {
+	Name    string
+	MaxSize int
+}
+
+type ImageOptions struct {
+	MaxBytes   int64
+	MaxPixels  int64
+	Quality    int
+	Renditions []Rendition
+}
+
+// ProcessedImage is an encoded rendition ready to be stored
+type ProcessedImage struct {
+	Name        string
+	Data        []byte
+	Width       int
+	Height      int
+	ContentType string
+	Ext         string
+}
+
+// ProcessImage validates an uploaded image by its real content type, applies the EXIF
+// orientation and re-encodes each rendition as JPEG. Re-encoding drops all metadata (EXIF,
+// GPS, ...) from the stored files. Images are never upscaled.
+func ProcessImage(data []byte, opts ImageOptions) ([]ProcessedImage, error) {
+	if opts.MaxBytes > 0 && int64(len(data)) > opts.MaxBytes {
+		return nil, ErrImageTooLarge
+	}
+	if !imageContentTypes[http.DetectContentType(data)] {
+		return nil, ErrUnsupportedImage
+	}
+	// check the declared dimensions before decoding allocates the pixels
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	maxPixels := opts.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = DefaultMaxPixels
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxPixels {
+		return nil, ErrImageTooManyPx
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedImage
+	}
+	src = applyOrientation(src, jpegOrientation(data))
+
+	quality := opts.Quality
+	if quality <= 0 {
+		quality = 85
+	}
+	out := make([]ProcessedImage, 0, len(opts.Renditions))
+	for _, r := range opts.Renditions {
+		img := resizeToFit(src, r.MaxSize)
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		b := img.Bounds()
+		out = append(out, ProcessedImage{
+			Name:        r.Name,
+			Data:        buf.Bytes(),
+			Width:       b.Dx(),
+			Height:      b.Dy(),
+			ContentType: "image/jpeg",
+			Ext:         ".jpg",
+		})
+	}
+	return out, nil
+}
+
+// resizeToFit scales img so its longest side is at most max and flattens transparency onto
+// white (JPEG has no alpha channel)
+func resizeToFit(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if max > 0 && (w > max || h > max) {
+		if w >= h {
+			h = h * max / w
+			w = max
+		} else {
+			w = w * max / h
+			h = max
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), &image.Uniform{C: color.White}, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation tag (1-8) from a JPEG, returning 1 when absent
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if marker == 0xDA || size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		seg := data[i+4 : i+2+size]
+		if marker == 0xE1 && len(seg) > 14 && string(seg[:6]) == "Exif\x00\x00" {
+			return exifOrientation(seg[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return 1
+	}
+	off := int(bo.Uint32(tiff[4:]))
+	if off+2 > len(tiff) {
+		return 1
+	}
+	n := int(bo.Uint16(tiff[off:]))
+	for k := 0; k < n; k++ {
+		e := off + 2 + k*12
+		if e+12 > len(tiff) {
+			return 1
+		}
+		if bo.Uint16(tiff[e:]) == 0x0112 {
+			v := int(bo.Uint16(tiff[e+8:]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation rotates/flips img so it displays upright once the EXIF tag is dropped
+func applyOrientation(img image.Image, o int) image.Image {
+	if o <= 1 || o > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if o >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch o {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResolveLocale picks the response language from ?lang= or the Accept-Language header.
+// Region variants fall back to their base language (en-US -> en) and anything unsupported
+// falls back to def.
+func ResolveLocale(c *gin.Context, supported []string, def string) string {
+	if l := matchLocale(c.Query("lang"), supported); l != "" {
+		return l
+	}
+	for _, l := range parseAcceptLanguage(c.GetHeader("Accept-Language")) {
+		if m := matchLocale(l, supported); m != "" {
+			return m
+		}
+	}
+	return def
+}
+
+func matchLocale(l string, supported []string) string {
+	l = strings.ToLower(strings.TrimSpace(l))
+	if l == "" {
+		return ""
+	}
+	base := strings.SplitN(strings.ReplaceAll(l, "_", "-"), "-", 2)[0]
+	for _, s := range supported {
+		if s == l {
+			return s
+		}
+	}
+	for _, s := range supported {
+		if s == base {
+			return s
+		}
+	}
+	return ""
+}
+
+// parseAcceptLanguage returns the tags of an Accept-Language header ordered by quality
+func parseAcceptLanguage(h string) []string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+	var tags []tag
+	for _, part := range strings.Split(h, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+		t := tag{lang: fields[0], q: 1}
+		for _, f := range fields[1:] {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(f), "q="); ok {
+				if q, err := strconv.ParseFloat(v, 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+	out := make([]string, len(tags))
+	for i, t := range tags {
+		out[i] = t.lang
+	}
+	return out
+}
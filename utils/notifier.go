@@ -4,22 +4,29 @@ import (
 	"sync"
 )
 
-// Simple in-memory notifier for server-sent events
+// Simple in-memory notifier for server-sent events. Clients subscribe to everything or to
+// a single topic (e.g. one kitchen station).
 type Notifier struct {
-	clients map[chan string]struct{}
+	clients map[chan string]string
 	mu      sync.Mutex
 }
 
 func NewNotifier() *Notifier {
-	return &Notifier{clients: make(map[chan string]struct{})}
+	return &Notifier{clients: make(map[chan string]string)}
 }
 
 var NotifierInstance = NewNotifier()
 
+// AddClient subscribes ch to every notification
 func (n *Notifier) AddClient(ch chan string) {
+	n.AddTopicClient(ch, "")
+}
+
+// AddTopicClient subscribes ch to the notifications of one topic only
+func (n *Notifier) AddTopicClient(ch chan string, topic string) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
-	n.clients[ch] = struct{}{}
+	n.clients[ch] = topic
 }
 
 func (n *Notifier) RemoveClient(ch chan string) {
@@ -29,10 +36,19 @@ func (n *Notifier) RemoveClient(ch chan string) {
 	close(ch)
 }
 
+// Notify sends msg to the clients subscribed to everything
 func (n *Notifier) Notify(msg string) {
+	n.NotifyTopic("", msg)
+}
+
+// NotifyTopic sends msg to the clients of topic and to those subscribed to everything
+func (n *Notifier) NotifyTopic(topic, msg string) {
 	n.mu.Lock()
 	defer n.mu.Unlock()
-	for ch := range n.clients {
+	for ch, t := range n.clients {
+		if t != "" && t != topic {
+			continue
+		}
 		// non-blocking send
 		select {
 		case ch <- msg:
@@ -1,3 +1,336 @@
 package utils
 
-// pagination.go - placeholder
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// FieldKind tells the query layer how to convert a raw query-string value
+type FieldKind int
+
+const (
+	KindString FieldKind = iota
+	KindNumber
+	KindBool
+	KindTime
+)
+
+// Field maps a public (query string) field name to a database column
+type Field struct {
+	Column string
+	Kind   FieldKind
+}
+
+// ListOptions describes which fields a list endpoint allows to be sorted and filtered
+type ListOptions struct {
+	DefaultLimit int
+	MaxLimit     int
+	// Unpaginated returns every row when none of page, limit or cursor is given, for
+	// endpoints that answered with the full list before they were paginated
+	Unpaginated bool
+	// DefaultSort uses the same syntax as the sort query param, e.g. "-created_at"
+	DefaultSort  string
+	SortFields   map[string]Field
+	FilterFields map[string]Field
+}
+
+type SortField struct {
+	Name   string
+	Column string
+	Kind   FieldKind
+	Desc   bool
+}
+
+type Filter struct {
+	Field  string
+	Column string
+	Op     string
+	Value  interface{}
+}
+
+// ListQuery holds pagination, sorting and filtering parsed from a request.
+// Either Page or Cursor is used: when a cursor is present the page number is ignored.
+// A zero Limit means the query is not paginated.
+type ListQuery struct {
+	Page    int
+	Limit   int
+	Cursor  *Cursor
+	Sort    SortField
+	Filters []Filter
+}
+
+// Cursor is the keyset position after the last returned row
+type Cursor struct {
+	Value interface{} `json:"v"`
+	ID    uint        `json:"id"`
+}
+
+// PageMeta is returned next to list data in the response envelope; Limit is 0 when the
+// list is not paginated
+type PageMeta struct {
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Total      int64  `json:"total"`
+	TotalPages int    `json:"total_pages"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Sort       string `json:"sort"`
+}
+
+var filterOps = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"gt":   ">",
+	"gte":  ">=",
+	"lt":   "<",
+	"lte":  "<=",
+	"like": "LIKE",
+	"in":   "IN",
+}
+
+// ParseListQuery reads page, limit, cursor, sort and filter query params.
+//
+//	?page=2&limit=20
+//	?cursor=<next_cursor from previous response>&limit=20
+//	?sort=-price            (single field, "-" for descending; id is always the tie-breaker)
+//	?filter=price:gte:10000&filter=name:like:nasi&filter=id:in:1|2|3
+func ParseListQuery(c *gin.Context, opts ListOptions) (ListQuery, error) {
+	q := ListQuery{Page: 1, Limit: opts.DefaultLimit}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("invalid limit")
+		}
+		q.Limit = n
+	}
+	if opts.Unpaginated && c.Query("page") == "" && c.Query("limit") == "" && c.Query("cursor") == "" {
+		q.Limit = 0
+	}
+	if opts.MaxLimit > 0 && q.Limit > opts.MaxLimit {
+		q.Limit = opts.MaxLimit
+	}
+	if v := c.Query("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return q, fmt.Errorf("invalid page")
+		}
+		q.Page = n
+	}
+
+	sortStr := c.Query("sort")
+	if sortStr == "" {
+		sortStr = opts.DefaultSort
+	}
+	if sortStr == "" {
+		sortStr = "id"
+	}
+	s, err := parseSort(sortStr, opts.SortFields)
+	if err != nil {
+		return q, err
+	}
+	q.Sort = s
+
+	if v := c.Query("cursor"); v != "" {
+		cur, err := decodeCursor(v)
+		if err != nil {
+			return q, fmt.Errorf("invalid cursor")
+		}
+		// JSON round-trips times as strings, restore the typed value for comparison
+		if s, ok := cur.Value.(string); ok && q.Sort.Kind == KindTime {
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return q, fmt.Errorf("invalid cursor")
+			}
+			cur.Value = t
+		}
+		q.Cursor = cur
+	}
+
+	for _, raw := range c.QueryArray("filter") {
+		f, err := parseFilter(raw, opts.FilterFields)
+		if err != nil {
+			return q, err
+		}
+		q.Filters = append(q.Filters, f)
+	}
+	return q, nil
+}
+
+func parseSort(s string, fields map[string]Field) (SortField, error) {
+	desc := strings.HasPrefix(s, "-")
+	name := strings.TrimPrefix(s, "-")
+	if name == "id" {
+		return SortField{Name: "id", Column: "id", Kind: KindNumber, Desc: desc}, nil
+	}
+	f, ok := fields[name]
+	if !ok {
+		return SortField{}, fmt.Errorf("cannot sort by %q", name)
+	}
+	return SortField{Name: name, Column: f.Column, Kind: f.Kind, Desc: desc}, nil
+}
+
+func parseFilter(raw string, fields map[string]Field) (Filter, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 {
+		return Filter{}, fmt.Errorf("invalid filter %q, use field:op:value", raw)
+	}
+	name, op, val := parts[0], parts[1], parts[2]
+	f, ok := fields[name]
+	if !ok {
+		return Filter{}, fmt.Errorf("cannot filter by %q", name)
+	}
+	if _, ok := filterOps[op]; !ok {
+		return Filter{}, fmt.Errorf("unknown filter operator %q", op)
+	}
+	out := Filter{Field: name, Column: f.Column, Op: op}
+	switch op {
+	case "like":
+		out.Value = "%" + EscapeLike(val) + "%"
+	case "in":
+		var vals []interface{}
+		for _, p := range strings.Split(val, "|") {
+			cv, err := convertValue(p, f.Kind)
+			if err != nil {
+				return Filter{}, fmt.Errorf("invalid value for %s: %v", name, err)
+			}
+			vals = append(vals, cv)
+		}
+		out.Value = vals
+	default:
+		cv, err := convertValue(val, f.Kind)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+		out.Value = cv
+	}
+	return out, nil
+}
+
+func convertValue(v string, kind FieldKind) (interface{}, error) {
+	switch kind {
+	case KindNumber:
+		return strconv.ParseFloat(v, 64)
+	case KindBool:
+		return strconv.ParseBool(v)
+	case KindTime:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t, nil
+		}
+		return time.ParseInLocation("2006-01-02", v, time.Local)
+	}
+	return v, nil
+}
+
+// EscapeLike escapes LIKE wildcards so user input is matched literally
+func EscapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+// ApplyFilters adds the parsed filter expressions to a query
+func (q ListQuery) ApplyFilters(db *gorm.DB) *gorm.DB {
+	for _, f := range q.Filters {
+		switch f.Op {
+		case "in":
+			db = db.Where(f.Column+" IN ?", f.Value)
+		default:
+			db = db.Where(f.Column+" "+filterOps[f.Op]+" ?", f.Value)
+		}
+	}
+	return db
+}
+
+// ApplyPage adds ordering and either offset or keyset pagination to a query.
+// Call it after counting the total so the count is not limited.
+func (q ListQuery) ApplyPage(db *gorm.DB, table string) *gorm.DB {
+	col := q.Sort.Column
+	idCol := "id"
+	if table != "" {
+		if !strings.Contains(col, ".") {
+			col = table + "." + col
+		}
+		idCol = table + ".id"
+	}
+	dir := "ASC"
+	cmp := ">"
+	if q.Sort.Desc {
+		dir = "DESC"
+		cmp = "<"
+	}
+	if q.Limit == 0 {
+		if col != idCol {
+			db = db.Order(col + " " + dir)
+		}
+		return db.Order(idCol + " " + dir)
+	}
+	if q.Cursor != nil {
+		if col == idCol {
+			db = db.Where(idCol+" "+cmp+" ?", q.Cursor.ID)
+		} else {
+			db = db.Where("("+col+" "+cmp+" ?) OR ("+col+" = ? AND "+idCol+" "+cmp+" ?)", q.Cursor.Value, q.Cursor.Value, q.Cursor.ID)
+		}
+	} else {
+		db = db.Offset((q.Page - 1) * q.Limit)
+	}
+	if col != idCol {
+		db = db.Order(col + " " + dir)
+	}
+	// fetch one extra row to know whether there is a next page
+	return db.Order(idCol + " " + dir).Limit(q.Limit + 1)
+}
+
+// Meta builds the pagination metadata. fetched is the number of rows returned by a query
+// built with ApplyPage (which over-fetches by one); last returns the sort value and id of the
+// last row that will actually be returned to the client.
+func (q ListQuery) Meta(total int64, fetched int, last func() (interface{}, uint)) PageMeta {
+	m := PageMeta{Limit: q.Limit, Total: total, Sort: q.sortString()}
+	if q.Limit > 0 {
+		m.TotalPages = int((total + int64(q.Limit) - 1) / int64(q.Limit))
+	} else if total > 0 {
+		m.TotalPages = 1
+	}
+	if q.Cursor == nil {
+		m.Page = q.Page
+	}
+	m.HasMore = q.Limit > 0 && fetched > q.Limit
+	if m.HasMore && last != nil {
+		v, id := last()
+		m.NextCursor = encodeCursor(Cursor{Value: v, ID: id})
+	}
+	return m
+}
+
+func (q ListQuery) sortString() string {
+	if q.Sort.Desc {
+		return "-" + q.Sort.Name
+	}
+	return q.Sort.Name
+}
+
+func encodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidTableToken is returned for malformed or tampered table tokens
+var ErrInvalidTableToken = errors.New("invalid table token")
+
+// SignTableToken returns the QR token of a table: "<id>.<version>.<signature>" where the
+// signature is a truncated HMAC-SHA256 of id and version
+func SignTableToken(secret []byte, id, version uint) string {
+	payload := fmt.Sprintf("%d.%d", id, version)
+	return payload + "." + tableTokenSignature(secret, payload)
+}
+
+// ParseTableToken verifies a token produced by SignTableToken and returns its id and version
+func ParseTableToken(secret []byte, token string) (uint, uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0, ErrInvalidTableToken
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(tableTokenSignature(secret, payload))) {
+		return 0, 0, ErrInvalidTableToken
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidTableToken
+	}
+	version, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, ErrInvalidTableToken
+	}
+	return uint(id), uint(version), nil
+}
+
+func tableTokenSignature(secret []byte, payload string) string {
+	m := hmac.New(sha256.New, secret)
+	m.Write([]byte("table:" + payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil)[:16])
+}